@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -14,8 +17,20 @@ func (e AppEnvironment) IsLocal() bool {
 	return e == AppEnvironmentLocal
 }
 
+// OIDCProviderConfig : settings of an external OpenID Connect provider, read from OIDC_<NAME>_* env vars
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
 // slice of all app environments except the `local`` env
 var (
+	// HostEnvironment : the environment the app runs in, `local` unless ENVIRONMENT says otherwise
+	HostEnvironment AppEnvironment
+
 	// MongoDB
 	MongoURI          string
 	MongoDatabaseName string
@@ -25,7 +40,74 @@ var (
 	RedisPassword string
 
 	// Token
-	JWTSecretKey string
+	JWTSecretKey    string
+	JWTKeysDir      string
+	JWTActiveKeyID  string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// LegacyTokensAcceptedUntil : access tokens without a `sub` claim are resolved by username until then, rejected after.
+	// By default the tokens issued before the start of the process are accepted until they would have expired anyway
+	LegacyTokensAcceptedUntil time.Time
+
+	// Two-factor authentication
+	TOTPIssuer string
+
+	// Password reset
+	PasswordResetTokenTTL time.Duration
+
+	// Password policy
+	PasswordMinLength        int64
+	PasswordMinScore         int64
+	PasswordCheckBreached    bool
+	PasswordBreachedListPath string
+
+	// Password hashing
+	PasswordHashAlgorithm string
+	BcryptCost            int64
+	Argon2Time            int64
+	Argon2MemoryKiB       int64
+	Argon2Threads         int64
+
+	// Sign in throttling
+	LoginMaxAttempts     int64
+	LoginLockoutDuration time.Duration
+
+	// Notifications
+	NotifierOutboxPath string
+
+	// Mailer
+	MailerDriver              string
+	MailerFrom                string
+	MailerOutboxPath          string
+	SMTPHost                  string
+	SMTPPort                  string
+	SMTPUsername              string
+	SMTPPassword              string
+	EmailVerificationTokenTTL time.Duration
+
+	// Base URL used to build the links sent to the users
+	AppBaseURL string
+
+	// OpenID Connect login providers
+	OIDCProviders []OIDCProviderConfig
+
+	// Account deletion
+	AccountDeletionGracePeriod time.Duration
+	AccountPurgeInterval       time.Duration
+
+	// Recipe search
+	SearchBackend           string
+	SearchIndexPath         string
+	SearchReconcileInterval time.Duration
+	AutocompleteCacheTTL    time.Duration
+
+	// Recipe lists
+	RecipesQueryCacheTTL time.Duration
+
+	// Recipe nutrition
+	NutritionDatasetPath string
+	NutritionCacheTTL    time.Duration
 
 	// Server
 	ServerPort string
@@ -64,6 +146,7 @@ func init() {
 func SetConfigFromViper() {
 	currentHostEnvironment := getCurrentHostEnvironment()
 	log.Printf("🚀 Current Host Environment: %s\n", currentHostEnvironment)
+	HostEnvironment = currentHostEnvironment
 
 	// if env is local, we set the env variables using the config file
 	if currentHostEnvironment.IsLocal() {
@@ -81,6 +164,81 @@ func SetConfigFromViper() {
 
 	// Token
 	JWTSecretKey = os.Getenv("JWT_SECRET_KEY")
+	JWTKeysDir = os.Getenv("JWT_KEYS_DIR")
+	JWTActiveKeyID = os.Getenv("JWT_ACTIVE_KEY_ID")
+	AccessTokenTTL = getDurationEnv("ACCESS_TOKEN_TTL", 10*time.Minute)
+	LegacyTokensAcceptedUntil = getTimeEnv("LEGACY_TOKENS_ACCEPTED_UNTIL", time.Now().Add(AccessTokenTTL))
+	RefreshTokenTTL = getDurationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+
+	// Two-factor authentication
+	TOTPIssuer = os.Getenv("TOTP_ISSUER")
+	if TOTPIssuer == "" {
+		TOTPIssuer = "SmileCook"
+	}
+
+	// Password reset
+	PasswordResetTokenTTL = getDurationEnv("PASSWORD_RESET_TOKEN_TTL", 30*time.Minute)
+
+	// Password policy
+	PasswordMinLength = getIntEnv("PASSWORD_MIN_LENGTH", 8)
+	PasswordMinScore = getIntEnv("PASSWORD_MIN_SCORE", 3)
+	PasswordCheckBreached = getBoolEnv("PASSWORD_CHECK_BREACHED", true)
+	PasswordBreachedListPath = os.Getenv("PASSWORD_BREACHED_LIST_PATH")
+
+	// Password hashing
+	PasswordHashAlgorithm = os.Getenv("PASSWORD_HASH_ALGORITHM")
+	if PasswordHashAlgorithm == "" {
+		PasswordHashAlgorithm = "argon2id"
+	}
+	BcryptCost = getIntEnv("BCRYPT_COST", 10)
+	Argon2Time = getIntEnv("ARGON2_TIME", 2)
+	Argon2MemoryKiB = getIntEnv("ARGON2_MEMORY_KIB", 19*1024)
+	Argon2Threads = getIntEnv("ARGON2_THREADS", 1)
+
+	// Sign in throttling
+	LoginMaxAttempts = getIntEnv("LOGIN_MAX_ATTEMPTS", 10)
+	LoginLockoutDuration = getDurationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+
+	// Notifications
+	NotifierOutboxPath = os.Getenv("NOTIFIER_OUTBOX_PATH")
+
+	// Mailer
+	MailerDriver = os.Getenv("MAILER_DRIVER")
+	MailerFrom = os.Getenv("MAILER_FROM")
+	MailerOutboxPath = os.Getenv("MAILER_OUTBOX_PATH")
+	SMTPHost = os.Getenv("SMTP_HOST")
+	SMTPPort = os.Getenv("SMTP_PORT")
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	EmailVerificationTokenTTL = getDurationEnv("EMAIL_VERIFICATION_TOKEN_TTL", 24*time.Hour)
+
+	AppBaseURL = os.Getenv("APP_BASE_URL")
+
+	// OpenID Connect login providers
+	OIDCProviders = getOIDCProviders()
+
+	// Account deletion
+	AccountDeletionGracePeriod = getDurationEnv("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	AccountPurgeInterval = getDurationEnv("ACCOUNT_PURGE_INTERVAL", time.Hour)
+
+	// Recipe search
+	SearchBackend = os.Getenv("SEARCH_BACKEND")
+	if SearchBackend == "" {
+		SearchBackend = "mongo"
+	}
+	SearchIndexPath = os.Getenv("SEARCH_INDEX_PATH")
+	if SearchIndexPath == "" {
+		SearchIndexPath = "data/search/recipes.idx"
+	}
+	SearchReconcileInterval = getDurationEnv("SEARCH_RECONCILE_INTERVAL", time.Hour)
+	AutocompleteCacheTTL = getDurationEnv("AUTOCOMPLETE_CACHE_TTL", 10*time.Minute)
+
+	// Recipe nutrition
+	NutritionDatasetPath = os.Getenv("NUTRITION_DATASET_PATH")
+	NutritionCacheTTL = getDurationEnv("NUTRITION_CACHE_TTL", 24*time.Hour)
+
+	// Recipe lists
+	RecipesQueryCacheTTL = getDurationEnv("RECIPES_QUERY_CACHE_TTL", 10*time.Minute)
 
 	// Server
 	ServerPort = os.Getenv("SERVER_PORT")
@@ -121,6 +279,44 @@ func setEnvironmentVarsFromConfig() {
 	redisURI := viper.GetString("REDIS_URI")
 	redisPassword := viper.GetString("REDIS_PASSWORD")
 	jwtSecretKey := viper.GetString("JWT_SECRET_KEY")
+	jwtKeysDir := viper.GetString("JWT_KEYS_DIR")
+	jwtActiveKeyID := viper.GetString("JWT_ACTIVE_KEY_ID")
+	accessTokenTTL := viper.GetString("ACCESS_TOKEN_TTL")
+	legacyTokensAcceptedUntil := viper.GetString("LEGACY_TOKENS_ACCEPTED_UNTIL")
+	refreshTokenTTL := viper.GetString("REFRESH_TOKEN_TTL")
+	totpIssuer := viper.GetString("TOTP_ISSUER")
+	passwordResetTokenTTL := viper.GetString("PASSWORD_RESET_TOKEN_TTL")
+	passwordMinLength := viper.GetString("PASSWORD_MIN_LENGTH")
+	passwordMinScore := viper.GetString("PASSWORD_MIN_SCORE")
+	passwordCheckBreached := viper.GetString("PASSWORD_CHECK_BREACHED")
+	passwordBreachedListPath := viper.GetString("PASSWORD_BREACHED_LIST_PATH")
+	passwordHashAlgorithm := viper.GetString("PASSWORD_HASH_ALGORITHM")
+	bcryptCost := viper.GetString("BCRYPT_COST")
+	argon2Time := viper.GetString("ARGON2_TIME")
+	argon2MemoryKiB := viper.GetString("ARGON2_MEMORY_KIB")
+	argon2Threads := viper.GetString("ARGON2_THREADS")
+	loginMaxAttempts := viper.GetString("LOGIN_MAX_ATTEMPTS")
+	loginLockoutDuration := viper.GetString("LOGIN_LOCKOUT_DURATION")
+	notifierOutboxPath := viper.GetString("NOTIFIER_OUTBOX_PATH")
+	mailerDriver := viper.GetString("MAILER_DRIVER")
+	mailerFrom := viper.GetString("MAILER_FROM")
+	mailerOutboxPath := viper.GetString("MAILER_OUTBOX_PATH")
+	smtpHost := viper.GetString("SMTP_HOST")
+	smtpPort := viper.GetString("SMTP_PORT")
+	smtpUsername := viper.GetString("SMTP_USERNAME")
+	smtpPassword := viper.GetString("SMTP_PASSWORD")
+	emailVerificationTokenTTL := viper.GetString("EMAIL_VERIFICATION_TOKEN_TTL")
+	appBaseURL := viper.GetString("APP_BASE_URL")
+	oidcProviders := viper.GetString("OIDC_PROVIDERS")
+	accountDeletionGracePeriod := viper.GetString("ACCOUNT_DELETION_GRACE_PERIOD")
+	accountPurgeInterval := viper.GetString("ACCOUNT_PURGE_INTERVAL")
+	searchBackend := viper.GetString("SEARCH_BACKEND")
+	searchIndexPath := viper.GetString("SEARCH_INDEX_PATH")
+	searchReconcileInterval := viper.GetString("SEARCH_RECONCILE_INTERVAL")
+	autocompleteCacheTTL := viper.GetString("AUTOCOMPLETE_CACHE_TTL")
+	nutritionDatasetPath := viper.GetString("NUTRITION_DATASET_PATH")
+	nutritionCacheTTL := viper.GetString("NUTRITION_CACHE_TTL")
+	recipesQueryCacheTTL := viper.GetString("RECIPES_QUERY_CACHE_TTL")
 	serverPort := viper.GetString("SERVER_PORT")
 
 	// set the host OS env vars
@@ -129,5 +325,151 @@ func setEnvironmentVarsFromConfig() {
 	os.Setenv("REDIS_URI", redisURI)
 	os.Setenv("REDIS_PASSWORD", redisPassword)
 	os.Setenv("JWT_SECRET_KEY", jwtSecretKey)
+	os.Setenv("JWT_KEYS_DIR", jwtKeysDir)
+	os.Setenv("JWT_ACTIVE_KEY_ID", jwtActiveKeyID)
+	os.Setenv("ACCESS_TOKEN_TTL", accessTokenTTL)
+	os.Setenv("LEGACY_TOKENS_ACCEPTED_UNTIL", legacyTokensAcceptedUntil)
+	os.Setenv("REFRESH_TOKEN_TTL", refreshTokenTTL)
+	os.Setenv("TOTP_ISSUER", totpIssuer)
+	os.Setenv("PASSWORD_RESET_TOKEN_TTL", passwordResetTokenTTL)
+	os.Setenv("PASSWORD_MIN_LENGTH", passwordMinLength)
+	os.Setenv("PASSWORD_MIN_SCORE", passwordMinScore)
+	os.Setenv("PASSWORD_CHECK_BREACHED", passwordCheckBreached)
+	os.Setenv("PASSWORD_BREACHED_LIST_PATH", passwordBreachedListPath)
+	os.Setenv("PASSWORD_HASH_ALGORITHM", passwordHashAlgorithm)
+	os.Setenv("BCRYPT_COST", bcryptCost)
+	os.Setenv("ARGON2_TIME", argon2Time)
+	os.Setenv("ARGON2_MEMORY_KIB", argon2MemoryKiB)
+	os.Setenv("ARGON2_THREADS", argon2Threads)
+	os.Setenv("LOGIN_MAX_ATTEMPTS", loginMaxAttempts)
+	os.Setenv("LOGIN_LOCKOUT_DURATION", loginLockoutDuration)
+	os.Setenv("NOTIFIER_OUTBOX_PATH", notifierOutboxPath)
+	os.Setenv("MAILER_DRIVER", mailerDriver)
+	os.Setenv("MAILER_FROM", mailerFrom)
+	os.Setenv("MAILER_OUTBOX_PATH", mailerOutboxPath)
+	os.Setenv("SMTP_HOST", smtpHost)
+	os.Setenv("SMTP_PORT", smtpPort)
+	os.Setenv("SMTP_USERNAME", smtpUsername)
+	os.Setenv("SMTP_PASSWORD", smtpPassword)
+	os.Setenv("EMAIL_VERIFICATION_TOKEN_TTL", emailVerificationTokenTTL)
+	os.Setenv("APP_BASE_URL", appBaseURL)
+	os.Setenv("OIDC_PROVIDERS", oidcProviders)
+	for _, name := range oidcProviderNames(oidcProviders) {
+		for _, key := range []string{"ISSUER", "CLIENT_ID", "CLIENT_SECRET", "REDIRECT_URL"} {
+			envKey := oidcProviderEnvKey(name, key)
+			os.Setenv(envKey, viper.GetString(envKey))
+		}
+	}
+	os.Setenv("ACCOUNT_DELETION_GRACE_PERIOD", accountDeletionGracePeriod)
+	os.Setenv("ACCOUNT_PURGE_INTERVAL", accountPurgeInterval)
+	os.Setenv("SEARCH_BACKEND", searchBackend)
+	os.Setenv("SEARCH_INDEX_PATH", searchIndexPath)
+	os.Setenv("SEARCH_RECONCILE_INTERVAL", searchReconcileInterval)
+	os.Setenv("AUTOCOMPLETE_CACHE_TTL", autocompleteCacheTTL)
+	os.Setenv("NUTRITION_DATASET_PATH", nutritionDatasetPath)
+	os.Setenv("NUTRITION_CACHE_TTL", nutritionCacheTTL)
+	os.Setenv("RECIPES_QUERY_CACHE_TTL", recipesQueryCacheTTL)
 	os.Setenv("SERVER_PORT", serverPort)
 }
+
+// getOIDCProviders : reads the providers listed in OIDC_PROVIDERS (e.g. `google,github`), incomplete providers are skipped
+func getOIDCProviders() []OIDCProviderConfig {
+	providers := make([]OIDCProviderConfig, 0)
+	for _, name := range oidcProviderNames(os.Getenv("OIDC_PROVIDERS")) {
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(oidcProviderEnvKey(name, "ISSUER")),
+			ClientID:     os.Getenv(oidcProviderEnvKey(name, "CLIENT_ID")),
+			ClientSecret: os.Getenv(oidcProviderEnvKey(name, "CLIENT_SECRET")),
+			RedirectURL:  os.Getenv(oidcProviderEnvKey(name, "REDIRECT_URL")),
+		}
+
+		if provider.RedirectURL == "" {
+			provider.RedirectURL = strings.TrimSuffix(os.Getenv("APP_BASE_URL"), "/") + "/oauth/" + name + "/callback"
+		}
+
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Printf("oidc provider %s is missing an issuer or a client id, skipping it\n", name)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// oidcProviderNames : splits the comma separated list of provider names
+func oidcProviderNames(value string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// oidcProviderEnvKey : returns the env var holding the setting of the provider, e.g. OIDC_GOOGLE_CLIENT_ID
+func oidcProviderEnvKey(name string, key string) string {
+	return "OIDC_" + strings.ToUpper(name) + "_" + key
+}
+
+// getDurationEnv : parses a duration (e.g. `720h`) from the env var, falls back to the default value if it is unset or invalid
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("invalid duration for %s: %q, using default: %s\n", key, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// getTimeEnv : parses an RFC 3339 time (e.g. `2026-11-17T00:00:00Z`) from the env var, falls back to the default value if it is unset or invalid
+func getTimeEnv(key string, defaultValue time.Time) time.Time {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Printf("invalid time for %s: %q, using default: %s\n", key, value, defaultValue.Format(time.RFC3339))
+		return defaultValue
+	}
+	return t
+}
+
+// getIntEnv : parses an integer from the env var, falls back to the default value if it is unset or invalid
+func getIntEnv(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("invalid integer for %s: %q, using default: %d\n", key, value, defaultValue)
+		return defaultValue
+	}
+	return i
+}
+
+// getBoolEnv : parses a boolean (e.g. `true`, `0`) from the env var, falls back to the default value if it is unset or invalid
+func getBoolEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("invalid boolean for %s: %q, using default: %t\n", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
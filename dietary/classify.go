@@ -0,0 +1,170 @@
+package dietary
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// dietExcludes : the categories a recipe of the diet must not contain
+var dietExcludes = map[Diet][]category{
+	Vegan:       animalProducts,
+	Vegetarian:  is(meat, gelatin, Fish, Shellfish),
+	Pescatarian: is(meat, gelatin),
+	GlutenFree:  is(Gluten),
+	DairyFree:   is(Dairy),
+	NutFree:     is(Peanut, TreeNut),
+	EggFree:     is(Egg),
+}
+
+// matcher : a rule or a modifier with its phrase split into singular words
+type matcher struct {
+	words      []string
+	categories []category
+	suppresses []category
+}
+
+// matchers : the matchers by their first word, the longest first
+var matchers = map[string][]matcher{}
+
+func init() {
+	add := func(phrase string, categories []category, suppresses []category) {
+		words := words(phrase)
+		matchers[words[0]] = append(matchers[words[0]], matcher{words: words, categories: categories, suppresses: suppresses})
+	}
+	for _, r := range rules {
+		add(r.phrase, r.categories, nil)
+	}
+	for _, m := range modifiers {
+		add(m.phrase, nil, m.suppresses)
+	}
+
+	for first := range matchers {
+		sort.SliceStable(matchers[first], func(i, j int) bool {
+			return len(matchers[first][i].words) > len(matchers[first][j].words)
+		})
+	}
+}
+
+// words : the lower cased, singular words of the text, "gluten-free" is two words
+func words(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for i, field := range fields {
+		fields[i] = singular(field)
+	}
+	return fields
+}
+
+// singular : the singular of a plural word, good enough for the names of ingredients ("anchovies", "tomatoes", "peaches")
+func singular(word string) string {
+	switch {
+	case len(word) <= 3 || strings.HasSuffix(word, "ss") || strings.HasSuffix(word, "us"):
+		return word
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "oes"), strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "xes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// detect : the categories of the ingredient lines
+func detect(ingredients []string) map[category]bool {
+	found := make(map[category]bool)
+
+	for _, line := range ingredients {
+		lineWords := words(line)
+		suppressed := make(map[category]bool)
+
+		for i := 0; i < len(lineWords); {
+			m, ok := match(lineWords[i:])
+			if !ok {
+				i++
+				continue
+			}
+			i += len(m.words)
+
+			// a modifier applies to the next phrase of the line
+			if m.suppresses != nil {
+				for _, c := range m.suppresses {
+					suppressed[c] = true
+				}
+				continue
+			}
+
+			for _, c := range m.categories {
+				if !suppressed[c] {
+					found[c] = true
+				}
+			}
+			suppressed = make(map[category]bool)
+		}
+	}
+
+	return found
+}
+
+// match : the longest matcher at the start of the words
+func match(lineWords []string) (matcher, bool) {
+	for _, m := range matchers[lineWords[0]] {
+		if len(m.words) > len(lineWords) {
+			continue
+		}
+		matches := true
+		for i, word := range m.words {
+			if lineWords[i] != word {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return m, true
+		}
+	}
+	return matcher{}, false
+}
+
+// Classify : the allergens of the ingredient lines and the diets they are suitable for, corrected by the overrides of the author.
+// An allergen the author removes no longer counts against the diets, and a recipe without ingredients gets no diet
+// unless the author adds it. The classification fails open, an ingredient no rule matches counts as free of every allergen,
+// so the labels are a guess that is returned along with Notice
+func Classify(ingredients []string, overrides *Overrides) ([]Allergen, []Diet) {
+	if overrides == nil {
+		overrides = &Overrides{}
+	}
+
+	found := detect(ingredients)
+	for allergen, present := range overrides.Allergens {
+		found[category(allergen)] = present
+	}
+
+	allergens := make([]Allergen, 0)
+	for _, allergen := range Allergens {
+		if found[category(allergen)] {
+			allergens = append(allergens, allergen)
+		}
+	}
+
+	diets := make([]Diet, 0)
+	for _, diet := range Diets {
+		suitable := len(ingredients) > 0
+		for _, c := range dietExcludes[diet] {
+			if found[c] {
+				suitable = false
+				break
+			}
+		}
+		if override, ok := overrides.Diets[diet]; ok {
+			suitable = override
+		}
+		if suitable {
+			diets = append(diets, diet)
+		}
+	}
+
+	return allergens, diets
+}
@@ -0,0 +1,125 @@
+package dietary
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSingular(t *testing.T) {
+	tests := map[string]string{
+		"anchovies": "anchovy",
+		"tomatoes":  "tomato",
+		"peaches":   "peach",
+		"radishes":  "radish",
+		"boxes":     "box",
+		"eggs":      "egg",
+		"glass":     "glass",
+		"hummus":    "hummus",
+		"oats":      "oat",
+		"gas":       "gas",
+		"rice":      "rice",
+	}
+	for word, want := range tests {
+		if got := singular(word); got != want {
+			t.Errorf("singular(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name        string
+		ingredients []string
+		overrides   *Overrides
+		allergens   []Allergen
+		diets       []Diet
+	}{
+		{
+			name:        "plain vegetables",
+			ingredients: []string{"2 tomatoes", "1 onion", "olive oil"},
+			allergens:   []Allergen{},
+			diets:       []Diet{Vegan, Vegetarian, Pescatarian, GlutenFree, DairyFree, NutFree, EggFree},
+		},
+		{
+			name:        "plural words",
+			ingredients: []string{"4 anchovies", "3 eggs"},
+			allergens:   []Allergen{Egg, Fish},
+			diets:       []Diet{Pescatarian, GlutenFree, DairyFree, NutFree},
+		},
+		{
+			name:        "longest phrase wins",
+			ingredients: []string{"2 tbsp peanut butter"},
+			allergens:   []Allergen{Peanut},
+			diets:       []Diet{Vegan, Vegetarian, Pescatarian, GlutenFree, DairyFree, EggFree},
+		},
+		{
+			name:        "almond milk is not dairy",
+			ingredients: []string{"1 cup almond milk"},
+			allergens:   []Allergen{TreeNut},
+			diets:       []Diet{Vegan, Vegetarian, Pescatarian, GlutenFree, DairyFree, EggFree},
+		},
+		{
+			name:        "water chestnut is not a nut",
+			ingredients: []string{"1 can water chestnuts"},
+			allergens:   []Allergen{},
+			diets:       []Diet{Vegan, Vegetarian, Pescatarian, GlutenFree, DairyFree, NutFree, EggFree},
+		},
+		{
+			name:        "modifiers",
+			ingredients: []string{"50g vegan butter", "200g gluten-free flour"},
+			allergens:   []Allergen{},
+			diets:       []Diet{Vegan, Vegetarian, Pescatarian, GlutenFree, DairyFree, NutFree, EggFree},
+		},
+		{
+			name:        "a modifier only applies to the next phrase",
+			ingredients: []string{"vegan butter and milk"},
+			allergens:   []Allergen{Dairy},
+			diets:       []Diet{Vegetarian, Pescatarian, GlutenFree, NutFree, EggFree},
+		},
+		{
+			name:        "meat and honey",
+			ingredients: []string{"1 chicken breast", "1 tbsp honey"},
+			allergens:   []Allergen{},
+			diets:       []Diet{GlutenFree, DairyFree, NutFree, EggFree},
+		},
+		{
+			name:        "no ingredients",
+			ingredients: nil,
+			allergens:   []Allergen{},
+			diets:       []Diet{},
+		},
+		{
+			name:        "no ingredients with an added diet",
+			ingredients: nil,
+			overrides:   &Overrides{Diets: map[Diet]bool{Vegan: true}},
+			allergens:   []Allergen{},
+			diets:       []Diet{Vegan},
+		},
+		{
+			name:        "removed allergen no longer counts against the diets",
+			ingredients: []string{"200g flour"},
+			overrides:   &Overrides{Allergens: map[Allergen]bool{Gluten: false}},
+			allergens:   []Allergen{},
+			diets:       []Diet{Vegan, Vegetarian, Pescatarian, GlutenFree, DairyFree, NutFree, EggFree},
+		},
+		{
+			name:        "added allergen and removed diet",
+			ingredients: []string{"2 tomatoes"},
+			overrides:   &Overrides{Allergens: map[Allergen]bool{Sesame: true}, Diets: map[Diet]bool{Vegan: false}},
+			allergens:   []Allergen{Sesame},
+			diets:       []Diet{Vegetarian, Pescatarian, GlutenFree, DairyFree, NutFree, EggFree},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allergens, diets := Classify(test.ingredients, test.overrides)
+			if !reflect.DeepEqual(allergens, test.allergens) {
+				t.Errorf("Classify(%q) allergens = %v, want %v", test.ingredients, allergens, test.allergens)
+			}
+			if !reflect.DeepEqual(diets, test.diets) {
+				t.Errorf("Classify(%q) diets = %v, want %v", test.ingredients, diets, test.diets)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Package dietary derives the allergens and the diets of a recipe from its ingredient lines.
+// The labels are a heuristic: an ingredient the rules do not know is assumed to contain no allergen,
+// so a recipe can be labelled vegan or gluten-free while it is not, only the overrides of the author are authoritative
+package dietary
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Notice : returned with the labels of every recipe, the labels are derived from the names of the ingredients and may be wrong
+const Notice = "allergens and diets are guessed from the ingredient names and may be incomplete, check the ingredients before relying on them"
+
+// Allergen : a major food allergen a recipe may contain
+type Allergen string
+
+const (
+	Gluten    Allergen = "gluten"
+	Dairy     Allergen = "dairy"
+	Egg       Allergen = "egg"
+	Peanut    Allergen = "peanut"
+	TreeNut   Allergen = "tree_nut"
+	Soy       Allergen = "soy"
+	Fish      Allergen = "fish"
+	Shellfish Allergen = "shellfish"
+	Sesame    Allergen = "sesame"
+)
+
+// Allergens : every allergen, in the order they are listed
+var Allergens = []Allergen{Gluten, Dairy, Egg, Peanut, TreeNut, Soy, Fish, Shellfish, Sesame}
+
+// IsValid : reports whether the allergen is one of the supported ones
+func (a Allergen) IsValid() bool {
+	for _, allergen := range Allergens {
+		if a == allergen {
+			return true
+		}
+	}
+	return false
+}
+
+// Diet : a diet a recipe is suitable for
+type Diet string
+
+const (
+	Vegan       Diet = "vegan"
+	Vegetarian  Diet = "vegetarian"
+	Pescatarian Diet = "pescatarian"
+	GlutenFree  Diet = "gluten_free"
+	DairyFree   Diet = "dairy_free"
+	NutFree     Diet = "nut_free"
+	EggFree     Diet = "egg_free"
+)
+
+// Diets : every diet, in the order they are listed
+var Diets = []Diet{Vegan, Vegetarian, Pescatarian, GlutenFree, DairyFree, NutFree, EggFree}
+
+// IsValid : reports whether the diet is one of the supported ones
+func (d Diet) IsValid() bool {
+	for _, diet := range Diets {
+		if d == diet {
+			return true
+		}
+	}
+	return false
+}
+
+// Overrides : the corrections of the author to the classification, true adds the label and false removes it
+type Overrides struct {
+	Allergens map[Allergen]bool `json:"allergens,omitempty" bson:"allergens,omitempty"`
+	Diets     map[Diet]bool     `json:"diets,omitempty" bson:"diets,omitempty"`
+}
+
+// Validate : reports the first unknown allergen or diet of the overrides, nil overrides are valid
+func (o *Overrides) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	for allergen := range o.Allergens {
+		if !allergen.IsValid() {
+			return fmt.Errorf("unknown allergen: %s, it must be one of %s", allergen, joinAllergens(Allergens))
+		}
+	}
+	for diet := range o.Diets {
+		if !diet.IsValid() {
+			return fmt.Errorf("unknown diet: %s, it must be one of %s", diet, joinDiets(Diets))
+		}
+	}
+
+	return nil
+}
+
+func joinAllergens(allergens []Allergen) string {
+	names := make([]string, 0, len(allergens))
+	for _, allergen := range allergens {
+		names = append(names, string(allergen))
+	}
+	return strings.Join(names, ", ")
+}
+
+func joinDiets(diets []Diet) string {
+	names := make([]string, 0, len(diets))
+	for _, diet := range diets {
+		names = append(names, string(diet))
+	}
+	return strings.Join(names, ", ")
+}
+
+// ParseAllergen : the allergen of a name, an error lists the supported ones
+func ParseAllergen(name string) (Allergen, error) {
+	allergen := Allergen(strings.ToLower(strings.TrimSpace(name)))
+	if !allergen.IsValid() {
+		return "", fmt.Errorf("unknown allergen: %s, it must be one of %s", name, joinAllergens(Allergens))
+	}
+	return allergen, nil
+}
+
+// ParseDiet : the diet of a name, an error lists the supported ones
+func ParseDiet(name string) (Diet, error) {
+	diet := Diet(strings.ToLower(strings.TrimSpace(name)))
+	if !diet.IsValid() {
+		return "", fmt.Errorf("unknown diet: %s, it must be one of %s", name, joinDiets(Diets))
+	}
+	return diet, nil
+}
@@ -0,0 +1,269 @@
+package dietary
+
+// category : an allergen or one of the other kinds of ingredients the diets are derived from
+type category string
+
+const (
+	meat    category = "meat"
+	gelatin category = "gelatin"
+	honey   category = "honey"
+)
+
+// animalProducts : every category a vegan recipe must not contain
+var animalProducts = []category{meat, gelatin, honey, category(Fish), category(Shellfish), category(Dairy), category(Egg)}
+
+// rule : a phrase of an ingredient line and the categories it belongs to, a rule without categories
+// marks a phrase that looks like an allergen but is not one ("almond milk" is not dairy, "water chestnut" is not a nut)
+type rule struct {
+	phrase     string
+	categories []category
+}
+
+// modifier : a phrase that removes categories from the next phrase of the line ("vegan butter", "gluten-free flour")
+type modifier struct {
+	phrase     string
+	suppresses []category
+}
+
+func is(categories ...interface{}) []category {
+	result := make([]category, 0, len(categories))
+	for _, c := range categories {
+		switch value := c.(type) {
+		case Allergen:
+			result = append(result, category(value))
+		case category:
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// rules : the phrases are matched on whole, singular words and the longest phrase wins,
+// so "peanut butter" is a peanut and not dairy
+var rules = []rule{
+	// gluten
+	{"wheat", is(Gluten)},
+	{"flour", is(Gluten)},
+	{"bread", is(Gluten)},
+	{"breadcrumbs", is(Gluten)},
+	{"bread crumbs", is(Gluten)},
+	{"panko", is(Gluten)},
+	{"crouton", is(Gluten)},
+	{"pasta", is(Gluten)},
+	{"spaghetti", is(Gluten)},
+	{"macaroni", is(Gluten)},
+	{"penne", is(Gluten)},
+	{"fettuccine", is(Gluten)},
+	{"linguine", is(Gluten)},
+	{"lasagna", is(Gluten)},
+	{"noodles", is(Gluten)},
+	{"couscous", is(Gluten)},
+	{"bulgur", is(Gluten)},
+	{"semolina", is(Gluten)},
+	{"barley", is(Gluten)},
+	{"rye", is(Gluten)},
+	{"spelt", is(Gluten)},
+	{"farro", is(Gluten)},
+	{"seitan", is(Gluten)},
+	{"crackers", is(Gluten)},
+	{"tortilla", is(Gluten)},
+	{"pita", is(Gluten)},
+	{"bagel", is(Gluten)},
+	{"croissant", is(Gluten)},
+	{"biscuits", is(Gluten)},
+	{"puff pastry", is(Gluten, Dairy)},
+	{"pastry", is(Gluten)},
+	{"phyllo", is(Gluten)},
+	{"pie crust", is(Gluten)},
+	{"beer", is(Gluten)},
+	{"malt", is(Gluten)},
+	{"rice flour", is()},
+	{"coconut flour", is()},
+	{"chickpea flour", is()},
+	{"corn flour", is()},
+	{"oat flour", is()},
+	{"tapioca flour", is()},
+	{"potato flour", is()},
+	{"buckwheat flour", is()},
+	{"rice noodles", is()},
+	{"glass noodles", is()},
+	{"corn tortilla", is()},
+
+	// dairy
+	{"milk", is(Dairy)},
+	{"buttermilk", is(Dairy)},
+	{"butter", is(Dairy)},
+	{"cream", is(Dairy)},
+	{"sour cream", is(Dairy)},
+	{"ice cream", is(Dairy)},
+	{"half and half", is(Dairy)},
+	{"cheese", is(Dairy)},
+	{"parmesan", is(Dairy)},
+	{"mozzarella", is(Dairy)},
+	{"cheddar", is(Dairy)},
+	{"ricotta", is(Dairy)},
+	{"feta", is(Dairy)},
+	{"brie", is(Dairy)},
+	{"gouda", is(Dairy)},
+	{"mascarpone", is(Dairy)},
+	{"paneer", is(Dairy)},
+	{"yogurt", is(Dairy)},
+	{"yoghurt", is(Dairy)},
+	{"ghee", is(Dairy)},
+	{"whey", is(Dairy)},
+	{"casein", is(Dairy)},
+	{"creme fraiche", is(Dairy)},
+	{"crème fraîche", is(Dairy)},
+	{"pesto", is(Dairy, TreeNut)},
+	{"coconut milk", is()},
+	{"coconut cream", is()},
+	{"coconut yogurt", is()},
+	{"oat milk", is()},
+	{"rice milk", is()},
+	{"soy milk", is(Soy)},
+	{"almond milk", is(TreeNut)},
+	{"cashew milk", is(TreeNut)},
+	{"almond butter", is(TreeNut)},
+	{"cashew butter", is(TreeNut)},
+	{"cocoa butter", is()},
+	{"apple butter", is()},
+	{"butter beans", is()},
+	{"cream of tartar", is()},
+
+	// egg
+	{"egg", is(Egg)},
+	{"egg yolk", is(Egg)},
+	{"egg white", is(Egg)},
+	{"egg noodles", is(Egg, Gluten)},
+	{"mayonnaise", is(Egg)},
+	{"mayo", is(Egg)},
+	{"aioli", is(Egg)},
+	{"meringue", is(Egg)},
+	{"custard", is(Egg, Dairy)},
+	{"eggnog", is(Egg, Dairy)},
+
+	// peanut
+	{"peanut", is(Peanut)},
+	{"peanut butter", is(Peanut)},
+	{"groundnut", is(Peanut)},
+
+	// tree nuts
+	{"nuts", is(TreeNut)},
+	{"almond", is(TreeNut)},
+	{"walnut", is(TreeNut)},
+	{"pecan", is(TreeNut)},
+	{"cashew", is(TreeNut)},
+	{"pistachio", is(TreeNut)},
+	{"hazelnut", is(TreeNut)},
+	{"macadamia", is(TreeNut)},
+	{"brazil nut", is(TreeNut)},
+	{"pine nut", is(TreeNut)},
+	{"chestnut", is(TreeNut)},
+	{"praline", is(TreeNut)},
+	{"marzipan", is(TreeNut)},
+	{"nutella", is(TreeNut, Dairy)},
+	{"water chestnut", is()},
+
+	// soy
+	{"soy", is(Soy)},
+	{"soya", is(Soy)},
+	{"soybeans", is(Soy)},
+	{"soy sauce", is(Soy, Gluten)},
+	{"teriyaki", is(Soy, Gluten)},
+	{"tamari", is(Soy)},
+	{"tofu", is(Soy)},
+	{"tempeh", is(Soy)},
+	{"edamame", is(Soy)},
+	{"miso", is(Soy)},
+
+	// fish
+	{"fish", is(Fish)},
+	{"fish sauce", is(Fish)},
+	{"salmon", is(Fish)},
+	{"tuna", is(Fish)},
+	{"cod", is(Fish)},
+	{"halibut", is(Fish)},
+	{"tilapia", is(Fish)},
+	{"trout", is(Fish)},
+	{"anchovy", is(Fish)},
+	{"sardine", is(Fish)},
+	{"mackerel", is(Fish)},
+	{"haddock", is(Fish)},
+	{"snapper", is(Fish)},
+	{"sea bass", is(Fish)},
+	{"swordfish", is(Fish)},
+	{"worcestershire sauce", is(Fish)},
+	{"caesar dressing", is(Fish, Egg, Dairy)},
+
+	// shellfish
+	{"shellfish", is(Shellfish)},
+	{"shrimp", is(Shellfish)},
+	{"prawn", is(Shellfish)},
+	{"crab", is(Shellfish)},
+	{"lobster", is(Shellfish)},
+	{"crawfish", is(Shellfish)},
+	{"crayfish", is(Shellfish)},
+	{"scallop", is(Shellfish)},
+	{"clam", is(Shellfish)},
+	{"mussel", is(Shellfish)},
+	{"oyster", is(Shellfish)},
+	{"oyster sauce", is(Shellfish)},
+	{"squid", is(Shellfish)},
+	{"calamari", is(Shellfish)},
+	{"octopus", is(Shellfish)},
+
+	// sesame
+	{"sesame", is(Sesame)},
+	{"tahini", is(Sesame)},
+	{"hummus", is(Sesame)},
+
+	// meat
+	{"meat", is(meat)},
+	{"chicken", is(meat)},
+	{"beef", is(meat)},
+	{"pork", is(meat)},
+	{"lamb", is(meat)},
+	{"mutton", is(meat)},
+	{"veal", is(meat)},
+	{"turkey", is(meat)},
+	{"duck", is(meat)},
+	{"goose", is(meat)},
+	{"venison", is(meat)},
+	{"bison", is(meat)},
+	{"steak", is(meat)},
+	{"bacon", is(meat)},
+	{"ham", is(meat)},
+	{"hamburger", is(meat)},
+	{"sausage", is(meat)},
+	{"hot dog", is(meat)},
+	{"pepperoni", is(meat)},
+	{"salami", is(meat)},
+	{"prosciutto", is(meat)},
+	{"pancetta", is(meat)},
+	{"chorizo", is(meat)},
+	{"lard", is(meat)},
+	{"bone broth", is(meat)},
+	{"duck egg", is(Egg)},
+
+	// other animal products
+	{"gelatin", is(gelatin)},
+	{"gelatine", is(gelatin)},
+	{"marshmallow", is(gelatin)},
+	{"honey", is(honey)},
+	{"honeycomb", is(honey)},
+}
+
+// modifiers : the phrases that say that the next ingredient is a substitute
+var modifiers = []modifier{
+	{"vegan", animalProducts},
+	{"plant based", animalProducts},
+	{"vegetarian", is(meat, gelatin, Fish, Shellfish)},
+	{"meatless", is(meat)},
+	{"dairy free", is(Dairy)},
+	{"non dairy", is(Dairy)},
+	{"lactose free", is(Dairy)},
+	{"gluten free", is(Gluten)},
+	{"egg free", is(Egg)},
+	{"nut free", is(Peanut, TreeNut)},
+	{"soy free", is(Soy)},
+}
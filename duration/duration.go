@@ -0,0 +1,185 @@
+// Package duration reads and writes the ISO 8601 durations of the recipe times, e.g. PT1H30M
+package duration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// ErrInvalid : the text is not an ISO 8601 duration made of weeks, days, hours, minutes and seconds
+var ErrInvalid = errors.New("invalid ISO 8601 duration, expected e.g. PT45M or PT1H30M")
+
+// designators : the units of a duration, in the order they may appear and whether they belong after the T
+var designators = []struct {
+	designator byte
+	unit       time.Duration
+	afterT     bool
+}{
+	{'W', 7 * 24 * time.Hour, false},
+	{'D', 24 * time.Hour, false},
+	{'H', time.Hour, true},
+	{'M', time.Minute, true},
+	{'S', time.Second, true},
+}
+
+// Parse : parses an ISO 8601 duration such as PT45M, PT1H30M, P1DT2H or PT1.5H,
+// years and months are rejected because their length varies
+func Parse(text string) (time.Duration, error) {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	if len(text) < 2 || text[0] != 'P' || strings.HasSuffix(text, "T") {
+		return 0, ErrInvalid
+	}
+
+	var total time.Duration
+	afterT := false
+	next := 0 // the index of the first designator that may still come
+	rest := text[1:]
+	for rest != "" {
+		if rest[0] == 'T' {
+			if afterT {
+				return 0, ErrInvalid
+			}
+			afterT = true
+			rest = rest[1:]
+			continue
+		}
+
+		end := strings.IndexFunc(rest, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.' && r != ','
+		})
+		if end <= 0 {
+			return 0, ErrInvalid
+		}
+		value, err := strconv.ParseFloat(strings.Replace(rest[:end], ",", ".", 1), 64)
+		if err != nil {
+			return 0, ErrInvalid
+		}
+
+		found := false
+		for i := next; i < len(designators); i++ {
+			d := designators[i]
+			if d.designator == rest[end] && d.afterT == afterT {
+				// a component or a sum beyond the range of a time.Duration would wrap around
+				if value >= float64(math.MaxInt64)/float64(d.unit) {
+					return 0, ErrInvalid
+				}
+				part := time.Duration(value * float64(d.unit))
+				if total > math.MaxInt64-part {
+					return 0, ErrInvalid
+				}
+				total += part
+				next = i + 1
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, ErrInvalid
+		}
+		rest = rest[end+1:]
+	}
+
+	if next == 0 {
+		return 0, ErrInvalid
+	}
+	return total, nil
+}
+
+// Format : the ISO 8601 form of the duration, in days, hours, minutes and whole seconds
+func Format(d time.Duration) string {
+	seconds := Seconds(d)
+	if seconds <= 0 {
+		return "PT0S"
+	}
+
+	days, seconds := seconds/86400, seconds%86400
+	hours, seconds := seconds/3600, seconds%3600
+	minutes, seconds := seconds/60, seconds%60
+
+	var b strings.Builder
+	b.WriteString("P")
+	if days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+	if hours > 0 || minutes > 0 || seconds > 0 {
+		b.WriteString("T")
+		if hours > 0 {
+			fmt.Fprintf(&b, "%dH", hours)
+		}
+		if minutes > 0 {
+			fmt.Fprintf(&b, "%dM", minutes)
+		}
+		if seconds > 0 {
+			fmt.Fprintf(&b, "%dS", seconds)
+		}
+	}
+	return b.String()
+}
+
+// Duration : a duration that is an ISO 8601 string in JSON and a number of seconds in BSON, so that mongo can compare it
+type Duration time.Duration
+
+// Std : the duration as a time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// String : the ISO 8601 form of the duration
+func (d Duration) String() string {
+	return Format(time.Duration(d))
+}
+
+// MarshalJSON : writes the ISO 8601 form of the duration
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON : reads an ISO 8601 duration
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return ErrInvalid
+	}
+
+	parsed, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalBSONValue : writes the duration as a number of seconds
+func (d Duration) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bsontype.Int64, bsoncore.AppendInt64(nil, Seconds(time.Duration(d))), nil
+}
+
+// UnmarshalBSONValue : reads a number of seconds
+func (d *Duration) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bsoncore.Value{Type: t, Data: data}
+	switch t {
+	case bsontype.Int64:
+		*d = Duration(time.Duration(value.Int64()) * time.Second)
+	case bsontype.Int32:
+		*d = Duration(time.Duration(value.Int32()) * time.Second)
+	case bsontype.Double:
+		*d = Duration(time.Duration(value.Double() * float64(time.Second)))
+	default:
+		return fmt.Errorf("cannot decode %s into a duration", t)
+	}
+	return nil
+}
+
+// Seconds : the whole number of seconds of the duration, the form it is stored and queried in
+func Seconds(d time.Duration) int64 {
+	return int64(d.Round(time.Second) / time.Second)
+}
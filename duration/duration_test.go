@@ -0,0 +1,104 @@
+package duration
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want time.Duration
+	}{
+		{"PT45M", 45 * time.Minute},
+		{"PT1H30M", 90 * time.Minute},
+		{"pt1h30m", 90 * time.Minute},
+		{" PT10S ", 10 * time.Second},
+		{"P1DT2H", 26 * time.Hour},
+		{"P1W", 7 * 24 * time.Hour},
+		{"P1W2D", 9 * 24 * time.Hour},
+		{"PT1.5H", 90 * time.Minute},
+		{"PT0,5M", 30 * time.Second},
+		{"PT0S", 0},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.text)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", test.text, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Parse(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"P",
+		"PT",
+		"P1DT",
+		"45M",
+		"P1Y",
+		"P1M",
+		"PT1D",
+		"P1H",
+		"PT30M1H",
+		"PT1H1H",
+		"PT1HT1M",
+		"PTH",
+		"PT-1H",
+		"PT1.2.3H",
+		"P99999W99999D",
+		"PT9223372037S",
+		"PT2562047H2562047H",
+		"PT2562047H47M17S",
+	}
+	for _, text := range tests {
+		if got, err := Parse(text); err != ErrInvalid {
+			t.Errorf("Parse(%q) = %v, %v, want ErrInvalid", text, got, err)
+		}
+	}
+}
+
+func TestParseLargest(t *testing.T) {
+	largest := "PT2562047H47M16S"
+	got, err := Parse(largest)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", largest, err)
+	}
+	if want := time.Duration(9223372036) * time.Second; got != want {
+		t.Errorf("Parse(%q) = %v, want %v", largest, got, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "PT0S"},
+		{-time.Minute, "PT0S"},
+		{45 * time.Minute, "PT45M"},
+		{90 * time.Minute, "PT1H30M"},
+		{26 * time.Hour, "P1DT2H"},
+		{9 * 24 * time.Hour, "P9D"},
+		{time.Hour + 1500*time.Millisecond, "PT1H2S"},
+		{400 * time.Millisecond, "PT0S"},
+	}
+	for _, test := range tests {
+		if got := Format(test.d); got != test.want {
+			t.Errorf("Format(%v) = %q, want %q", test.d, got, test.want)
+		}
+	}
+}
+
+func TestFormatParse(t *testing.T) {
+	for _, d := range []time.Duration{time.Second, 45 * time.Minute, 26*time.Hour + 5*time.Second, 30 * 24 * time.Hour} {
+		got, err := Parse(Format(d))
+		if err != nil || got != d {
+			t.Errorf("Parse(Format(%v)) = %v, %v, want %v", d, got, err, d)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skamranahmed/smilecook/service"
+	"github.com/skamranahmed/smilecook/validation"
+)
+
+type AccountHandler struct {
+	ctx            context.Context
+	accountService service.AccountService
+	authHandler    *AuthHandler
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type changeUsernameRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// NewAccountHandler: used to create a new instance from the AccountHandler struct
+// the auth handler is used to issue new tokens once the old ones have been revoked
+func NewAccountHandler(ctx context.Context, accountService service.AccountService, authHandler *AuthHandler) *AccountHandler {
+	return &AccountHandler{
+		ctx:            ctx,
+		accountService: accountService,
+		authHandler:    authHandler,
+	}
+}
+
+// ChangePasswordHandler: changes the password of the current user, every other session is signed out
+func (handler *AccountHandler) ChangePasswordHandler(c *gin.Context) {
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	var request changePasswordRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = handler.accountService.ChangePassword(user, request.CurrentPassword, request.NewPassword)
+	if err != nil {
+		if err == service.ErrPasswordMismatch {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+			return
+		}
+		if fieldErrors, ok := err.(validation.Errors); ok {
+			abortWithValidationErrors(c, renameField(fieldErrors, "password", "new_password"))
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the tokens used for this request have just been revoked
+	jwtOutput, err := handler.authHandler.issueTokens(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, jwtOutput)
+	return
+}
+
+// ChangeUsernameHandler: renames the current user, the recipes of the user are moved along
+func (handler *AccountHandler) ChangeUsernameHandler(c *gin.Context) {
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	var request changeUsernameRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	newUsername := strings.TrimSpace(request.Username)
+	if newUsername == user.Username {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "new username is the same as the current one"})
+		return
+	}
+
+	err = handler.accountService.ChangeUsername(user, newUsername)
+	if err != nil {
+		if err == service.ErrUsernameTaken {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if fieldErrors, ok := err.(validation.Errors); ok {
+			abortWithValidationErrors(c, fieldErrors)
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the old tokens carry the old username, new ones are issued for the renamed user
+	user.Username = newUsername
+	jwtOutput, err := handler.authHandler.issueTokens(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, jwtOutput)
+	return
+}
+
+// DeleteAccountHandler: deletes the current user, the account can be restored by an admin until it is purged
+func (handler *AccountHandler) DeleteAccountHandler(c *gin.Context) {
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	var request deleteAccountRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	purgeAt, err := handler.accountService.Delete(user, request.Password)
+	if err != nil {
+		if err == service.ErrPasswordMismatch {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "password is incorrect"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "account has been deleted",
+		"purge_at": purgeAt.Format(time.RFC3339),
+	})
+	return
+}
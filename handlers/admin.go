@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/rbac"
+	"github.com/skamranahmed/smilecook/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultUsersPerPage int64 = 20
+	maxUsersPerPage     int64 = 100
+)
+
+type AdminHandler struct {
+	ctx                  context.Context
+	userService          service.UserService
+	recipeService        service.RecipeService
+	refreshTokenService  service.RefreshTokenService
+	revocationService    service.TokenRevocationService
+	loginThrottleService service.LoginThrottleService
+	accountService       service.AccountService
+}
+
+type updateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type banUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+type suspendUserRequest struct {
+	Until  time.Time `json:"until" binding:"required"`
+	Reason string    `json:"reason"`
+}
+
+// NewAdminHandler: used to create a new instance from the AdminHandler struct
+func NewAdminHandler(ctx context.Context, userService service.UserService, recipeService service.RecipeService, refreshTokenService service.RefreshTokenService, revocationService service.TokenRevocationService, loginThrottleService service.LoginThrottleService, accountService service.AccountService) *AdminHandler {
+	return &AdminHandler{
+		ctx:                  ctx,
+		userService:          userService,
+		recipeService:        recipeService,
+		refreshTokenService:  refreshTokenService,
+		revocationService:    revocationService,
+		loginThrottleService: loginThrottleService,
+		accountService:       accountService,
+	}
+}
+
+// ListUsersHandler: lists the users, `q` searches by username or email prefix, `page` and `per_page` paginate the result
+func (handler *AdminHandler) ListUsersHandler(c *gin.Context) {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "page must be a positive integer"})
+		return
+	}
+
+	perPage, err := strconv.ParseInt(c.DefaultQuery("per_page", strconv.FormatInt(defaultUsersPerPage, 10)), 10, 64)
+	if err != nil || perPage < 1 || perPage > maxUsersPerPage {
+		errMsg := fmt.Sprintf("per_page must be between 1 and %d", maxUsersPerPage)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	users, total, err := handler.userService.List(c.Query("q"), (page-1)*perPage, perPage)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":    users,
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+	})
+	return
+}
+
+// GetUserHandler: fetches a single user
+func (handler *AdminHandler) GetUserHandler(c *gin.Context) {
+	user, err := handler.userService.FindOne(c.Param("username"))
+	if err != nil {
+		abortWithUserLookupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+	return
+}
+
+// ListUserRecipesHandler: lists every recipe of a user, including the private ones
+func (handler *AdminHandler) ListUserRecipesHandler(c *gin.Context) {
+	user, err := handler.userService.FindOne(c.Param("username"))
+	if err != nil {
+		abortWithUserLookupError(c, err)
+		return
+	}
+
+	recipes, err := handler.recipeService.FetchAllByAuthorID(user.ID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, recipes)
+	return
+}
+
+// UpdateRoleHandler: promotes or demotes a user, the sessions of the user are revoked so that the new role applies right away
+func (handler *AdminHandler) UpdateRoleHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	var request updateRoleRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := rbac.Role(request.Role)
+	if !role.IsValid() {
+		errMsg := fmt.Sprintf("invalid role: %s", request.Role)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	if !handler.isSomeoneElse(c, username) {
+		return
+	}
+
+	target, err := handler.userService.FindOne(username)
+	if err != nil {
+		abortWithUserLookupError(c, err)
+		return
+	}
+
+	updated, err := handler.userService.UpdateRole(username, string(role), role == rbac.RoleAdmin)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !updated {
+		abortWithUserLookupError(c, mongo.ErrNoDocuments)
+		return
+	}
+
+	err = handler.revokeAllSessions(target.ID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("user %s is now %s", username, role)})
+	return
+}
+
+// BanUserHandler: bans a user until the ban is lifted and revokes all of their sessions
+func (handler *AdminHandler) BanUserHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	// the request body is optional
+	var request banUserRequest
+	c.ShouldBindJSON(&request)
+
+	now := time.Now()
+	handler.moderate(c, username, &now, nil, request.Reason, fmt.Sprintf("user %s has been banned", username))
+	return
+}
+
+// SuspendUserHandler: suspends a user until the provided time and revokes all of their sessions
+func (handler *AdminHandler) SuspendUserHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	var request suspendUserRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !request.Until.After(time.Now()) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "until must be in the future"})
+		return
+	}
+
+	handler.moderate(c, username, nil, &request.Until, request.Reason, fmt.Sprintf("user %s has been suspended", username))
+	return
+}
+
+// ReinstateUserHandler: lifts the ban or the suspension of a user
+func (handler *AdminHandler) ReinstateUserHandler(c *gin.Context) {
+	username := c.Param("username")
+	handler.moderate(c, username, nil, nil, "", fmt.Sprintf("user %s has been reinstated", username))
+	return
+}
+
+// RevokeSessionsHandler: revokes every access token and refresh token of a user
+func (handler *AdminHandler) RevokeSessionsHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	target, err := handler.userService.FindOne(username)
+	if err != nil {
+		abortWithUserLookupError(c, err)
+		return
+	}
+
+	err = handler.revokeAllSessions(target.ID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("all sessions of user %s have been revoked", username)})
+	return
+}
+
+// UnlockUserHandler: lifts the sign in lockout of a user
+func (handler *AdminHandler) UnlockUserHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	err := handler.loginThrottleService.Unlock(username)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("user %s has been unlocked", username)})
+	return
+}
+
+// RestoreUserHandler: restores a deleted user whose account has not been purged yet
+func (handler *AdminHandler) RestoreUserHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	restored, err := handler.accountService.Restore(username)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !restored {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "no deleted user with this username"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("user %s has been restored", username)})
+	return
+}
+
+// moderate : updates the ban and suspension state of the user, banning or suspending also revokes all of their sessions
+func (handler *AdminHandler) moderate(c *gin.Context, username string, bannedAt *time.Time, suspendedUntil *time.Time, reason string, message string) {
+	if !handler.isSomeoneElse(c, username) {
+		return
+	}
+
+	// a moderator must not be able to ban an admin (or another moderator)
+	target, err := handler.userService.FindOne(username)
+	if err != nil {
+		abortWithUserLookupError(c, err)
+		return
+	}
+
+	jwtAuthToken, _ := c.Get("auth")
+	if !jwtAuthToken.(*Claims).Subject().Role.Outranks(rbac.RoleOf(target)) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "you cannot moderate a user with the same or a higher role"})
+		return
+	}
+
+	updated, err := handler.userService.UpdateModeration(username, bannedAt, suspendedUntil, reason)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !updated {
+		abortWithUserLookupError(c, mongo.ErrNoDocuments)
+		return
+	}
+
+	if bannedAt != nil || suspendedUntil != nil {
+		err = handler.revokeAllSessions(target.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// isSomeoneElse : admins cannot change their own role or moderate themselves, this prevents locking every admin out by accident
+func (handler *AdminHandler) isSomeoneElse(c *gin.Context, username string) bool {
+	jwtAuthToken, exists := c.Get("auth")
+	if !exists {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return false
+	}
+
+	jwtAuthPayload, ok := jwtAuthToken.(*Claims)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return false
+	}
+
+	if jwtAuthPayload.Username == username {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "you cannot perform this action on your own account"})
+		return false
+	}
+	return true
+}
+
+// revokeAllSessions : revokes every refresh token and every access token of the user
+func (handler *AdminHandler) revokeAllSessions(userID primitive.ObjectID) error {
+	err := handler.refreshTokenService.RevokeAllForUser(userID)
+	if err != nil {
+		return err
+	}
+
+	return handler.revocationService.RevokeAllForUser(userID)
+}
+
+// abortWithUserLookupError : responds with a 404 if no user was found, 500 otherwise
+func abortWithUserLookupError(c *gin.Context, err error) {
+	if err == mongo.ErrNoDocuments {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// abortIfBlocked : responds with a 403 if the user is banned or currently suspended
+func abortIfBlocked(c *gin.Context, user *models.User) bool {
+	if !user.IsBlocked(time.Now()) {
+		return false
+	}
+
+	response := gin.H{"error": "account is suspended", "reason": user.ModerationReason}
+	if user.BannedAt != nil {
+		response["error"] = "account is banned"
+	} else {
+		response["suspended_until"] = user.SuspendedUntil
+	}
+
+	c.AbortWithStatusJSON(http.StatusForbidden, response)
+	return true
+}
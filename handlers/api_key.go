@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/rbac"
+	"github.com/skamranahmed/smilecook/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/net/context"
+)
+
+type APIKeysHandler struct {
+	ctx           context.Context
+	apiKeyService service.APIKeyService
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type createAPIKeyResponse struct {
+	Key string `json:"key"`
+	*models.APIKey
+}
+
+// NewAPIKeysHandler: used to create a new instance from the APIKeysHandler struct
+func NewAPIKeysHandler(ctx context.Context, apiKeyService service.APIKeyService) *APIKeysHandler {
+	return &APIKeysHandler{
+		ctx:           ctx,
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKeyHandler: creates a new api key, the key itself is only returned in this response
+func (handler *APIKeysHandler) CreateAPIKeyHandler(c *gin.Context) {
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	var request createAPIKeyRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	for _, scope := range request.Scopes {
+		if !rbac.Scope(scope).IsValid() {
+			errMsg := fmt.Sprintf("invalid scope: %s", scope)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	plainTextKey, apiKey, err := handler.apiKeyService.Create(user, request.Name, request.Scopes, request.ExpiresAt)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, createAPIKeyResponse{Key: plainTextKey, APIKey: apiKey})
+	return
+}
+
+// ListAPIKeysHandler: lists the api keys of the user
+func (handler *APIKeysHandler) ListAPIKeysHandler(c *gin.Context) {
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	apiKeys, err := handler.apiKeyService.FetchAllByUserID(user.ID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
+	return
+}
+
+// RevokeAPIKeyHandler: revokes an api key of the user
+func (handler *APIKeysHandler) RevokeAPIKeyHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revoked, err := handler.apiKeyService.Revoke(objectID, user.ID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !revoked {
+		errMsg := fmt.Sprintf("no active api key found with id: %s", id)
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": errMsg})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+	return
+}
+
+// authenticatedUser : returns the user record that was loaded by the AuthMiddleware
+func authenticatedUser(c *gin.Context) (*models.User, bool) {
+	authUser, exists := c.Get("user")
+	if !exists {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	user, ok := authUser.(*models.User)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return user, true
+}
@@ -2,38 +2,101 @@ package handlers
 
 import (
 	"fmt"
+	"html/template"
+	"log"
 	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/skamranahmed/smilecook/config"
+	"github.com/skamranahmed/smilecook/keyring"
 	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/rbac"
 	"github.com/skamranahmed/smilecook/service"
+	"github.com/skamranahmed/smilecook/validation"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/net/context"
 )
 
+// maxUserAgentLength : longer user agents are cut when they are recorded on a session
+const maxUserAgentLength int = 512
+
 type Claims struct {
-	Username string `json:"username"`
-	IsAdmin  bool   `json:"is_admin"`
+	Username  string `json:"username"`
+	IsAdmin   bool   `json:"is_admin"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"` // the refresh token family the access token was issued for
+	// IssuedAtMs : the issue time in milliseconds, iat is in seconds and cannot tell a token issued right after a revocation
+	// from one issued right before it
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
 	jwt.StandardClaims
+
+	// set when the request was authenticated with an api key instead of a jwt
+	APIKeyID string   `json:"-"`
+	Scopes   []string `json:"-"`
+}
+
+// IssuedAtTime : the time the token was issued, to the millisecond unless it was issued before iat_ms was introduced
+func (claims *Claims) IssuedAtTime() time.Time {
+	if claims.IssuedAtMs != 0 {
+		return time.UnixMilli(claims.IssuedAtMs)
+	}
+	return time.Unix(claims.IssuedAt, 0)
+}
+
+// HasScope : requests authenticated with a jwt are not restricted by scopes, api keys only get the scopes they were created with
+func (claims *Claims) HasScope(scope rbac.Scope) bool {
+	if claims.APIKeyID == "" {
+		return true
+	}
+
+	for _, s := range claims.Scopes {
+		if rbac.Scope(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Subject : returns the caller the rbac policies are evaluated for, the `sub` claim holds the ID of the user
+// the method shadows the promoted StandardClaims.Subject field, use claims.StandardClaims.Subject to read the claim
+func (claims *Claims) Subject() rbac.Subject {
+	userID, _ := primitive.ObjectIDFromHex(claims.StandardClaims.Subject)
+	return rbac.Subject{
+		UserID:   userID,
+		Username: claims.Username,
+		Role:     rbac.Resolve(claims.Role, claims.IsAdmin),
+	}
 }
 
 type JWTOutput struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Token                 string    `json:"token"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
 }
 type AuthHandler struct {
-	ctx         context.Context
-	collection  *mongo.Collection
-	userService service.UserService
+	ctx                      context.Context
+	collection               *mongo.Collection
+	userService              service.UserService
+	refreshTokenService      service.RefreshTokenService
+	revocationService        service.TokenRevocationService
+	passwordResetService     service.PasswordResetService
+	emailVerificationService service.EmailVerificationService
+	loginThrottleService     service.LoginThrottleService
+	twoFactorService         service.TwoFactorService
+	keyring                  *keyring.Keyring
 }
 
 type userSignUpRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Email    string `json:"email"`
 }
 
 type userSignInRequest struct {
@@ -41,11 +104,43 @@ type userSignInRequest struct {
 	Password string `json:"password"`
 }
 
-func NewAuthHandler(ctx context.Context, collection *mongo.Collection, userService service.UserService) *AuthHandler {
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type signOutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type forgotPasswordRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+type updateEmailRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+func NewAuthHandler(ctx context.Context, collection *mongo.Collection, userService service.UserService, refreshTokenService service.RefreshTokenService, revocationService service.TokenRevocationService, passwordResetService service.PasswordResetService, emailVerificationService service.EmailVerificationService, loginThrottleService service.LoginThrottleService, twoFactorService service.TwoFactorService, keyring *keyring.Keyring) *AuthHandler {
 	return &AuthHandler{
-		ctx:         ctx,
-		collection:  collection,
-		userService: userService,
+		ctx:                      ctx,
+		collection:               collection,
+		userService:              userService,
+		refreshTokenService:      refreshTokenService,
+		revocationService:        revocationService,
+		passwordResetService:     passwordResetService,
+		emailVerificationService: emailVerificationService,
+		loginThrottleService:     loginThrottleService,
+		twoFactorService:         twoFactorService,
+		keyring:                  keyring,
 	}
 }
 
@@ -58,6 +153,32 @@ func (handler *AuthHandler) SignUpHandler(c *gin.Context) {
 		return
 	}
 
+	// the username is stored as it is validated, surrounding spaces are never part of it
+	request.Username = strings.TrimSpace(request.Username)
+
+	// every rule is checked up front so that all the problems are reported at once
+	fieldErrors := validation.ValidateUsername(request.Username)
+	var email string
+	if request.Email != "" {
+		email, err = normalizeEmail(request.Email)
+		if err != nil {
+			fieldErrors = append(fieldErrors, validation.FieldError{Field: "email", Code: "invalid", Message: err.Error()})
+		}
+	}
+
+	err = handler.userService.ValidatePassword(request.Password, request.Username, email)
+	if passwordErrors, ok := err.(validation.Errors); ok {
+		fieldErrors = append(fieldErrors, passwordErrors...)
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(fieldErrors) > 0 {
+		abortWithValidationErrors(c, fieldErrors)
+		return
+	}
+
 	usernameAlreadyExists, err := handler.userService.DoesUsernameAlreadyExist(request.Username)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -69,6 +190,20 @@ func (handler *AuthHandler) SignUpHandler(c *gin.Context) {
 		return
 	}
 
+	// email is optional during sign up
+	if email != "" {
+		emailAlreadyExists, err := handler.userService.DoesEmailAlreadyExist(email)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if emailAlreadyExists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "email already exists"})
+			return
+		}
+	}
+
 	// hash the password before saving in db
 	hashedPassword, err := handler.userService.HashPassword(request.Password)
 	if err != nil {
@@ -83,6 +218,7 @@ func (handler *AuthHandler) SignUpHandler(c *gin.Context) {
 		Username:  request.Username,
 		Password:  hashedPassword,
 		IsAdmin:   false, // always false in this handler
+		Email:     email,
 	}
 
 	err = handler.userService.Create(user)
@@ -92,6 +228,14 @@ func (handler *AuthHandler) SignUpHandler(c *gin.Context) {
 		return
 	}
 
+	if user.Email != "" {
+		// the account is usable even if the mail could not be sent, the user can request a new link
+		err = handler.emailVerificationService.SendVerification(user)
+		if err != nil {
+			log.Printf("unable to send verification email to user: %s, err: %v\n", user.Username, err)
+		}
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"message": "signup successfull"})
 	return
 }
@@ -105,12 +249,31 @@ func (handler *AuthHandler) SignInHandler(c *gin.Context) {
 		return
 	}
 
+	clientIP := c.ClientIP()
+
+	// reject the attempt right away if the username or the client ip is locked out or backing off
+	throttle, err := handler.loginThrottleService.Check(request.Username, clientIP)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if throttle.RetryAfter > 0 {
+		reason := "backoff"
+		if throttle.Locked {
+			reason = "locked"
+		}
+		SignInLockoutsTotal.WithLabelValues(reason).Inc()
+		abortWithRetryAfter(c, http.StatusTooManyRequests, throttle.RetryAfter, "too many failed sign in attempts, try again later")
+		return
+	}
+
 	// find a user with the requested username
 	user, err := handler.userService.FindOne(request.Username)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			// no user record found
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid username or password"})
+			// no user record found, this counts as a failed attempt as well
+			handler.handleSignInFailure(c, request.Username, clientIP, "unknown_user")
 			return
 		}
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -120,73 +283,510 @@ func (handler *AuthHandler) SignInHandler(c *gin.Context) {
 	// verify the password
 	err = handler.userService.VerifyPassword(request.Password, user.Password)
 	if err != nil {
+		if err == service.ErrPasswordMismatch {
+			handler.handleSignInFailure(c, request.Username, clientIP, "wrong_password")
+			return
+		}
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	expirationTime := time.Now().Add(10 * time.Minute)
-	claims := &Claims{
-		Username: user.Username,
-		IsAdmin:  user.IsAdmin,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
+	err = handler.loginThrottleService.RegisterSuccess(user.Username)
+	if err != nil {
+		log.Printf("unable to reset failed sign in attempts of user: %s, err: %v\n", user.Username, err)
+	}
+
+	// the ban status is only disclosed to someone who knows the password
+	if abortIfBlocked(c, user) {
+		return
+	}
+
+	// with two-factor authentication enabled, the password alone only buys a short-lived mfa pending token
+	if user.TOTPEnabled {
+		// the plain text password is only known here, an upgraded hash is staged and saved once the second factor is verified
+		_, err = handler.userService.StagePasswordRehash(user, request.Password)
+		if err != nil {
+			log.Printf("unable to stage the upgraded password hash of user: %s, err: %v\n", user.Username, err)
+		}
+
+		mfaPendingOutput, err := handler.newMFAPendingOutput(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, mfaPendingOutput)
+		return
+	}
+
+	// the plain text password is only known here, so hashes with outdated parameters are upgraded on the fly
+	_, err = handler.userService.RehashPasswordIfNeeded(user, request.Password)
+	if err != nil {
+		log.Printf("unable to upgrade the password hash of user: %s, err: %v\n", user.Username, err)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.JWTSecretKey))
+	jwtOutput, err := handler.issueTokens(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	jwtOutput := JWTOutput{
-		Token:     tokenString,
-		ExpiresAt: expirationTime,
-	}
 
 	c.JSON(http.StatusOK, jwtOutput)
 	return
 }
 
-// RefreshHandler: refreshes the token
+// RefreshHandler: exchanges a refresh token for a new access token and refresh token pair
 func (handler *AuthHandler) RefreshHandler(c *gin.Context) {
-	// TODO: add a middleware to extract the auth header and process the validation logic
-	tokenValue := c.GetHeader("Authorization")
-	claims := &Claims{}
-	tkn, err := jwt.ParseWithClaims(tokenValue, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.JWTSecretKey), nil
-	})
-
+	var request refreshTokenRequest
+	err := c.ShouldBindJSON(&request)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if tkn == nil || !tkn.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	// the old refresh token is rotated, it can never be used again
+	refreshTokenValue, refreshToken, err := handler.refreshTokenService.Rotate(request.RefreshToken, clientInfo(c))
+	if err != nil {
+		if err == service.ErrInvalidRefreshToken || err == service.ErrRefreshTokenReused {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if time.Unix(claims.ExpiresAt, 0).Sub(time.Now()) > 30*time.Second {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is not expired yet"})
+	user, err := handler.userService.FindOneByID(refreshToken.UserID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user no longer exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	expirationTime := time.Now().Add(5 * time.Minute)
-	claims.ExpiresAt = expirationTime.Unix()
+	if abortIfBlocked(c, user) {
+		return
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(config.JWTSecretKey)
+	jwtOutput, err := handler.newJWTOutput(user, refreshTokenValue, refreshToken)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	jwtOutput := JWTOutput{
-		Token:     tokenString,
-		ExpiresAt: expirationTime,
+	c.JSON(http.StatusOK, jwtOutput)
+	return
+}
+
+// SignOutHandler: revokes the access token used for the request and, if provided, the refresh token family
+func (handler *AuthHandler) SignOutHandler(c *gin.Context) {
+	// extract the payload from the context that was set by the AuthMiddleware
+	jwtAuthToken, exists := c.Get("auth")
+	if !exists {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	jwtAuthPayload, ok := jwtAuthToken.(*Claims)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
 	}
 
-	c.JSON(http.StatusOK, jwtOutput)
+	// the request body is optional
+	var request signOutRequest
+	c.ShouldBindJSON(&request)
+
+	err := handler.revocationService.RevokeToken(jwtAuthPayload.Id, time.Unix(jwtAuthPayload.ExpiresAt, 0))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the other access tokens of the session, e.g. the ones issued on refresh, are revoked too
+	if jwtAuthPayload.SessionID != "" {
+		err = handler.revocationService.RevokeSession(jwtAuthPayload.SessionID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if request.RefreshToken != "" {
+		err = handler.refreshTokenService.Revoke(request.RefreshToken)
+		if err != nil && err != service.ErrInvalidRefreshToken {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "signed out"})
+	return
+}
+
+// SignOutAllHandler: revokes every access token and refresh token of the user
+func (handler *AuthHandler) SignOutAllHandler(c *gin.Context) {
+	// extract the payload from the context that was set by the AuthMiddleware
+	jwtAuthToken, exists := c.Get("auth")
+	if !exists {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	jwtAuthPayload, ok := jwtAuthToken.(*Claims)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	err := handler.revokeAllSessions(jwtAuthPayload.Subject().UserID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "signed out from all sessions"})
+	return
+}
+
+// ForgotPasswordHandler: sends a password reset token to the user
+func (handler *AuthHandler) ForgotPasswordHandler(c *gin.Context) {
+	var request forgotPasswordRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = handler.passwordResetService.RequestReset(request.Username)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the response is the same whether the user exists or not
+	c.JSON(http.StatusOK, gin.H{"message": "if the account exists, a password reset token has been sent"})
+	return
+}
+
+// ResetPasswordHandler: sets a new password using a password reset token and signs the user out everywhere
+func (handler *AuthHandler) ResetPasswordHandler(c *gin.Context) {
+	var request resetPasswordRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passwordResetToken, err := handler.passwordResetService.Reset(request.Token, request.NewPassword)
+	if err != nil {
+		if err == service.ErrInvalidPasswordResetToken {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if fieldErrors, ok := err.(validation.Errors); ok {
+			abortWithValidationErrors(c, renameField(fieldErrors, "password", "new_password"))
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = handler.revokeAllSessions(passwordResetToken.UserID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+	return
+}
+
+// UpdateEmailHandler: sets a new email for the user and sends a verification link to it
+func (handler *AuthHandler) UpdateEmailHandler(c *gin.Context) {
+	// extract the payload from the context that was set by the AuthMiddleware
+	jwtAuthToken, exists := c.Get("auth")
+	if !exists {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	jwtAuthPayload, ok := jwtAuthToken.(*Claims)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	var request updateEmailRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email, err := normalizeEmail(request.Email)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	emailAlreadyExists, err := handler.userService.DoesEmailAlreadyExist(email)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if emailAlreadyExists {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "email already exists"})
+		return
+	}
+
+	_, err = handler.userService.UpdateEmail(jwtAuthPayload.Username, email)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := handler.userService.FindOne(jwtAuthPayload.Username)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = handler.emailVerificationService.SendVerification(user)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "a verification link has been sent to the new email"})
+	return
+}
+
+// ResendEmailVerificationHandler: sends a new verification link to the current email of the user
+func (handler *AuthHandler) ResendEmailVerificationHandler(c *gin.Context) {
+	// extract the payload from the context that was set by the AuthMiddleware
+	jwtAuthToken, exists := c.Get("auth")
+	if !exists {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	jwtAuthPayload, ok := jwtAuthToken.(*Claims)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := handler.userService.FindOne(jwtAuthPayload.Username)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if user.Email == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "no email has been set for the account"})
+		return
+	}
+
+	if user.EmailVerified {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "email is already verified"})
+		return
+	}
+
+	err = handler.emailVerificationService.SendVerification(user)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "a verification link has been sent"})
+	return
+}
+
+// verifyEmailPage : the page of the verification link, the token is only consumed when the user submits the form
+// so that the mail scanners and link previews that open the link do not use it up
+var verifyEmailPage = template.Must(template.New("verify_email").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Verify your email</title></head>
+<body>
+<form method="post" action="/email/verify">
+<input type="hidden" name="token" value="{{.}}">
+<button type="submit">Verify my email</button>
+</form>
+</body>
+</html>
+`))
+
+// CheckEmailVerificationHandler: the page the verification link opens, it checks the token without consuming it
+// and asks the user to confirm
+func (handler *AuthHandler) CheckEmailVerificationHandler(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	err := handler.emailVerificationService.Check(token)
+	if err != nil {
+		if err == service.ErrInvalidEmailVerificationToken {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	verifyEmailPage.Execute(c.Writer, token)
+	return
+}
+
+// VerifyEmailHandler: verifies the email of a user using the token from the verification link, sent as a form or as json
+func (handler *AuthHandler) VerifyEmailHandler(c *gin.Context) {
+	var request verifyEmailRequest
+	err := c.ShouldBind(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	_, err = handler.emailVerificationService.Verify(request.Token)
+	if err != nil {
+		if err == service.ErrInvalidEmailVerificationToken {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email has been verified"})
 	return
 }
+
+// handleSignInFailure : records the failed sign in attempt and responds with a 401, or a 429 if the attempt triggered a lockout
+func (handler *AuthHandler) handleSignInFailure(c *gin.Context, username string, clientIP string, reason string) {
+	SignInFailuresTotal.WithLabelValues(reason).Inc()
+
+	throttle, err := handler.loginThrottleService.RegisterFailure(username, clientIP)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if throttle.Locked {
+		SignInLockoutsTotal.WithLabelValues("locked").Inc()
+		abortWithRetryAfter(c, http.StatusTooManyRequests, throttle.RetryAfter, "too many failed sign in attempts, try again later")
+		return
+	}
+
+	if throttle.RetryAfter > 0 {
+		abortWithRetryAfter(c, http.StatusUnauthorized, throttle.RetryAfter, "invalid username or password")
+		return
+	}
+
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid username or password"})
+}
+
+// revokeAllSessions : revokes every refresh token and every access token of the user
+func (handler *AuthHandler) revokeAllSessions(userID primitive.ObjectID) error {
+	err := handler.refreshTokenService.RevokeAllForUser(userID)
+	if err != nil {
+		return err
+	}
+
+	return handler.revocationService.RevokeAllForUser(userID)
+}
+
+// JWKSHandler: publishes the public keys that can be used to verify the access tokens
+func (handler *AuthHandler) JWKSHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, handler.keyring.JWKS())
+	return
+}
+
+// issueTokens : issues a new access token and a new refresh token family, i.e. a new session, for the user
+func (handler *AuthHandler) issueTokens(c *gin.Context, user *models.User) (*JWTOutput, error) {
+	refreshTokenValue, refreshToken, err := handler.refreshTokenService.Issue(user, clientInfo(c))
+	if err != nil {
+		return nil, err
+	}
+
+	return handler.newJWTOutput(user, refreshTokenValue, refreshToken)
+}
+
+// newJWTOutput : signs a short-lived access token for the user and pairs it with the provided refresh token
+func (handler *AuthHandler) newJWTOutput(user *models.User, refreshTokenValue string, refreshToken *models.RefreshToken) (*JWTOutput, error) {
+	issuedAt := time.Now()
+	expirationTime := issuedAt.Add(config.AccessTokenTTL)
+	claims := &Claims{
+		Username:   user.Username,
+		IsAdmin:    user.IsAdmin,
+		Role:       string(rbac.RoleOf(user)),
+		SessionID:  refreshToken.FamilyID.Hex(),
+		IssuedAtMs: issuedAt.UnixMilli(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        primitive.NewObjectID().Hex(),
+			Subject:   user.ID.Hex(),
+			IssuedAt:  issuedAt.Unix(),
+			ExpiresAt: expirationTime.Unix(),
+		},
+	}
+
+	tokenString, err := handler.keyring.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWTOutput{
+		Token:                 tokenString,
+		ExpiresAt:             expirationTime,
+		RefreshToken:          refreshTokenValue,
+		RefreshTokenExpiresAt: refreshToken.ExpiresAt,
+	}, nil
+}
+
+// clientInfo : describes the device the request comes from, the user agent is capped to keep the session records small
+func clientInfo(c *gin.Context) service.ClientInfo {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return service.ClientInfo{UserAgent: userAgent, IP: c.ClientIP()}
+}
+
+// normalizeEmail : validates the email and returns it in lower case
+func normalizeEmail(email string) (string, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Name != "" {
+		return "", fmt.Errorf("invalid email: %s", email)
+	}
+	return strings.ToLower(address.Address), nil
+}
+
+// abortWithValidationErrors : responds with a 400 that lists every failed rule per field
+func abortWithValidationErrors(c *gin.Context, fieldErrors validation.Errors) {
+	c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "validation failed", "fields": fieldErrors})
+}
+
+// renameField : reports the errors under the name the field has in the request
+func renameField(fieldErrors validation.Errors, from string, to string) validation.Errors {
+	renamed := make(validation.Errors, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		if fieldError.Field == from {
+			fieldError.Field = to
+		}
+		renamed = append(renamed, fieldError)
+	}
+	return renamed
+}
+
+// abortWithRetryAfter : aborts the request and sets the Retry-After header (in whole seconds, rounded up)
+func abortWithRetryAfter(c *gin.Context, status int, retryAfter time.Duration, errMsg string) {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	c.AbortWithStatusJSON(status, gin.H{"error": errMsg, "retry_after_seconds": seconds})
+}
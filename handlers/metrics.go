@@ -0,0 +1,21 @@
+package handlers
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// SignInFailuresTotal : number of failed sign in attempts, partitioned by reason
+var SignInFailuresTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "signin_failures_total",
+		Help: "Number of failed sign in attempts",
+	},
+	[]string{"reason"},
+)
+
+// SignInLockoutsTotal : number of sign in attempts that were rejected because of a lockout or a backoff
+var SignInLockoutsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "signin_locked_total",
+		Help: "Number of sign in attempts rejected because of a lockout or a backoff",
+	},
+	[]string{"reason"},
+)
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skamranahmed/smilecook/oidc"
+	"github.com/skamranahmed/smilecook/service"
+)
+
+type OIDCHandler struct {
+	ctx                context.Context
+	socialLoginService service.SocialLoginService
+	authHandler        *AuthHandler
+}
+
+// NewOIDCHandler: used to create a new instance from the OIDCHandler struct
+// the auth handler is used to issue our own tokens once the provider has authenticated the user
+func NewOIDCHandler(ctx context.Context, socialLoginService service.SocialLoginService, authHandler *AuthHandler) *OIDCHandler {
+	return &OIDCHandler{
+		ctx:                ctx,
+		socialLoginService: socialLoginService,
+		authHandler:        authHandler,
+	}
+}
+
+// LoginHandler: redirects the user to the login page of the provider
+// api clients that follow the redirect themselves can pass ?redirect=false to receive the url as json
+func (handler *OIDCHandler) LoginHandler(c *gin.Context) {
+	authURL, err := handler.socialLoginService.BeginLogin(c.Param("provider"))
+	if err != nil {
+		if err == service.ErrUnknownOIDCProvider {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("unable to start login with provider: %s, err: %v\n", c.Param("provider"), err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "login provider is unavailable"})
+		return
+	}
+
+	if c.Query("redirect") == "false" {
+		c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+	return
+}
+
+// CallbackHandler: completes the login once the provider redirects back and issues our own tokens
+func (handler *OIDCHandler) CallbackHandler(c *gin.Context) {
+	if providerError := c.Query("error"); providerError != "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "login was not completed: " + providerError})
+		return
+	}
+
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "state and code are required"})
+		return
+	}
+
+	user, err := handler.socialLoginService.CompleteLogin(c.Param("provider"), state, code)
+	if err != nil {
+		switch {
+		case err == service.ErrUnknownOIDCProvider:
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case err == service.ErrInvalidOIDCState || errors.Is(err, oidc.ErrInvalidIDToken):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case err == service.ErrOIDCAccountConflict:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("unable to complete login with provider: %s, err: %v\n", c.Param("provider"), err)
+			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "unable to complete login with the provider"})
+		}
+		return
+	}
+
+	if abortIfBlocked(c, user) {
+		return
+	}
+
+	// the provider replaces the password, not the second factor
+	if user.TOTPEnabled {
+		mfaPendingOutput, err := handler.authHandler.newMFAPendingOutput(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, mfaPendingOutput)
+		return
+	}
+
+	jwtOutput, err := handler.authHandler.issueTokens(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, jwtOutput)
+	return
+}
@@ -1,35 +1,68 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	redis "github.com/go-redis/redis/v8"
+	"github.com/skamranahmed/smilecook/ingredient"
 	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/rbac"
+	"github.com/skamranahmed/smilecook/repository"
 	"github.com/skamranahmed/smilecook/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/net/context"
 )
 
+const (
+	defaultRecipesPerPage int64 = 20
+	maxRecipesPerPage     int64 = 100
+
+	defaultSuggestions int = 10
+	maxSuggestions     int = 25
+
+	// recipesCacheKey holds the whole list of recipes, the filtered lists and the pages are cached one per key under
+	// recipesQueryCacheKeyPrefix, with a ttl, the keys of a generation are dropped at once by bumping recipesQueriesGenerationKey
+	recipesCacheKey             string = "recipes"
+	recipesQueryCacheKeyPrefix  string = "recipes:query:"
+	recipesQueriesGenerationKey string = "recipes:queries:generation"
+
+	// legacyRecipesQueriesCacheKey : the hash the filtered lists used to be cached in, it is dropped on the next invalidation
+	legacyRecipesQueriesCacheKey string = "recipes:queries"
+)
+
 type RecipesHandler struct {
-	ctx           context.Context
-	collection    *mongo.Collection
-	redisClient   *redis.Client
-	recipeService service.RecipeService
+	ctx                 context.Context
+	collection          *mongo.Collection
+	redisClient         *redis.Client
+	recipeService       service.RecipeService
+	autocompleteService service.AutocompleteService
+	scalingService      service.ScalingService
+	nutritionService    service.NutritionService
+	queryCacheTTL       time.Duration
 }
 
 // NewRecipesHandler: used to create a new instance from the RecipesHanlder struct
-func NewRecipesHandler(ctx context.Context, collection *mongo.Collection, redisClient *redis.Client, recipeService service.RecipeService) *RecipesHandler {
+func NewRecipesHandler(ctx context.Context, collection *mongo.Collection, redisClient *redis.Client, recipeService service.RecipeService, autocompleteService service.AutocompleteService, scalingService service.ScalingService, nutritionService service.NutritionService, queryCacheTTL time.Duration) *RecipesHandler {
 	return &RecipesHandler{
-		ctx:           ctx,
-		collection:    collection,
-		redisClient:   redisClient,
-		recipeService: recipeService,
+		ctx:                 ctx,
+		collection:          collection,
+		redisClient:         redisClient,
+		recipeService:       recipeService,
+		autocompleteService: autocompleteService,
+		scalingService:      scalingService,
+		nutritionService:    nutritionService,
+		queryCacheTTL:       queryCacheTTL,
 	}
 }
 
@@ -55,7 +88,14 @@ func (handler *RecipesHandler) CreateRecipeHandler(c *gin.Context) {
 		return
 	}
 
+	err = recipe.Validate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	recipe.ID = primitive.NewObjectID()
+	recipe.AuthorID = jwtAuthPayload.Subject().UserID
 	recipe.Username = jwtAuthPayload.Username
 	recipe.PublishedAt = time.Now()
 
@@ -65,16 +105,34 @@ func (handler *RecipesHandler) CreateRecipeHandler(c *gin.Context) {
 		return
 	}
 
-	log.Println("deleting data from redis")
-	handler.redisClient.Del(handler.ctx, "recipes")
+	handler.invalidateCache()
 
 	c.JSON(http.StatusOK, recipe)
 	return
 }
 
-// ListRecipesHandler: fetches a list of recipes
+// ListRecipesHandler: fetches a list of recipes, filtered and sorted by the query params (see parseRecipeQuery),
+// `limit` and `cursor` return a page of recipes in an envelope, without them the whole list is returned as an array like before
 func (handler *RecipesHandler) ListRecipesHandler(c *gin.Context) {
-	val, err := handler.redisClient.Get(handler.ctx, "recipes").Result()
+	query, normalized, err := parseRecipeQuery(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, hasLimit := c.GetQuery("limit")
+	_, hasCursor := c.GetQuery("cursor")
+	if hasLimit || hasCursor {
+		handler.listRecipesPage(c, query, normalized)
+		return
+	}
+
+	if len(normalized) > 0 {
+		handler.listFilteredRecipes(c, query, normalized)
+		return
+	}
+
+	val, err := handler.redisClient.Get(handler.ctx, recipesCacheKey).Result()
 	if err != nil {
 		if err == redis.Nil {
 			log.Println("value not found in redis, hitting mongo db now")
@@ -83,7 +141,7 @@ func (handler *RecipesHandler) ListRecipesHandler(c *gin.Context) {
 		}
 
 		// fetch all recipes from mongo db
-		recipes, err := handler.recipeService.FetchAll()
+		recipes, err := handler.recipeService.FetchAll(query)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -91,7 +149,7 @@ func (handler *RecipesHandler) ListRecipesHandler(c *gin.Context) {
 
 		// save the data in redis
 		data, _ := json.Marshal(recipes)
-		handler.redisClient.Set(handler.ctx, "recipes", string(data), 0)
+		handler.redisClient.Set(handler.ctx, recipesCacheKey, string(data), 0)
 
 		c.JSON(http.StatusOK, recipes)
 		return
@@ -104,6 +162,114 @@ func (handler *RecipesHandler) ListRecipesHandler(c *gin.Context) {
 	return
 }
 
+// listFilteredRecipes : responds with every recipe of the query as an array
+func (handler *RecipesHandler) listFilteredRecipes(c *gin.Context, query repository.RecipeQuery, normalized url.Values) {
+	recipes := make([]*models.Recipe, 0)
+	key, cached := handler.cachedQuery("list?"+normalized.Encode(), &recipes)
+	if cached {
+		c.JSON(http.StatusOK, recipes)
+		return
+	}
+
+	recipes, err := handler.recipeService.FetchAll(query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	handler.cacheQuery(key, recipes)
+
+	c.JSON(http.StatusOK, recipes)
+	return
+}
+
+// listRecipesPage : responds with the page of recipes of the query that starts after the `cursor` query param
+func (handler *RecipesHandler) listRecipesPage(c *gin.Context, query repository.RecipeQuery, normalized url.Values) {
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.FormatInt(defaultRecipesPerPage, 10)), 10, 64)
+	if err != nil || limit < 1 || limit > maxRecipesPerPage {
+		errMsg := fmt.Sprintf("limit must be between 1 and %d", maxRecipesPerPage)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	cursor := c.Query("cursor")
+	normalized.Set("limit", strconv.FormatInt(limit, 10))
+	normalized.Set("cursor", cursor)
+
+	var cachedPage service.RecipePage
+	key, cached := handler.cachedQuery("page?"+normalized.Encode(), &cachedPage)
+	if cached {
+		c.JSON(http.StatusOK, cachedPage)
+		return
+	}
+
+	page, err := handler.recipeService.FetchPage(query, cursor, limit)
+	if err != nil {
+		if err == service.ErrInvalidCursor {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	handler.cacheQuery(key, page)
+
+	c.JSON(http.StatusOK, page)
+	return
+}
+
+// SearchRecipesHandler: searches the public recipes by name, tags, ingredients and instructions, the most relevant first
+func (handler *RecipesHandler) SearchRecipesHandler(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.FormatInt(defaultRecipesPerPage, 10)), 10, 64)
+	if err != nil || limit < 1 || limit > maxRecipesPerPage {
+		errMsg := fmt.Sprintf("limit must be between 1 and %d", maxRecipesPerPage)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	results, err := handler.recipeService.Search(query, limit)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"query": query, "results": results})
+	return
+}
+
+// AutocompleteHandler: suggests recipe names, tags and ingredients for the typed text `q`, the most popular first,
+// with a "did you mean" correction when the text has misspelled words
+func (handler *RecipesHandler) AutocompleteHandler(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSuggestions)))
+	if err != nil || limit < 1 || limit > maxSuggestions {
+		errMsg := fmt.Sprintf("limit must be between 1 and %d", maxSuggestions)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	result, err := handler.autocompleteService.Suggest(query, limit)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+	return
+}
+
 func (handler *RecipesHandler) UpdateRecipeHandler(c *gin.Context) {
 	id := c.Param("id")
 
@@ -133,6 +299,12 @@ func (handler *RecipesHandler) UpdateRecipeHandler(c *gin.Context) {
 		return
 	}
 
+	err = recipe.Validate()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// find a recipe with the requested id
 	recipeRecord, err := handler.recipeService.FindOne(objectID)
 	if err != nil {
@@ -146,9 +318,9 @@ func (handler *RecipesHandler) UpdateRecipeHandler(c *gin.Context) {
 		return
 	}
 
-	if recipeRecord.Username != jwtAuthPayload.Username {
-		errMsg := fmt.Sprintf("you are not the author of the recipe")
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errMsg})
+	if !rbac.CanUpdateRecipe(jwtAuthPayload.Subject(), recipeRecord) {
+		errMsg := fmt.Sprintf("you are not allowed to update this recipe")
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errMsg})
 		return
 	}
 
@@ -165,8 +337,7 @@ func (handler *RecipesHandler) UpdateRecipeHandler(c *gin.Context) {
 		return
 	}
 
-	log.Println("deleting data from redis")
-	handler.redisClient.Del(handler.ctx, "recipes")
+	handler.invalidateCache()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Recipe has been updated"})
 	return
@@ -206,9 +377,9 @@ func (handler *RecipesHandler) DeleteRecipeHandler(c *gin.Context) {
 		return
 	}
 
-	if recipe.Username != jwtAuthPayload.Username {
-		errMsg := fmt.Sprintf("you are not the author of the recipe")
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errMsg})
+	if !rbac.CanDeleteRecipe(jwtAuthPayload.Subject(), recipe) {
+		errMsg := fmt.Sprintf("you are not allowed to delete this recipe")
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errMsg})
 		return
 	}
 
@@ -225,30 +396,110 @@ func (handler *RecipesHandler) DeleteRecipeHandler(c *gin.Context) {
 		return
 	}
 
+	handler.invalidateCache()
+
 	c.JSON(http.StatusNoContent, nil)
 	return
 }
 
+// GetOneRecipeHandler: fetches a recipe, `servings` scales its ingredients to that number of servings
 func (handler *RecipesHandler) GetOneRecipeHandler(c *gin.Context) {
+	recipe, ok := handler.findReadableRecipe(c)
+	if !ok {
+		return
+	}
+
+	value, scale := c.GetQuery("servings")
+	if !scale {
+		c.JSON(http.StatusOK, recipe)
+		return
+	}
+
+	servings, err := strconv.Atoi(value)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": service.ErrInvalidServings.Error()})
+		return
+	}
+
+	scaled, err := handler.scalingService.Scale(recipe, servings)
+	if err != nil {
+		if err == service.ErrInvalidServings || err == service.ErrServingsUnknown {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scaled)
+	return
+}
+
+// ListIngredientsHandler: lists the ingredients of a recipe split into quantities, units and items,
+// `system` (metric or imperial) converts them
+func (handler *RecipesHandler) ListIngredientsHandler(c *gin.Context) {
+	system := ingredient.System(c.Query("system"))
+	if system != "" && !system.IsValid() {
+		errMsg := fmt.Sprintf("system must be %s or %s", ingredient.Metric, ingredient.Imperial)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	recipe, ok := handler.findReadableRecipe(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recipe_id":   recipe.ID,
+		"ingredients": handler.recipeService.Ingredients(recipe, system),
+	})
+	return
+}
+
+// NutritionHandler: estimates the calories, macros and key micronutrients per serving of a recipe from its ingredients,
+// each ingredient line says which food it was matched with and how confident the estimate is
+func (handler *RecipesHandler) NutritionHandler(c *gin.Context) {
+	recipe, ok := handler.findReadableRecipe(c)
+	if !ok {
+		return
+	}
+
+	estimate, err := handler.nutritionService.Estimate(recipe)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recipe_id": recipe.ID,
+		"nutrition": estimate,
+	})
+	return
+}
+
+// findReadableRecipe : finds the recipe of the `id` path param, it responds with an error and returns false
+// if there is no such recipe or the caller may not read it
+func (handler *RecipesHandler) findReadableRecipe(c *gin.Context) (*models.Recipe, bool) {
 	id := c.Param("id")
 
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return nil, false
 	}
 
 	// extract the payload from the context that was set by the AuthMiddleware
 	jwtAuthToken, exists := c.Get("auth")
 	if !exists {
 		c.AbortWithStatus(http.StatusUnauthorized)
-		return
+		return nil, false
 	}
 
 	jwtAuthPayload, ok := jwtAuthToken.(*Claims)
 	if !ok {
 		c.AbortWithStatus(http.StatusUnauthorized)
-		return
+		return nil, false
 	}
 
 	// find a recipe with the requested id
@@ -258,19 +509,66 @@ func (handler *RecipesHandler) GetOneRecipeHandler(c *gin.Context) {
 			// no recipe record found
 			errMsg := fmt.Sprintf("no recipe found with id: %s", id)
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": errMsg})
-			return
+			return nil, false
 		}
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, false
 	}
 
-	// recipe is NOT private or the owner of the recipe themself is fetching the recipe
-	if !recipe.IsPrivate || recipe.Username == jwtAuthPayload.Username {
-		c.JSON(http.StatusOK, recipe)
+	// recipe is NOT private, the owner of the recipe themself is fetching the recipe or the caller may read any recipe
+	if !rbac.CanReadRecipe(jwtAuthPayload.Subject(), recipe) {
+		errMsg := fmt.Sprintf("recipe is private")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errMsg})
+		return nil, false
+	}
+
+	return recipe, true
+}
+
+// cachedQuery : decodes the cached response of a filtered list or a page into v, returns the key to cache the response under
+// and whether it was cached. The key is empty if redis cannot be reached, the response is not cached then
+func (handler *RecipesHandler) cachedQuery(field string, v interface{}) (string, bool) {
+	generation, err := handler.redisClient.Get(handler.ctx, recipesQueriesGenerationKey).Result()
+	if err != nil && err != redis.Nil {
+		log.Printf("error in retrieving value from redis, err: %v, hitting mongo db now\n", err)
+		return "", false
+	}
+
+	// the field holds user input, it is hashed to bound the length of the key
+	sum := sha256.Sum256([]byte(field))
+	key := recipesQueryCacheKeyPrefix + generation + ":" + hex.EncodeToString(sum[:])
+
+	val, err := handler.redisClient.Get(handler.ctx, key).Result()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("error in retrieving value from redis, err: %v, hitting mongo db now\n", err)
+		}
+		return key, false
+	}
+
+	log.Println("request to redis")
+	return key, json.Unmarshal([]byte(val), v) == nil
+}
+
+// cacheQuery : saves the response of a filtered list or a page under the key returned by cachedQuery, it expires after the query cache ttl
+func (handler *RecipesHandler) cacheQuery(key string, v interface{}) {
+	if key == "" {
 		return
 	}
 
-	errMsg := fmt.Sprintf("recipe is private")
-	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errMsg})
-	return
+	data, _ := json.Marshal(v)
+	handler.redisClient.Set(handler.ctx, key, string(data), handler.queryCacheTTL)
+}
+
+// invalidateCache : drops the cached lists and pages of recipes and the autocomplete entries after a recipe has changed,
+// the cached queries of the previous generation are no longer read and expire on their own
+func (handler *RecipesHandler) invalidateCache() {
+	log.Println("deleting data from redis")
+	handler.redisClient.Del(handler.ctx, recipesCacheKey, legacyRecipesQueriesCacheKey)
+	handler.redisClient.Incr(handler.ctx, recipesQueriesGenerationKey)
+
+	err := handler.autocompleteService.Invalidate()
+	if err != nil {
+		log.Printf("unable to invalidate the autocomplete entries, err: %v\n", err)
+	}
 }
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skamranahmed/smilecook/dietary"
+	"github.com/skamranahmed/smilecook/duration"
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/repository"
+)
+
+// parseRecipeQuery : reads the filters and the order of the recipe list from the query params
+//
+//	tag               repeatable or comma separated
+//	tag_match         any (default) or all
+//	author            username of the author
+//	ingredient        case insensitive substring of an ingredient
+//	published_after   RFC 3339 time or YYYY-MM-DD date, inclusive
+//	published_before  RFC 3339 time or YYYY-MM-DD date, inclusive
+//	diet              repeatable or comma separated, a recipe must suit all of them
+//	exclude_allergen  repeatable or comma separated, a recipe must contain none of them
+//	min_prep_time     ISO 8601 (PT30M) or Go (30m) duration, inclusive, also max_prep_time,
+//	                  min_cook_time, max_cook_time, min_total_time and max_total_time
+//	difficulty        repeatable or comma separated, easy, medium or hard
+//	cuisine           repeatable or comma separated, case insensitive
+//	course            repeatable or comma separated, see models.Courses
+//	sort              newest, oldest or name
+//
+// it also returns the normalized query params, two requests with the same normalized params list the same recipes
+func parseRecipeQuery(c *gin.Context) (repository.RecipeQuery, url.Values, error) {
+	var query repository.RecipeQuery
+	normalized := url.Values{}
+
+	seen := make(map[string]bool)
+	for _, value := range c.QueryArray("tag") {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			query.Tags = append(query.Tags, tag)
+		}
+	}
+	sort.Strings(query.Tags)
+	for _, tag := range query.Tags {
+		normalized.Add("tag", tag)
+	}
+
+	switch tagMatch := c.DefaultQuery("tag_match", "any"); tagMatch {
+	case "any":
+	case "all":
+		query.MatchAllTags = true
+		if len(query.Tags) > 1 {
+			normalized.Set("tag_match", tagMatch)
+		}
+	default:
+		return query, nil, errors.New("tag_match must be any or all")
+	}
+
+	query.Username = strings.TrimSpace(c.Query("author"))
+	if query.Username != "" {
+		normalized.Set("author", query.Username)
+	}
+
+	query.Ingredient = strings.ToLower(strings.TrimSpace(c.Query("ingredient")))
+	if query.Ingredient != "" {
+		normalized.Set("ingredient", query.Ingredient)
+	}
+
+	var err error
+	query.PublishedAfter, err = parseRecipeDate(c, "published_after", false, normalized)
+	if err != nil {
+		return query, nil, err
+	}
+
+	query.PublishedBefore, err = parseRecipeDate(c, "published_before", true, normalized)
+	if err != nil {
+		return query, nil, err
+	}
+
+	for _, name := range splitQueryArray(c, "diet") {
+		diet, err := dietary.ParseDiet(name)
+		if err != nil {
+			return query, nil, err
+		}
+		query.Diets = append(query.Diets, diet)
+		normalized.Add("diet", string(diet))
+	}
+
+	for _, name := range splitQueryArray(c, "exclude_allergen") {
+		allergen, err := dietary.ParseAllergen(name)
+		if err != nil {
+			return query, nil, err
+		}
+		query.ExcludeAllergen = append(query.ExcludeAllergen, allergen)
+		normalized.Add("exclude_allergen", string(allergen))
+	}
+
+	timeRanges := []struct {
+		param     string
+		timeRange *repository.DurationRange
+	}{
+		{"prep_time", &query.PrepTime},
+		{"cook_time", &query.CookTime},
+		{"total_time", &query.TotalTime},
+	}
+	for _, t := range timeRanges {
+		t.timeRange.Min, err = parseRecipeDuration(c, "min_"+t.param, normalized)
+		if err != nil {
+			return query, nil, err
+		}
+		t.timeRange.Max, err = parseRecipeDuration(c, "max_"+t.param, normalized)
+		if err != nil {
+			return query, nil, err
+		}
+	}
+
+	for _, name := range splitQueryArray(c, "difficulty") {
+		difficulty := models.Difficulty(name)
+		if !difficulty.IsValid() {
+			return query, nil, fmt.Errorf("unknown difficulty: %s", name)
+		}
+		query.Difficulties = append(query.Difficulties, difficulty)
+		normalized.Add("difficulty", name)
+	}
+
+	for _, cuisine := range splitQueryArray(c, "cuisine") {
+		query.Cuisines = append(query.Cuisines, cuisine)
+		normalized.Add("cuisine", cuisine)
+	}
+
+	for _, name := range splitQueryArray(c, "course") {
+		course := models.Course(name)
+		if !course.IsValid() {
+			return query, nil, fmt.Errorf("unknown course: %s", name)
+		}
+		query.Courses = append(query.Courses, course)
+		normalized.Add("course", name)
+	}
+
+	if value := c.Query("sort"); value != "" {
+		query.Sort = repository.RecipeSort(value)
+		if !query.Sort.IsValid() {
+			return query, nil, fmt.Errorf("sort must be one of %s, %s or %s", repository.RecipeSortNewest, repository.RecipeSortOldest, repository.RecipeSortName)
+		}
+		normalized.Set("sort", value)
+	}
+
+	return query, normalized, nil
+}
+
+// splitQueryArray : the distinct, lower cased values of a repeatable or comma separated query param, sorted
+func splitQueryArray(c *gin.Context, param string) []string {
+	values := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, value := range c.QueryArray(param) {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.ToLower(strings.TrimSpace(item))
+			if item == "" || seen[item] {
+				continue
+			}
+			seen[item] = true
+			values = append(values, item)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// parseRecipeDuration : parses a duration query param, either an ISO 8601 duration like the recipe times or a Go duration like 1h30m
+func parseRecipeDuration(c *gin.Context, param string, normalized url.Values) (*time.Duration, error) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, nil
+	}
+
+	d, err := duration.Parse(value)
+	if err != nil {
+		d, err = time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("%s must be an ISO 8601 duration such as PT30M or a duration such as 30m", param)
+		}
+	}
+
+	normalized.Set(param, duration.Format(d))
+	return &d, nil
+}
+
+// parseRecipeDate : parses a date query param, a plain date covers the whole day so it is the last instant of the day for an upper bound
+func parseRecipeDate(c *gin.Context, param string, endOfDay bool, normalized url.Values) (*time.Time, error) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		date, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an RFC 3339 time or a YYYY-MM-DD date", param)
+		}
+		if endOfDay {
+			date = date.Add(24*time.Hour - time.Millisecond)
+		}
+	}
+
+	date = date.UTC()
+	normalized.Set(param, date.Format(time.RFC3339Nano))
+	return &date, nil
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skamranahmed/smilecook/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionsHandler struct {
+	ctx            context.Context
+	sessionService service.SessionService
+}
+
+// NewSessionsHandler: used to create a new instance from the SessionsHandler struct
+func NewSessionsHandler(ctx context.Context, sessionService service.SessionService) *SessionsHandler {
+	return &SessionsHandler{
+		ctx:            ctx,
+		sessionService: sessionService,
+	}
+}
+
+// ListSessionsHandler: lists the devices the user is signed in on, the session of the request is flagged as current
+func (handler *SessionsHandler) ListSessionsHandler(c *gin.Context) {
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := handler.sessionService.FetchAllByUserID(user.ID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if claims, exists := c.Get("auth"); exists {
+		for _, session := range sessions {
+			session.Current = session.ID.Hex() == claims.(*Claims).SessionID
+		}
+	}
+
+	c.JSON(http.StatusOK, sessions)
+	return
+}
+
+// RevokeSessionHandler: signs a session of the user out
+func (handler *SessionsHandler) RevokeSessionHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	user, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revoked, err := handler.sessionService.Revoke(objectID, user.ID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !revoked {
+		errMsg := fmt.Sprintf("no active session found with id: %s", id)
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": errMsg})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+	return
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MFAPendingAudience : audience of the tokens that are issued after the password check when two-factor authentication is enabled
+// these tokens can only be exchanged on /signin/mfa, the AuthMiddleware rejects them
+const MFAPendingAudience string = "mfa_pending"
+
+const mfaPendingTokenTTL time.Duration = 5 * time.Minute
+
+type MFAPendingOutput struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type signInMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// SignInMFAHandler: exchanges a mfa pending token and a valid totp (or recovery) code for real tokens
+func (handler *AuthHandler) SignInMFAHandler(c *gin.Context) {
+	var request signInMFARequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(request.MFAToken, claims, handler.keyring.Keyfunc)
+	if err != nil || token == nil || !token.Valid || claims.Audience != MFAPendingAudience {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa token"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.StandardClaims.Subject)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa token"})
+		return
+	}
+
+	isRevoked, err := handler.revocationService.IsRevoked(claims.Id, "", userID, claims.IssuedAtTime())
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if isRevoked {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa token"})
+		return
+	}
+
+	clientIP := c.ClientIP()
+
+	// the codes are short, so they get the same brute force protection as the passwords
+	throttle, err := handler.loginThrottleService.Check(claims.Username, clientIP)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if throttle.RetryAfter > 0 {
+		SignInLockoutsTotal.WithLabelValues("mfa").Inc()
+		abortWithRetryAfter(c, http.StatusTooManyRequests, throttle.RetryAfter, "too many failed sign in attempts, try again later")
+		return
+	}
+
+	user, err := handler.userService.FindOneByID(userID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa token"})
+		return
+	}
+
+	if abortIfBlocked(c, user) {
+		return
+	}
+
+	err = handler.twoFactorService.Verify(user, request.Code)
+	if err != nil {
+		if err == service.ErrInvalidTwoFactorCode {
+			handler.handleSignInFailure(c, user.Username, clientIP, "wrong_mfa_code")
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the mfa pending token is single-use
+	err = handler.revocationService.RevokeToken(claims.Id, time.Unix(claims.ExpiresAt, 0))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = handler.userService.ApplyStagedPasswordRehash(user)
+	if err != nil {
+		log.Printf("unable to upgrade the password hash of user: %s, err: %v\n", user.Username, err)
+	}
+
+	jwtOutput, err := handler.issueTokens(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, jwtOutput)
+	return
+}
+
+// EnrollTwoFactorHandler: starts the two-factor authentication enrollment, returns the secret and the provisioning uri
+func (handler *AuthHandler) EnrollTwoFactorHandler(c *gin.Context) {
+	user, ok := handler.currentUser(c)
+	if !ok {
+		return
+	}
+
+	secret, provisioningURI, err := handler.twoFactorService.Enroll(user)
+	if err != nil {
+		if err == service.ErrTwoFactorAlreadyEnabled {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"secret": secret, "provisioning_uri": provisioningURI})
+	return
+}
+
+// ConfirmTwoFactorHandler: turns on two-factor authentication, returns the recovery codes which are only ever shown once
+func (handler *AuthHandler) ConfirmTwoFactorHandler(c *gin.Context) {
+	var request twoFactorCodeRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := handler.currentUser(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := handler.twoFactorService.Confirm(user, request.Code)
+	if err != nil {
+		if err == service.ErrTwoFactorAlreadyEnabled || err == service.ErrTwoFactorNotEnrolled || err == service.ErrInvalidTwoFactorCode {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication has been enabled", "recovery_codes": recoveryCodes})
+	return
+}
+
+// DisableTwoFactorHandler: turns off two-factor authentication
+func (handler *AuthHandler) DisableTwoFactorHandler(c *gin.Context) {
+	var request twoFactorCodeRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := handler.currentUser(c)
+	if !ok {
+		return
+	}
+
+	err = handler.twoFactorService.Disable(user, request.Code)
+	if err != nil {
+		if err == service.ErrTwoFactorNotEnabled || err == service.ErrInvalidTwoFactorCode {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication has been disabled"})
+	return
+}
+
+// newMFAPendingOutput : signs a short-lived token that proves the password check succeeded
+func (handler *AuthHandler) newMFAPendingOutput(user *models.User) (*MFAPendingOutput, error) {
+	issuedAt := time.Now()
+	expirationTime := issuedAt.Add(mfaPendingTokenTTL)
+	claims := &Claims{
+		Username:   user.Username,
+		IssuedAtMs: issuedAt.UnixMilli(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        primitive.NewObjectID().Hex(),
+			Subject:   user.ID.Hex(),
+			Audience:  MFAPendingAudience,
+			IssuedAt:  issuedAt.Unix(),
+			ExpiresAt: expirationTime.Unix(),
+		},
+	}
+
+	tokenString, err := handler.keyring.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAPendingOutput{
+		MFARequired: true,
+		MFAToken:    tokenString,
+		ExpiresAt:   expirationTime,
+	}, nil
+}
+
+// currentUser : loads the user of the access token set by the AuthMiddleware, aborts the request if that is not possible
+func (handler *AuthHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	jwtAuthToken, exists := c.Get("auth")
+	if !exists {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	jwtAuthPayload, ok := jwtAuthToken.(*Claims)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	user, err := handler.userService.FindOne(jwtAuthPayload.Username)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return user, true
+}
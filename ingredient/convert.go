@@ -0,0 +1,121 @@
+package ingredient
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrUnknownUnit : the unit is not in the unit table
+	ErrUnknownUnit = errors.New("unknown unit")
+
+	// ErrIncompatibleUnits : the units measure different things, e.g. a pinch and a gram
+	ErrIncompatibleUnits = errors.New("incompatible units")
+
+	// ErrUnknownDensity : a volume and a mass only convert into each other for the items of the density table
+	ErrUnknownDensity = errors.New("unknown density")
+)
+
+// Convert : converts an amount of the item from one unit to another, volumes and masses convert into each other
+// through the density of the item
+func Convert(value float64, from string, to string, item string) (float64, error) {
+	fromUnit, ok := LookupUnit(from)
+	if !ok {
+		return 0, ErrUnknownUnit
+	}
+	toUnit, ok := LookupUnit(to)
+	if !ok {
+		return 0, ErrUnknownUnit
+	}
+
+	if fromUnit.Kind == Count || toUnit.Kind == Count {
+		if fromUnit == toUnit {
+			return value, nil
+		}
+		return 0, ErrIncompatibleUnits
+	}
+
+	base := value * fromUnit.Factor
+	if fromUnit.Kind != toUnit.Kind {
+		d, ok := lookupDensity(item)
+		if !ok {
+			return 0, ErrUnknownDensity
+		}
+		if fromUnit.Kind == Volume {
+			base *= d.gramsPerMl
+		} else {
+			base /= d.gramsPerMl
+		}
+	}
+
+	return base / toUnit.Factor, nil
+}
+
+// ConvertTo : expresses the ingredient in the units of the system, dry goods measured by volume are weighed in metric,
+// ingredients without a unit or with a unit that does not convert are returned as they are
+func (i Ingredient) ConvertTo(system System) Ingredient {
+	if i.Quantity == nil {
+		return i
+	}
+
+	unit, ok := LookupUnit(i.Unit)
+	if !ok || unit.Kind == Count {
+		return i
+	}
+
+	kind := unit.Kind
+	if system == Metric && kind == Volume {
+		if d, ok := lookupDensity(i.Item); ok && d.isDryGood {
+			kind = Mass
+		}
+	}
+
+	// the unit is picked for the lower bound, so that both bounds of a range use the same unit
+	base, err := Convert(i.Quantity.Value, unit.Name, baseUnit(kind), i.Item)
+	if err != nil {
+		return i
+	}
+	target, value := fitUnit(system, kind, base)
+
+	converted := i
+	quantity := Quantity{Value: value}
+	if i.Quantity.IsRange() {
+		max, _ := Convert(i.Quantity.Max, unit.Name, target.Name, i.Item)
+		quantity.Max = roundAmount(max, target)
+	}
+	converted.Quantity = &quantity
+	converted.Unit = target.Name
+	return converted
+}
+
+// ConvertAll : expresses every ingredient in the units of the system
+func ConvertAll(ingredients []Ingredient, system System) []Ingredient {
+	converted := make([]Ingredient, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		converted = append(converted, ingredient.ConvertTo(system))
+	}
+	return converted
+}
+
+// baseUnit : the unit the factors of the kind are relative to
+func baseUnit(kind Kind) string {
+	if kind == Mass {
+		return "g"
+	}
+	return "ml"
+}
+
+// roundAmount : grams and millilitres are rounded to whole numbers (to one decimal below ten), the other metric units
+// to two decimals, everything else to a kitchen fraction, the unit is nil for an ingredient without a unit
+func roundAmount(value float64, unit *Unit) float64 {
+	if unit == nil || unit.System != Metric {
+		return RoundKitchen(value)
+	}
+	if unit.Factor == 1 && value >= 10 {
+		return math.Round(value)
+	}
+	if unit.Factor == 1 {
+		return math.Round(value*10) / 10
+	}
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,71 @@
+package ingredient
+
+import (
+	"sort"
+	"strings"
+)
+
+// density : the grams per millilitre of an ingredient, dry goods are weighed rather than measured in metric recipes
+type density struct {
+	item       string
+	gramsPerMl float64
+	isDryGood  bool
+}
+
+// densities : the common dry goods and liquids, a cup of all-purpose flour weighs about 125 g
+var densities = []density{
+	{"flour", 0.53, true},
+	{"all-purpose flour", 0.53, true},
+	{"bread flour", 0.55, true},
+	{"whole wheat flour", 0.51, true},
+	{"almond flour", 0.41, true},
+	{"cornstarch", 0.54, true},
+	{"cornmeal", 0.64, true},
+	{"sugar", 0.85, true},
+	{"brown sugar", 0.93, true},
+	{"powdered sugar", 0.51, true},
+	{"icing sugar", 0.51, true},
+	{"confectioners sugar", 0.51, true},
+	{"cocoa", 0.36, true},
+	{"cocoa powder", 0.36, true},
+	{"salt", 1.22, true},
+	{"kosher salt", 0.65, true},
+	{"baking soda", 0.97, true},
+	{"baking powder", 0.81, true},
+	{"yeast", 0.64, true},
+	{"rice", 0.78, true},
+	{"oats", 0.38, true},
+	{"rolled oats", 0.38, true},
+	{"breadcrumbs", 0.46, true},
+	{"chocolate chips", 0.72, true},
+	{"parmesan", 0.42, true},
+	{"butter", 0.96, true},
+	{"water", 1, false},
+	{"milk", 1.03, false},
+	{"cream", 1.01, false},
+	{"oil", 0.92, false},
+	{"olive oil", 0.92, false},
+	{"honey", 1.42, false},
+	{"maple syrup", 1.32, false},
+}
+
+func init() {
+	// the most specific item wins, "brown sugar" before "sugar"
+	sort.SliceStable(densities, func(i, j int) bool {
+		return len(densities[i].item) > len(densities[j].item)
+	})
+}
+
+// lookupDensity : the density of the item, matched on whole words so that "sugar snap peas" are not sugar
+func lookupDensity(item string) (density, bool) {
+	words := " " + strings.Join(strings.Fields(strings.ToLower(item)), " ") + " "
+	for _, d := range densities {
+		if strings.Contains(words, " "+d.item+" ") || strings.Contains(words, " "+d.item+"s ") {
+			if d.item == "sugar" && strings.Contains(words, " sugar snap ") {
+				continue
+			}
+			return d, true
+		}
+	}
+	return density{}, false
+}
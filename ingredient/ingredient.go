@@ -0,0 +1,83 @@
+// Package ingredient turns free-text ingredient lines into quantities, units and items, and converts between units
+package ingredient
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Quantity : an amount, Max is the upper bound of a range such as "2-3" and zero otherwise
+type Quantity struct {
+	Value float64 `json:"value"`
+	Max   float64 `json:"max,omitempty"`
+}
+
+// IsRange : reports whether the quantity is a range
+func (q Quantity) IsRange() bool {
+	return q.Max > q.Value
+}
+
+// Scale : multiplies both bounds of the quantity
+func (q Quantity) Scale(factor float64) Quantity {
+	return Quantity{Value: q.Value * factor, Max: q.Max * factor}
+}
+
+// String : formats the quantity with at most two decimals
+func (q Quantity) String() string {
+	if q.IsRange() {
+		return formatNumber(q.Value) + "-" + formatNumber(q.Max)
+	}
+	return formatNumber(q.Value)
+}
+
+// Ingredient : an ingredient line split into its parts, Original keeps the text it has been parsed from
+type Ingredient struct {
+	Quantity *Quantity `json:"quantity,omitempty"`
+	Unit     string    `json:"unit,omitempty"` // canonical unit name, see LookupUnit
+	Item     string    `json:"item"`
+	Notes    string    `json:"notes,omitempty"`
+	Original string    `json:"original"`
+}
+
+// String : formats the ingredient back into a line, e.g. "1 1/2 cups flour, sifted",
+// metric amounts are written with decimals and the other amounts with kitchen fractions
+func (i Ingredient) String() string {
+	unit, hasUnit := LookupUnit(i.Unit)
+
+	parts := make([]string, 0, 3)
+	if i.Quantity != nil {
+		format := FormatKitchen
+		if hasUnit && unit.System == Metric {
+			format = formatNumber
+		}
+		quantity := format(i.Quantity.Value)
+		if i.Quantity.IsRange() {
+			quantity += "-" + format(i.Quantity.Max)
+		}
+		parts = append(parts, quantity)
+	}
+	if hasUnit {
+		amount := 1.0
+		if i.Quantity != nil {
+			amount = math.Max(i.Quantity.Value, i.Quantity.Max)
+		}
+		parts = append(parts, unit.NameFor(amount))
+	} else if i.Unit != "" {
+		parts = append(parts, i.Unit)
+	}
+	if i.Item != "" {
+		parts = append(parts, i.Item)
+	}
+
+	line := strings.Join(parts, " ")
+	if i.Notes != "" {
+		line += ", " + i.Notes
+	}
+	return line
+}
+
+// formatNumber : formats a number with at most two decimals and no trailing zeros
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,166 @@
+package ingredient
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// unicodeFractions : the vulgar fraction characters found in recipes
+var unicodeFractions = map[rune]float64{
+	'½': 1.0 / 2, '⅓': 1.0 / 3, '⅔': 2.0 / 3, '¼': 1.0 / 4, '¾': 3.0 / 4,
+	'⅕': 1.0 / 5, '⅖': 2.0 / 5, '⅗': 3.0 / 5, '⅘': 4.0 / 5, '⅙': 1.0 / 6,
+	'⅚': 5.0 / 6, '⅛': 1.0 / 8, '⅜': 3.0 / 8, '⅝': 5.0 / 8, '⅞': 7.0 / 8,
+}
+
+const fractionChars = `½⅓⅔¼¾⅕⅖⅗⅘⅙⅚⅛⅜⅝⅞`
+
+// number : a mixed number ("1 1/2", "1½"), a fraction ("1/2", "½"), a decimal ("1.5") or an integer
+const number = `(?:\d+\s+\d+\s*/\s*\d+|\d+\s*/\s*\d+|\d+\s*[` + fractionChars + `]|[` + fractionChars + `]|\d+(?:\.\d+)?|\.\d+)`
+
+// quantityPattern : a number or a range of numbers ("1-2", "1 to 2") at the start of a line
+var quantityPattern = regexp.MustCompile(`^(` + number + `)(?:\s*(?:-|–|—|to)\s*(` + number + `))?`)
+
+// parenthesesPattern : a parenthesized part of a line, e.g. "(14 oz)" or "(optional)"
+var parenthesesPattern = regexp.MustCompile(`\s*\(([^)]*)\)`)
+
+// Parse : splits a free-text ingredient line such as "1 1/2 cups flour, sifted" into its quantity, unit, item and notes,
+// a line it cannot make sense of becomes an ingredient with only an item
+func Parse(line string) Ingredient {
+	ingredient := Ingredient{Original: line}
+	notes := make([]string, 0)
+
+	text := strings.TrimSpace(line)
+	text = strings.TrimLeft(text, "-•*· \t")
+
+	// parenthesized parts are notes, "1 (14 oz) can tomatoes" is a can of tomatoes
+	for _, match := range parenthesesPattern.FindAllStringSubmatch(text, -1) {
+		if note := strings.TrimSpace(match[1]); note != "" {
+			notes = append(notes, note)
+		}
+	}
+	text = strings.TrimSpace(parenthesesPattern.ReplaceAllString(text, ""))
+
+	if match := quantityPattern.FindStringSubmatch(text); match != nil {
+		value, ok := parseNumber(match[1])
+		if ok {
+			quantity := &Quantity{Value: value}
+			if match[2] != "" {
+				max, ok := parseNumber(match[2])
+				if ok && max > value {
+					quantity.Max = max
+				}
+			}
+			ingredient.Quantity = quantity
+			text = strings.TrimSpace(text[len(match[0]):])
+		}
+	}
+
+	if unit, rest, ok := parseUnit(text); ok {
+		ingredient.Unit = unit.Name
+		text = rest
+	}
+
+	text = strings.TrimSpace(strings.TrimPrefix(text, "of "))
+
+	// everything after the first comma describes the preparation
+	if i := strings.Index(text, ","); i >= 0 {
+		if note := strings.TrimSpace(text[i+1:]); note != "" {
+			notes = append([]string{note}, notes...)
+		}
+		text = strings.TrimSpace(text[:i])
+	}
+
+	for _, suffix := range []string{" to taste", " for garnish", " for serving"} {
+		if strings.HasSuffix(strings.ToLower(text), suffix) {
+			notes = append([]string{strings.TrimSpace(suffix)}, notes...)
+			text = strings.TrimSpace(text[:len(text)-len(suffix)])
+		}
+	}
+
+	ingredient.Item = text
+	ingredient.Notes = strings.Join(notes, "; ")
+	return ingredient
+}
+
+// ParseAll : parses every ingredient line
+func ParseAll(lines []string) []Ingredient {
+	ingredients := make([]Ingredient, 0, len(lines))
+	for _, line := range lines {
+		ingredients = append(ingredients, Parse(line))
+	}
+	return ingredients
+}
+
+// parseNumber : the value of a number matched by the number pattern
+func parseNumber(text string) (float64, bool) {
+	text = strings.TrimSpace(text)
+
+	// a whole number followed by a fraction, "1 1/2" or "1½"
+	var whole float64
+	if i := strings.IndexFunc(text, func(r rune) bool { return r < '0' || r > '9' }); i > 0 {
+		rest := strings.TrimSpace(text[i:])
+		// "3 / 4" is a fraction with spaces, not a whole number
+		if (text[i] == ' ' && strings.ContainsRune(rest, '/') && !strings.HasPrefix(rest, "/")) || isUnicodeFraction(rest) {
+			whole, _ = strconv.ParseFloat(text[:i], 64)
+			text = rest
+		}
+	}
+
+	if isUnicodeFraction(text) {
+		return whole + unicodeFractions[[]rune(text)[0]], true
+	}
+
+	if parts := strings.Split(text, "/"); len(parts) == 2 {
+		numerator, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil {
+			return 0, false
+		}
+		denominator, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || denominator == 0 {
+			return 0, false
+		}
+		return whole + numerator/denominator, true
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, false
+	}
+	return whole + value, true
+}
+
+// isUnicodeFraction : reports whether the text is a single vulgar fraction character
+func isUnicodeFraction(text string) bool {
+	runes := []rune(text)
+	if len(runes) != 1 {
+		return false
+	}
+	_, ok := unicodeFractions[runes[0]]
+	return ok
+}
+
+// parseUnit : the unit at the start of the text and the text after it, two word units such as "fl oz" are tried first
+func parseUnit(text string) (*Unit, string, bool) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil, text, false
+	}
+
+	for _, length := range []int{2, 1} {
+		if len(words) < length {
+			continue
+		}
+		unit, ok := LookupUnit(strings.Join(words[:length], " "))
+		if !ok {
+			continue
+		}
+		// a unit with nothing after it is the item itself, "4 cloves" are the spice
+		if len(words) == length {
+			return nil, text, false
+		}
+		return unit, strings.Join(words[length:], " "), true
+	}
+
+	return nil, text, false
+}
@@ -0,0 +1,100 @@
+package ingredient
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		value float64 // 0 when the line has no quantity
+		max   float64
+		unit  string
+		item  string
+		notes string
+	}{
+		{"integer", "2 eggs", 2, 0, "", "eggs", ""},
+		{"decimal", "1.5 kg potatoes", 1.5, 0, "kg", "potatoes", ""},
+		{"leading decimal point", ".5 tsp salt", 0.5, 0, "tsp", "salt", ""},
+		{"fraction", "1/2 cup milk", 0.5, 0, "cup", "milk", ""},
+		{"fraction with spaces", "3 / 4 cup milk", 0.75, 0, "cup", "milk", ""},
+		{"mixed fraction with notes", "1 1/2 cups flour, sifted", 1.5, 0, "cup", "flour", "sifted"},
+		{"unicode fraction", "½ cup sugar", 0.5, 0, "cup", "sugar", ""},
+		{"mixed unicode fraction", "1½ tbsp butter", 1.5, 0, "tbsp", "butter", ""},
+		{"mixed unicode fraction with a space", "2 ¾ cups water", 2.75, 0, "cup", "water", ""},
+		{"range", "1-2 tbsp olive oil", 1, 2, "tbsp", "olive oil", ""},
+		{"range with to", "2 to 3 cloves garlic, minced", 2, 3, "clove", "garlic", "minced"},
+		{"range with an en dash", "3–4 cups stock", 3, 4, "cup", "stock", ""},
+		{"range without a unit", "2-3 onions", 2, 3, "", "onions", ""},
+		{"decreasing range is no range", "3-2 apples", 3, 0, "", "apples", ""},
+		{"two word unit", "4 fl oz cream", 4, 0, "fl oz", "cream", ""},
+		{"of after the unit", "1 cup of rice", 1, 0, "cup", "rice", ""},
+		{"parenthesized note", "1 (14 oz) can tomatoes", 1, 0, "can", "tomatoes", "14 oz"},
+		{"unit alone is the item", "4 cloves", 4, 0, "", "cloves", ""},
+		{"bullet", "- 3 g yeast", 3, 0, "g", "yeast", ""},
+		{"no quantity", "salt and pepper to taste", 0, 0, "", "salt and pepper", "to taste"},
+		{"no quantity with a unit word", "pinch of salt", 0, 0, "pinch", "salt", ""},
+		{"no quantity for garnish", "fresh parsley, chopped for garnish", 0, 0, "", "fresh parsley", "chopped for garnish"},
+		{"zero denominator", "1/0 cup sugar", 0, 0, "", "1/0 cup sugar", ""},
+		{"empty line", "", 0, 0, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.line)
+
+			if got.Original != tt.line {
+				t.Errorf("Parse(%q).Original = %q, want %q", tt.line, got.Original, tt.line)
+			}
+
+			if tt.value == 0 {
+				if got.Quantity != nil {
+					t.Errorf("Parse(%q).Quantity = %+v, want nil", tt.line, *got.Quantity)
+				}
+			} else if got.Quantity == nil {
+				t.Errorf("Parse(%q).Quantity = nil, want %v", tt.line, tt.value)
+			} else if math.Abs(got.Quantity.Value-tt.value) > 1e-9 || math.Abs(got.Quantity.Max-tt.max) > 1e-9 {
+				t.Errorf("Parse(%q).Quantity = %+v, want {Value:%v Max:%v}", tt.line, *got.Quantity, tt.value, tt.max)
+			}
+
+			if got.Unit != tt.unit {
+				t.Errorf("Parse(%q).Unit = %q, want %q", tt.line, got.Unit, tt.unit)
+			}
+			if got.Item != tt.item {
+				t.Errorf("Parse(%q).Item = %q, want %q", tt.line, got.Item, tt.item)
+			}
+			if got.Notes != tt.notes {
+				t.Errorf("Parse(%q).Notes = %q, want %q", tt.line, got.Notes, tt.notes)
+			}
+		})
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		text  string
+		want  float64
+		valid bool
+	}{
+		{"3", 3, true},
+		{"2.25", 2.25, true},
+		{"3/4", 0.75, true},
+		{"3 / 4", 0.75, true},
+		{"1 1/2", 1.5, true},
+		{"⅓", 1.0 / 3, true},
+		{"2⅛", 2.125, true},
+		{"1/0", 0, false},
+		{"1/2/3", 0, false},
+		{"abc", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseNumber(tt.text)
+		if ok != tt.valid || (ok && math.Abs(got-tt.want) > 1e-9) {
+			t.Errorf("parseNumber(%q) = %v, %v, want %v, %v", tt.text, got, ok, tt.want, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package ingredient
+
+import (
+	"math"
+	"strconv"
+)
+
+// kitchenFraction : a fraction that can be measured with a common set of measuring cups and spoons
+type kitchenFraction struct {
+	value float64
+	text  string
+}
+
+// fractionsBelowOne : the fractions an amount below one is rounded to
+var fractionsBelowOne = []kitchenFraction{
+	{1.0 / 8, "1/8"}, {1.0 / 4, "1/4"}, {1.0 / 3, "1/3"}, {1.0 / 2, "1/2"}, {2.0 / 3, "2/3"}, {3.0 / 4, "3/4"}, {1, ""},
+}
+
+// fractionsOfWhole : the fractions that are kept after the whole part of a small amount
+var fractionsOfWhole = []kitchenFraction{
+	{0, ""}, {1.0 / 4, "1/4"}, {1.0 / 3, "1/3"}, {1.0 / 2, "1/2"}, {2.0 / 3, "2/3"}, {3.0 / 4, "3/4"}, {1, ""},
+}
+
+// formattedFractions : the fractions FormatKitchen writes as fractions
+var formattedFractions = append([]kitchenFraction{{0, ""}}, fractionsBelowOne...)
+
+// RoundKitchen : rounds an amount to something that can be measured in a kitchen,
+//
+//	below 1      the nearest of 1/8, 1/4, 1/3, 1/2, 2/3, 3/4 and 1, never less than 1/8 so that an ingredient does not vanish
+//	1 up to 5    the whole part and the nearest quarter or third
+//	5 up to 20   the nearest half
+//	from 20 on   the nearest whole number
+func RoundKitchen(value float64) float64 {
+	switch {
+	case value <= 0:
+		return 0
+	case value < 1:
+		return nearestFraction(value, fractionsBelowOne).value
+	case value < 5:
+		whole := math.Floor(value)
+		return whole + nearestFraction(value-whole, fractionsOfWhole).value
+	case value < 20:
+		return math.Round(value*2) / 2
+	default:
+		return math.Round(value)
+	}
+}
+
+// FormatKitchen : formats an amount as a whole number and a kitchen fraction, "1 1/2" or "3/4",
+// an amount that is not a kitchen fraction is formatted with at most two decimals
+func FormatKitchen(value float64) string {
+	whole := math.Floor(value)
+	fraction := value - whole
+
+	for _, candidate := range formattedFractions {
+		if math.Abs(fraction-candidate.value) > 0.01 {
+			continue
+		}
+		if candidate.value == 1 {
+			whole++
+		}
+		switch {
+		case candidate.text == "":
+			return strconv.FormatFloat(whole, 'f', -1, 64)
+		case whole == 0:
+			return candidate.text
+		default:
+			return strconv.FormatFloat(whole, 'f', -1, 64) + " " + candidate.text
+		}
+	}
+
+	return formatNumber(value)
+}
+
+// Scale : multiplies the quantity by the factor, moves the unit along its ladder as the amount grows or shrinks
+// (tsp, tbsp, cup / oz, lb / g, kg / ml, l) and rounds the amount to what can be measured in a kitchen
+func (i Ingredient) Scale(factor float64) Ingredient {
+	if i.Quantity == nil || factor == 1 {
+		return i
+	}
+
+	scaled := i
+	quantity := i.Quantity.Scale(factor)
+
+	unit, ok := LookupUnit(i.Unit)
+	if !ok {
+		unit = nil
+	}
+
+	if unit != nil && isOnLadder(unit) {
+		// the unit is picked for the lower bound, so that both bounds of a range use the same unit
+		var target *Unit
+		target, quantity.Value = fitUnit(unit.System, unit.Kind, quantity.Value*unit.Factor)
+		quantity.Max = roundAmount(quantity.Max*unit.Factor/target.Factor, target)
+		scaled.Unit = target.Name
+	} else {
+		quantity.Value = roundAmount(quantity.Value, unit)
+		quantity.Max = roundAmount(quantity.Max, unit)
+	}
+
+	scaled.Quantity = &quantity
+	return scaled
+}
+
+// ScaleAll : scales every ingredient by the factor
+func ScaleAll(ingredients []Ingredient, factor float64) []Ingredient {
+	scaled := make([]Ingredient, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		scaled = append(scaled, ingredient.Scale(factor))
+	}
+	return scaled
+}
+
+// nearestFraction : the candidate closest to the value
+func nearestFraction(value float64, candidates []kitchenFraction) kitchenFraction {
+	nearest := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if math.Abs(value-candidate.value) < math.Abs(value-nearest.value) {
+			nearest = candidate
+		}
+	}
+	return nearest
+}
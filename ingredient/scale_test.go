@@ -0,0 +1,107 @@
+package ingredient
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRoundKitchen(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		want  float64
+	}{
+		{"zero stays zero", 0, 0},
+		{"tiny amount does not vanish", 0.02, 1.0 / 8},
+		{"eighth", 0.13, 1.0 / 8},
+		{"quarter", 0.27, 1.0 / 4},
+		{"third", 0.36, 1.0 / 3},
+		{"half", 0.48, 1.0 / 2},
+		{"two thirds", 0.64, 2.0 / 3},
+		{"three quarters", 0.77, 3.0 / 4},
+		{"almost one", 0.93, 1},
+		{"whole and a quarter", 1.22, 1.25},
+		{"whole and a third", 2.35, 2 + 1.0/3},
+		{"whole and two thirds", 3.68, 3 + 2.0/3},
+		{"rounds up to the next whole", 4.95, 5},
+		{"half above five", 7.3, 7.5},
+		{"whole above five", 11.9, 12},
+		{"whole above twenty", 33.4, 33},
+		{"whole above twenty rounds up", 33.6, 34},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RoundKitchen(tt.value)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("RoundKitchen(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatKitchen(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0.125, "1/8"},
+		{0.25, "1/4"},
+		{1.0 / 3, "1/3"},
+		{0.5, "1/2"},
+		{2.0 / 3, "2/3"},
+		{0.75, "3/4"},
+		{1, "1"},
+		{1.5, "1 1/2"},
+		{2 + 1.0/3, "2 1/3"},
+		{12, "12"},
+		{0.999, "1"},
+		{1.42, "1.42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got := FormatKitchen(tt.value)
+			if got != tt.want {
+				t.Fatalf("FormatKitchen(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		factor float64
+		want   string
+	}{
+		{"unchanged when not scaled", "3 tbsp butter", 1, "3 tbsp butter"},
+		{"tsp promoted to tbsp", "1 1/2 tsp salt", 2, "1 tbsp salt"},
+		{"tbsp promoted to cup", "2 tbsp sugar", 4, "1/2 cup sugar"},
+		{"tbsp kept when a cup measure would be off", "3 tbsp butter", 2, "6 tbsp butter"},
+		{"cup demoted to tbsp", "1/4 cup oil", 0.5, "2 tbsp oil"},
+		{"tbsp demoted to tsp", "2 tbsp sugar", 1.0 / 3, "2 tsp sugar"},
+		{"cups pluralized", "3/4 cup milk", 2, "1 1/2 cups milk"},
+		{"oz promoted to lb", "8 oz cheese", 4, "2 lb cheese"},
+		{"g promoted to kg", "600 g pasta", 2, "1.2 kg pasta"},
+		{"kg demoted to g", "1 kg flour", 0.25, "250 g flour"},
+		{"ml promoted to l", "500 ml stock", 3, "1.5 l stock"},
+		{"metric rounded to whole grams", "200 g butter", 1.0 / 3, "67 g butter"},
+		{"unit off the ladder is kept", "1 fl oz cream", 4, "4 fl oz cream"},
+		{"count unit rounded to a fraction", "2 cloves garlic", 0.3, "2/3 clove garlic"},
+		{"no unit rounded to a fraction", "3 eggs", 0.5, "1 1/2 eggs"},
+		{"range keeps one unit", "2-3 tbsp honey", 2, "1/4-1/3 cup honey"},
+		{"notes are kept", "1 1/2 cups flour, sifted", 2, "3 cups flour, sifted"},
+		{"no quantity is kept", "salt to taste", 3, "salt, to taste"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.line).Scale(tt.factor).String()
+			if got != tt.want {
+				t.Fatalf("Parse(%q).Scale(%v) = %q, want %q", tt.line, tt.factor, got, tt.want)
+			}
+		})
+	}
+}
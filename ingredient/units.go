@@ -0,0 +1,161 @@
+package ingredient
+
+import (
+	"math"
+	"strings"
+)
+
+// System : a system of measurement, imperial stands for the US customary units used by most recipes
+type System string
+
+const (
+	Metric   System = "metric"
+	Imperial System = "imperial"
+)
+
+// IsValid : reports whether the system is one of the supported ones
+func (s System) IsValid() bool {
+	return s == Metric || s == Imperial
+}
+
+// Kind : what a unit measures, only units of the same kind convert into each other without a density
+type Kind string
+
+const (
+	Volume Kind = "volume"
+	Mass   Kind = "mass"
+	Count  Kind = "count" // pinches, cloves, cans... they do not convert
+)
+
+// Unit : a unit of measurement, Factor is the amount of the base unit (ml or g) in one unit,
+// Plural is only set for the units that are written out in full
+type Unit struct {
+	Name    string
+	Plural  string
+	Kind    Kind
+	System  System
+	Factor  float64
+	Aliases []string
+}
+
+// NameFor : the name of the unit for an amount, "2 cups" but "2 tbsp"
+func (u *Unit) NameFor(amount float64) string {
+	if u.Plural != "" && amount > 1 {
+		return u.Plural
+	}
+	return u.Name
+}
+
+// units : the canonical name is the first alias, single letter aliases are case sensitive ("T" is a tablespoon, "t" a teaspoon)
+var units = []Unit{
+	{Name: "ml", Kind: Volume, System: Metric, Factor: 1, Aliases: []string{"ml", "milliliter", "milliliters", "millilitre", "millilitres", "cc"}},
+	{Name: "cl", Kind: Volume, System: Metric, Factor: 10, Aliases: []string{"cl", "centiliter", "centiliters", "centilitre", "centilitres"}},
+	{Name: "dl", Kind: Volume, System: Metric, Factor: 100, Aliases: []string{"dl", "deciliter", "deciliters", "decilitre", "decilitres"}},
+	{Name: "l", Kind: Volume, System: Metric, Factor: 1000, Aliases: []string{"l", "L", "liter", "liters", "litre", "litres"}},
+	{Name: "tsp", Kind: Volume, System: Imperial, Factor: 4.92892159375, Aliases: []string{"tsp", "tsps", "t", "teaspoon", "teaspoons"}},
+	{Name: "tbsp", Kind: Volume, System: Imperial, Factor: 14.78676478125, Aliases: []string{"tbsp", "tbsps", "tbs", "tbl", "T", "tablespoon", "tablespoons"}},
+	{Name: "fl oz", Kind: Volume, System: Imperial, Factor: 29.5735295625, Aliases: []string{"fl oz", "fl. oz", "floz", "fluid ounce", "fluid ounces"}},
+	{Name: "cup", Plural: "cups", Kind: Volume, System: Imperial, Factor: 236.5882365, Aliases: []string{"cup", "cups", "c", "C"}},
+	{Name: "pint", Plural: "pints", Kind: Volume, System: Imperial, Factor: 473.176473, Aliases: []string{"pint", "pints", "pt", "pts"}},
+	{Name: "quart", Plural: "quarts", Kind: Volume, System: Imperial, Factor: 946.352946, Aliases: []string{"quart", "quarts", "qt", "qts"}},
+	{Name: "gallon", Plural: "gallons", Kind: Volume, System: Imperial, Factor: 3785.411784, Aliases: []string{"gallon", "gallons", "gal"}},
+	{Name: "mg", Kind: Mass, System: Metric, Factor: 0.001, Aliases: []string{"mg", "milligram", "milligrams"}},
+	{Name: "g", Kind: Mass, System: Metric, Factor: 1, Aliases: []string{"g", "gr", "gram", "grams", "gramme", "grammes"}},
+	{Name: "kg", Kind: Mass, System: Metric, Factor: 1000, Aliases: []string{"kg", "kgs", "kilogram", "kilograms", "kilo", "kilos"}},
+	{Name: "oz", Kind: Mass, System: Imperial, Factor: 28.349523125, Aliases: []string{"oz", "ounce", "ounces"}},
+	{Name: "lb", Kind: Mass, System: Imperial, Factor: 453.59237, Aliases: []string{"lb", "lbs", "pound", "pounds"}},
+	{Name: "pinch", Plural: "pinches", Kind: Count, Aliases: []string{"pinch", "pinches"}},
+	{Name: "dash", Plural: "dashes", Kind: Count, Aliases: []string{"dash", "dashes"}},
+	{Name: "clove", Plural: "cloves", Kind: Count, Aliases: []string{"clove", "cloves"}},
+	{Name: "can", Plural: "cans", Kind: Count, Aliases: []string{"can", "cans", "tin", "tins"}},
+	{Name: "slice", Plural: "slices", Kind: Count, Aliases: []string{"slice", "slices"}},
+	{Name: "piece", Plural: "pieces", Kind: Count, Aliases: []string{"piece", "pieces", "pc", "pcs"}},
+	{Name: "bunch", Plural: "bunches", Kind: Count, Aliases: []string{"bunch", "bunches"}},
+	{Name: "sprig", Plural: "sprigs", Kind: Count, Aliases: []string{"sprig", "sprigs"}},
+	{Name: "stick", Plural: "sticks", Kind: Count, Aliases: []string{"stick", "sticks"}},
+	{Name: "handful", Plural: "handfuls", Kind: Count, Aliases: []string{"handful", "handfuls"}},
+	{Name: "package", Plural: "packages", Kind: Count, Aliases: []string{"package", "packages", "pkg", "packet", "packets"}},
+	{Name: "head", Plural: "heads", Kind: Count, Aliases: []string{"head", "heads"}},
+}
+
+var (
+	unitsByName  = make(map[string]*Unit)
+	unitsByAlias = make(map[string]*Unit)
+)
+
+func init() {
+	for i := range units {
+		unit := &units[i]
+		unitsByName[unit.Name] = unit
+		for _, alias := range unit.Aliases {
+			if len(alias) > 1 {
+				alias = strings.ToLower(alias)
+			}
+			unitsByAlias[alias] = unit
+		}
+	}
+}
+
+// LookupUnit : finds a unit by its canonical name or by one of its aliases
+func LookupUnit(name string) (*Unit, bool) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".")
+	if len(name) > 1 {
+		name = strings.ToLower(name)
+	}
+	if unit, ok := unitsByName[name]; ok {
+		return unit, true
+	}
+	unit, ok := unitsByAlias[name]
+	return unit, ok
+}
+
+// preferredUnits : the ladders of units an amount moves along when it is converted or scaled, largest first,
+// an amount is only expressed in a unit once it reaches the minimum of that unit
+var preferredUnits = map[System]map[Kind][]struct {
+	name    string
+	minimum float64
+}{
+	Metric: {
+		Volume: {{"l", 1}, {"ml", 0}},
+		Mass:   {{"kg", 1}, {"g", 0}},
+	},
+	Imperial: {
+		Volume: {{"cup", 0.25}, {"tbsp", 1}, {"tsp", 0}},
+		Mass:   {{"lb", 1}, {"oz", 0}},
+	},
+}
+
+// maxRoundingError : the relative error rounding may introduce before a smaller unit is preferred,
+// 6 tbsp stay 6 tbsp rather than becoming 1/3 cup
+const maxRoundingError float64 = 0.05
+
+// isOnLadder : reports whether the unit is one of the preferred units of its system
+func isOnLadder(unit *Unit) bool {
+	for _, candidate := range preferredUnits[unit.System][unit.Kind] {
+		if candidate.name == unit.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// fitUnit : the largest preferred unit of the system in which an amount of the base unit (ml or g) of the kind
+// can be measured without rounding it by more than maxRoundingError, and the rounded amount in that unit
+func fitUnit(system System, kind Kind, base float64) (*Unit, float64) {
+	candidates := preferredUnits[system][kind]
+	for _, candidate := range candidates {
+		unit := unitsByName[candidate.name]
+		amount := base / unit.Factor
+		if amount < candidate.minimum*(1-1e-9) {
+			continue
+		}
+
+		rounded := roundAmount(amount, unit)
+		if amount == 0 || math.Abs(rounded-amount)/amount <= maxRoundingError {
+			return unit, rounded
+		}
+	}
+
+	unit := unitsByName[candidates[len(candidates)-1].name]
+	return unit, roundAmount(base/unit.Factor, unit)
+}
@@ -0,0 +1,192 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// ErrUnknownKeyID : the token was signed with a key that is not part of the keyring
+	ErrUnknownKeyID = errors.New("unknown key id")
+
+	// ErrUnexpectedSigningMethod : the alg header does not match the algorithm of the key
+	ErrUnexpectedSigningMethod = errors.New("unexpected signing method")
+)
+
+// Key : a single signing/verification key of the keyring
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	// signingKey is nil for keys that are only used to verify tokens
+	signingKey      interface{}
+	verificationKey interface{}
+}
+
+// Keyring : holds the key used to sign new tokens and all the keys that are accepted while verifying tokens
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+
+	// legacy is used to verify tokens that were issued without a `kid` header
+	legacy *Key
+}
+
+// NewHMAC : returns a keyring that signs and verifies with a single HS256 secret, used when no key directory is configured
+func NewHMAC(secret string) *Keyring {
+	key := &Key{
+		Method:          jwt.SigningMethodHS256,
+		signingKey:      []byte(secret),
+		verificationKey: []byte(secret),
+	}
+
+	return &Keyring{
+		active: key,
+		keys:   map[string]*Key{},
+		legacy: key,
+	}
+}
+
+// Load : loads every `<kid>.pem` file from the directory, files can either hold a private key (RSA or Ed25519)
+// or only a public key, the latter are accepted for verification but can never be used to sign
+// tokens without a `kid` header keep being verified with the HS256 legacySecret (if any) so that a switch from
+// the shared secret to asymmetric keys does not log everyone out
+func Load(dir string, activeKeyID string, legacySecret string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	kr := &Keyring{keys: map[string]*Key{}}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read key file: %s, error: %s", path, err)
+		}
+
+		keyID := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := parseKey(keyID, data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse key file: %s, error: %s", path, err)
+		}
+		kr.keys[keyID] = key
+	}
+
+	active, ok := kr.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active key: %q not found in %s", activeKeyID, dir)
+	}
+
+	if active.signingKey == nil {
+		return nil, fmt.Errorf("active key: %q is a public key, a private key is required to sign tokens", activeKeyID)
+	}
+	kr.active = active
+
+	if legacySecret != "" {
+		kr.legacy = &Key{
+			Method:          jwt.SigningMethodHS256,
+			verificationKey: []byte(legacySecret),
+		}
+	}
+
+	return kr, nil
+}
+
+// Sign : signs the claims with the active key and sets the `kid` header
+func (kr *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(kr.active.Method, claims)
+	if kr.active.ID != "" {
+		token.Header["kid"] = kr.active.ID
+	}
+	return token.SignedString(kr.active.signingKey)
+}
+
+// Keyfunc : a jwt.Keyfunc that resolves the verification key using the `kid` header of the token
+func (kr *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	key := kr.legacy
+	if keyID, ok := token.Header["kid"].(string); ok && keyID != "" {
+		key = kr.keys[keyID]
+	}
+
+	if key == nil {
+		return nil, ErrUnknownKeyID
+	}
+
+	// never let the token decide which algorithm is used to verify it
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnexpectedSigningMethod
+	}
+
+	return key.verificationKey, nil
+}
+
+// PublicKeys : returns the asymmetric keys of the keyring, sorted by key id
+func (kr *Keyring) PublicKeys() []*Key {
+	keys := make([]*Key, 0, len(kr.keys))
+	for _, key := range kr.keys {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// parseKey : parses a PEM encoded private or public key
+func parseKey(keyID string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(keyID, privateKey)
+	case "PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(keyID, privateKey)
+	case "PUBLIC KEY":
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(keyID, publicKey)
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+}
+
+// newKey : derives the signing method from the type of the key
+func newKey(keyID string, k crypto.PublicKey) (*Key, error) {
+	switch key := k.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: keyID, Method: jwt.SigningMethodRS256, signingKey: key, verificationKey: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: keyID, Method: jwt.SigningMethodRS256, verificationKey: key}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: keyID, Method: SigningMethodEdDSA, signingKey: key, verificationKey: key.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: keyID, Method: SigningMethodEdDSA, verificationKey: key}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %T", k)
+}
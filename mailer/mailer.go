@@ -0,0 +1,13 @@
+package mailer
+
+// Message : a plain text email
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Mailer : sends emails
+type Mailer interface {
+	Send(msg Message) error
+}
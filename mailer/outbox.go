@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// maxOutboxMessages : the number of messages the outbox keeps in memory, the oldest ones are dropped
+const maxOutboxMessages int = 100
+
+// OutboxMailer : keeps the last sent messages in memory and, if a path is provided, appends every message as a JSON line to that file
+// meant for tests and local docker-compose runs where no SMTP server is available
+type OutboxMailer struct {
+	mu       sync.Mutex
+	path     string
+	messages []Message
+}
+
+// NewOutboxMailer : returns an OutboxMailer, path can be empty to only keep the messages in memory
+func NewOutboxMailer(path string) *OutboxMailer {
+	return &OutboxMailer{
+		path: path,
+	}
+}
+
+// Send : stores the message in the outbox
+func (om *OutboxMailer) Send(msg Message) error {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	om.messages = append(om.messages, msg)
+	if len(om.messages) > maxOutboxMessages {
+		om.messages = om.messages[len(om.messages)-maxOutboxMessages:]
+	}
+	if om.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(om.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Messages : returns a copy of the last messages sent
+func (om *OutboxMailer) Messages() []Message {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	messages := make([]Message, len(om.messages))
+	copy(messages, om.messages)
+	return messages
+}
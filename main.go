@@ -6,6 +6,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-contrib/cors"
@@ -15,18 +17,41 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/skamranahmed/smilecook/config"
 	"github.com/skamranahmed/smilecook/handlers"
+	"github.com/skamranahmed/smilecook/keyring"
+	"github.com/skamranahmed/smilecook/mailer"
+	"github.com/skamranahmed/smilecook/migrations"
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/notifier"
+	"github.com/skamranahmed/smilecook/nutrition"
+	"github.com/skamranahmed/smilecook/oidc"
+	"github.com/skamranahmed/smilecook/passwordhash"
+	"github.com/skamranahmed/smilecook/rbac"
 	"github.com/skamranahmed/smilecook/repository"
+	"github.com/skamranahmed/smilecook/search"
 	"github.com/skamranahmed/smilecook/service"
+	"github.com/skamranahmed/smilecook/validation"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 var (
-	ctx            context.Context
-	err            error
-	recipesHandler *handlers.RecipesHandler
-	authHandler    *handlers.AuthHandler
+	ctx               context.Context
+	err               error
+	recipesHandler    *handlers.RecipesHandler
+	authHandler       *handlers.AuthHandler
+	adminHandler      *handlers.AdminHandler
+	apiKeysHandler    *handlers.APIKeysHandler
+	oidcHandler       *handlers.OIDCHandler
+	accountHandler    *handlers.AccountHandler
+	sessionsHandler   *handlers.SessionsHandler
+	revocationService service.TokenRevocationService
+	userService       service.UserService
+	apiKeyService     service.APIKeyService
+	accountService    service.AccountService
+	sessionService    service.SessionService
+	jwtKeyring        *keyring.Keyring
 )
 
 var totalRequests = prometheus.NewCounterVec(
@@ -65,6 +90,15 @@ func init() {
 
 	recipesCollection := mongoClient.Database(config.MongoDatabaseName).Collection("recipes")
 	usersCollection := mongoClient.Database(config.MongoDatabaseName).Collection("users")
+	refreshTokensCollection := mongoClient.Database(config.MongoDatabaseName).Collection("refresh_tokens")
+	passwordResetTokensCollection := mongoClient.Database(config.MongoDatabaseName).Collection("password_reset_tokens")
+	apiKeysCollection := mongoClient.Database(config.MongoDatabaseName).Collection("api_keys")
+
+	// bring the existing documents up to date before serving any request
+	err = migrations.Run(ctx, mongoClient.Database(config.MongoDatabaseName), migrations.All)
+	if err != nil {
+		log.Fatalf("❌ unable to run the migrations, error: %v", err)
+	}
 
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     config.RedisURI,
@@ -84,18 +118,151 @@ func init() {
 	prometheus.Register(totalRequests)
 	prometheus.Register(totalHTTPMethods)
 	prometheus.Register(httpDuration)
+	prometheus.Register(handlers.SignInFailuresTotal)
+	prometheus.Register(handlers.SignInLockoutsTotal)
+
+	// load the keys used to sign and verify the access tokens
+	if config.JWTKeysDir != "" {
+		jwtKeyring, err = keyring.Load(config.JWTKeysDir, config.JWTActiveKeyID, config.JWTSecretKey)
+		if err != nil {
+			log.Fatalf("❌ unable to load the jwt keyring, error: %v", err)
+		}
+	} else {
+		jwtKeyring = keyring.NewHMAC(config.JWTSecretKey)
+	}
+
+	// instantiate the openid connect login providers, their discovery documents are fetched on first use
+	oidcProviders := make([]*oidc.Provider, 0, len(config.OIDCProviders))
+	for _, providerConfig := range config.OIDCProviders {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(oidc.Config{
+			Name:         providerConfig.Name,
+			Issuer:       providerConfig.Issuer,
+			ClientID:     providerConfig.ClientID,
+			ClientSecret: providerConfig.ClientSecret,
+			RedirectURL:  providerConfig.RedirectURL,
+		}, nil))
+	}
 
 	// instantiate the repo(s)
 	userRepository := repository.NewUserRepository(ctx, usersCollection)
 	recipeRepository := repository.NewRecipeRepository(ctx, recipesCollection)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(ctx, refreshTokensCollection)
+	passwordResetTokenRepository := repository.NewPasswordResetTokenRepository(ctx, passwordResetTokensCollection)
+	apiKeyRepository := repository.NewAPIKeyRepository(ctx, apiKeysCollection)
+
+	// instantiate the mailer and the notifier
+	var accountMailer mailer.Mailer
+	switch config.MailerDriver {
+	case "smtp":
+		accountMailer = mailer.NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.MailerFrom)
+	case "outbox":
+		if !config.HostEnvironment.IsLocal() {
+			log.Fatalf("❌ the outbox mailer does not deliver any mail, it is only allowed in the local environment")
+		}
+		accountMailer = mailer.NewOutboxMailer(config.MailerOutboxPath)
+	default:
+		log.Fatalf("❌ MAILER_DRIVER must be smtp or outbox, got: %q", config.MailerDriver)
+	}
+	// the users without a verified email only get their notifications in local development, through the outbox file
+	var fallbackNotifier notifier.Notifier
+	if config.NotifierOutboxPath != "" {
+		if !config.HostEnvironment.IsLocal() {
+			log.Fatalf("❌ NOTIFIER_OUTBOX_PATH writes tokens in plain text, it is only allowed in the local environment")
+		}
+		fallbackNotifier = notifier.NewFileNotifier(config.NotifierOutboxPath)
+	}
+	accountNotifier := notifier.NewMailNotifier(accountMailer, fallbackNotifier)
+
+	// instantiate the recipe search index, the embedded index is built from mongo when it starts empty
+	var recipeSearchIndex search.SearchIndex
+	var diskSearchIndex *search.DiskIndex
+	switch config.SearchBackend {
+	case "mongo":
+		recipeSearchIndex = search.NewMongoIndex(ctx, recipesCollection)
+	case "disk":
+		diskSearchIndex, err = search.OpenDiskIndex(config.SearchIndexPath)
+		if err != nil {
+			log.Fatalf("❌ unable to open the search index, error: %v", err)
+		}
+		recipeSearchIndex = diskSearchIndex
+	default:
+		log.Fatalf("❌ unknown search backend: %s", config.SearchBackend)
+	}
+
+	// the food composition table the nutrition of the recipes is estimated with, the bundled table can be replaced by a bigger one on disk
+	nutritionDatabase := nutrition.Bundled()
+	if config.NutritionDatasetPath != "" {
+		nutritionDatabase, err = nutrition.Load(config.NutritionDatasetPath)
+		if err != nil {
+			log.Fatalf("❌ unable to load the nutrition dataset, error: %v", err)
+		}
+	}
+	log.Printf("✅ loaded %d foods for nutrition estimates\n", nutritionDatabase.Len())
+
+	// the password policy, the bundled breached password list can be replaced by a bigger one on disk
+	passwordPolicy := validation.PasswordPolicy{
+		MinLength: int(config.PasswordMinLength),
+		MinScore:  int(config.PasswordMinScore),
+	}
+	if config.PasswordCheckBreached {
+		passwordPolicy.BreachedPasswords = validation.BundledPasswordList()
+		if config.PasswordBreachedListPath != "" {
+			passwordPolicy.BreachedPasswords, err = validation.LoadPasswordList(config.PasswordBreachedListPath)
+			if err != nil {
+				log.Fatalf("❌ unable to load the breached password list, error: %v", err)
+			}
+		}
+	}
+
+	// the parameters new password hashes are created with, older hashes are upgraded on sign in
+	passwordHashParams := passwordhash.DefaultParams
+	passwordHashParams.Algorithm = config.PasswordHashAlgorithm
+	passwordHashParams.BcryptCost = int(config.BcryptCost)
+	passwordHashParams.Argon2Time = uint32(config.Argon2Time)
+	passwordHashParams.Argon2MemoryKiB = uint32(config.Argon2MemoryKiB)
+	passwordHashParams.Argon2Threads = uint8(config.Argon2Threads)
+	err = passwordHashParams.Validate()
+	if err != nil {
+		log.Fatalf("❌ invalid password hashing configuration, error: %v", err)
+	}
 
 	// instantiate the service(s)
-	userService := service.NewUserService(userRepository)
-	recipeService := service.NewRecipeService(recipeRepository)
+	userService = service.NewUserService(userRepository, passwordPolicy, passwordHashParams)
+	nutritionService := service.NewNutritionService(ctx, redisClient, nutritionDatabase, config.NutritionCacheTTL)
+	recipeService := service.NewRecipeService(recipeRepository, recipeSearchIndex, nutritionService)
+	scalingService := service.NewScalingService()
+	autocompleteService := service.NewAutocompleteService(ctx, redisClient, recipeRepository, config.AutocompleteCacheTTL)
+	refreshTokenService := service.NewRefreshTokenService(refreshTokenRepository, config.RefreshTokenTTL)
+	revocationService = service.NewTokenRevocationService(ctx, redisClient, config.AccessTokenTTL)
+	passwordResetService := service.NewPasswordResetService(passwordResetTokenRepository, userService, accountNotifier, config.PasswordResetTokenTTL)
+	emailVerificationService := service.NewEmailVerificationService(ctx, redisClient, userService, accountMailer, config.AppBaseURL, config.EmailVerificationTokenTTL)
+	loginThrottleService := service.NewLoginThrottleService(ctx, redisClient, config.LoginMaxAttempts, config.LoginLockoutDuration)
+	twoFactorService := service.NewTwoFactorService(ctx, redisClient, userRepository, config.TOTPIssuer)
+	apiKeyService = service.NewAPIKeyService(apiKeyRepository)
+	sessionService = service.NewSessionService(ctx, redisClient, refreshTokenRepository, revocationService)
+	socialLoginService := service.NewSocialLoginService(ctx, redisClient, userService, oidcProviders)
+	accountService = service.NewAccountService(ctx, redisClient, userService, userRepository, recipeRepository, refreshTokenRepository, apiKeyRepository, passwordResetTokenRepository, revocationService, recipeSearchIndex, autocompleteService, config.AccountDeletionGracePeriod)
+
+	// the embedded search index is built from the recipes in mongo when it starts empty and caught up with them otherwise
+	if diskSearchIndex != nil {
+		indexed, removed, err := recipeService.ReconcileSearchIndex()
+		if err != nil {
+			log.Fatalf("❌ unable to build the search index, error: %v", err)
+		}
+		log.Printf("✅ indexed %d and removed %d recipes for search, %d recipes are searchable\n", indexed, removed, diskSearchIndex.Count())
+
+		// catch the index up with the recipes it missed while running
+		go reconcileSearchIndex(recipeService, config.SearchReconcileInterval)
+	}
 
 	// instantiate the handler(s)
-	recipesHandler = handlers.NewRecipesHandler(ctx, recipesCollection, redisClient, recipeService)
-	authHandler = handlers.NewAuthHandler(ctx, usersCollection, userService)
+	recipesHandler = handlers.NewRecipesHandler(ctx, recipesCollection, redisClient, recipeService, autocompleteService, scalingService, nutritionService, config.RecipesQueryCacheTTL)
+	authHandler = handlers.NewAuthHandler(ctx, usersCollection, userService, refreshTokenService, revocationService, passwordResetService, emailVerificationService, loginThrottleService, twoFactorService, jwtKeyring)
+	adminHandler = handlers.NewAdminHandler(ctx, userService, recipeService, refreshTokenService, revocationService, loginThrottleService, accountService)
+	apiKeysHandler = handlers.NewAPIKeysHandler(ctx, apiKeyService)
+	oidcHandler = handlers.NewOIDCHandler(ctx, socialLoginService, authHandler)
+	accountHandler = handlers.NewAccountHandler(ctx, accountService, authHandler)
+	sessionsHandler = handlers.NewSessionsHandler(ctx, sessionService)
 }
 
 // this is just a test route - no logic here
@@ -115,14 +282,23 @@ func PrometheusMiddleware() gin.HandlerFunc {
 	}
 }
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware : authenticates the request with the bearer jwt from the Authorization header
+// if acceptAPIKeys is true, an api key in the X-API-Key header is accepted as well, the handlers get the same `auth` value either way
+func AuthMiddleware(acceptAPIKeys bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenValue := c.GetHeader("Authorization")
+		if apiKeyValue := c.GetHeader("X-API-Key"); apiKeyValue != "" {
+			if !acceptAPIKeys {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api keys are not accepted on this endpoint"})
+				return
+			}
+			authenticateAPIKey(c, apiKeyValue)
+			return
+		}
+
+		tokenValue := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
 
 		claims := &handlers.Claims{}
-		token, err := jwt.ParseWithClaims(tokenValue, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(config.JWTSecretKey), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenValue, claims, jwtKeyring.Keyfunc)
 		if err != nil {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
@@ -132,11 +308,236 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
+
+		// mfa pending tokens can only be used on /signin/mfa
+		if claims.Audience == handlers.MFAPendingAudience {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		// tokens issued before the `sub` claim was introduced are resolved by username, until config.LegacyTokensAcceptedUntil
+		var userID primitive.ObjectID
+		if claims.StandardClaims.Subject != "" {
+			userID, err = primitive.ObjectIDFromHex(claims.StandardClaims.Subject)
+			if err != nil {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		} else if time.Now().After(config.LegacyTokensAcceptedUntil) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		user, ok := loadActiveUser(c, userID, claims.Username)
+		if !ok {
+			return
+		}
+		claims.StandardClaims.Subject = user.ID.Hex()
+
+		// reject tokens that were revoked on sign out, the user wide revocations are kept under the user ID
+		isRevoked, err := revocationService.IsRevoked(claims.Id, claims.SessionID, user.ID, claims.IssuedAtTime())
+		if err != nil {
+			log.Printf("unable to check token revocation status, err: %v\n", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		if isRevoked {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		// tokens issued before sessions were introduced have no sid
+		if sessionID, err := primitive.ObjectIDFromHex(claims.SessionID); err == nil {
+			err = sessionService.Touch(sessionID, c.ClientIP())
+			if err != nil {
+				log.Printf("unable to update the last seen time of session: %s, err: %v\n", claims.SessionID, err)
+			}
+		}
+
 		c.Set("auth", claims)
+		c.Set("user", user)
 		c.Next()
 	}
 }
 
+// authenticateAPIKey : resolves the api key and builds the same claims a jwt of its owner would carry, restricted to the key scopes
+func authenticateAPIKey(c *gin.Context, apiKeyValue string) {
+	apiKey, err := apiKeyService.Authenticate(apiKeyValue)
+	if err != nil {
+		if err == service.ErrInvalidAPIKey {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := loadActiveUser(c, apiKey.UserID, apiKey.Username)
+	if !ok {
+		return
+	}
+
+	claims := &handlers.Claims{
+		Username: user.Username,
+		IsAdmin:  user.IsAdmin,
+		Role:     string(rbac.RoleOf(user)),
+		APIKeyID: apiKey.ID.Hex(),
+		Scopes:   apiKey.Scopes,
+	}
+	claims.StandardClaims.Subject = user.ID.Hex()
+
+	c.Set("auth", claims)
+	c.Set("user", user)
+	c.Next()
+}
+
+// loadActiveUser : loads the user record by ID (by username if the ID is unknown),
+// banned and suspended users are rejected even if their credentials are still valid
+func loadActiveUser(c *gin.Context, userID primitive.ObjectID, username string) (*models.User, bool) {
+	var user *models.User
+	var err error
+	if !userID.IsZero() {
+		user, err = userService.FindOneByID(userID)
+	} else {
+		user, err = userService.FindOne(username)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return nil, false
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	// FindOne already ignores deleted users, this guards against a user deleted in between
+	if user.DeletedAt != nil {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if user.IsBlocked(time.Now()) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account is banned or suspended"})
+		return nil, false
+	}
+
+	return user, true
+}
+
+// RequireScope : rejects api keys that were not granted the scope, it has to run after the AuthMiddleware
+func RequireScope(scope rbac.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jwtAuthToken, exists := c.Get("auth")
+		if !exists {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		claims, ok := jwtAuthToken.(*handlers.Claims)
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if !claims.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("missing scope: %s", scope)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// VerifiedEmailMiddleware : only lets users with a verified email through, it has to run after the AuthMiddleware
+func VerifiedEmailMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// the user record is loaded by the AuthMiddleware
+		authUser, exists := c.Get("user")
+		if !exists {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		user, ok := authUser.(*models.User)
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		// the accounts that existed before the email verification are let through, see the migration
+		if !user.EmailVerified && !user.EmailVerificationExempt {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "a verified email is required to perform this action"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission : only lets callers whose role grants the permission through, it has to run after the AuthMiddleware
+func RequirePermission(permission rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jwtAuthToken, exists := c.Get("auth")
+		if !exists {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		claims, ok := jwtAuthToken.(*handlers.Claims)
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if !claims.Subject().Role.Can(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("missing permission: %s", permission)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// reconcileSearchIndex : brings the search index in line with the recipes in mongo on every tick, it runs for the lifetime of the process
+func reconcileSearchIndex(recipeService service.RecipeService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		indexed, removed, err := recipeService.ReconcileSearchIndex()
+		if err != nil {
+			log.Printf("unable to reconcile the search index, err: %v\n", err)
+			continue
+		}
+
+		if indexed > 0 || removed > 0 {
+			log.Printf("reconciled the search index, indexed %d and removed %d recipes\n", indexed, removed)
+		}
+	}
+}
+
+// purgeDeletedAccounts : purges the deleted accounts and completes the renames that failed midway on every tick,
+// it runs for the lifetime of the process
+func purgeDeletedAccounts(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		renamed, err := accountService.CompletePendingRenames()
+		if err != nil {
+			log.Printf("unable to complete the pending renames, err: %v\n", err)
+		} else if renamed > 0 {
+			log.Printf("completed %d pending rename(s)\n", renamed)
+		}
+
+		purged, err := accountService.PurgeDeleted()
+		if err != nil {
+			log.Printf("unable to purge deleted accounts, err: %v\n", err)
+		} else if purged > 0 {
+			log.Printf("purged %d deleted account(s)\n", purged)
+		}
+		<-ticker.C
+	}
+}
+
 func main() {
 	router := gin.Default()
 
@@ -148,20 +549,70 @@ func main() {
 
 	router.GET("/version", VersionHandler)
 	router.GET("/prometheus", gin.WrapH(promhttp.Handler()))
+	router.GET("/.well-known/jwks.json", authHandler.JWKSHandler)
 	router.GET("/recipes", recipesHandler.ListRecipesHandler)
+	router.GET("/recipes/search", recipesHandler.SearchRecipesHandler)
+	router.GET("/recipes/autocomplete", recipesHandler.AutocompleteHandler)
 	router.POST("/signup", authHandler.SignUpHandler)
 	router.POST("/signin", authHandler.SignInHandler)
+	router.POST("/signin/mfa", authHandler.SignInMFAHandler)
 	router.POST("/refresh", authHandler.RefreshHandler)
+	router.POST("/password/forgot", authHandler.ForgotPasswordHandler)
+	router.POST("/password/reset", authHandler.ResetPasswordHandler)
+	router.GET("/email/verify", authHandler.CheckEmailVerificationHandler)
+	router.POST("/email/verify", authHandler.VerifyEmailHandler)
+	router.GET("/oauth/:provider/login", oidcHandler.LoginHandler)
+	router.GET("/oauth/:provider/callback", oidcHandler.CallbackHandler)
+
+	// the recipe endpoints can also be used with an api key
+	recipes := router.Group("/recipes")
+	recipes.Use(AuthMiddleware(true))
+	{
+		recipes.POST("", RequireScope(rbac.ScopeRecipesWrite), RequirePermission(rbac.PermRecipeCreate), VerifiedEmailMiddleware(), recipesHandler.CreateRecipeHandler)
+		recipes.GET("/:id", RequireScope(rbac.ScopeRecipesRead), recipesHandler.GetOneRecipeHandler)
+		recipes.GET("/:id/ingredients", RequireScope(rbac.ScopeRecipesRead), recipesHandler.ListIngredientsHandler)
+		recipes.GET("/:id/nutrition", RequireScope(rbac.ScopeRecipesRead), recipesHandler.NutritionHandler)
+		recipes.PUT("/:id", RequireScope(rbac.ScopeRecipesWrite), recipesHandler.UpdateRecipeHandler)
+		recipes.DELETE("/:id", RequireScope(rbac.ScopeRecipesWrite), recipesHandler.DeleteRecipeHandler)
+	}
 
 	authorized := router.Group("/")
-	authorized.Use(AuthMiddleware())
+	authorized.Use(AuthMiddleware(false))
 	{
-		authorized.POST("/recipes", recipesHandler.CreateRecipeHandler)
-		authorized.GET("/recipes/:id", recipesHandler.GetOneRecipeHandler)
-		authorized.PUT("/recipes/:id", recipesHandler.UpdateRecipeHandler)
-		authorized.DELETE("/recipes/:id", recipesHandler.DeleteRecipeHandler)
+		authorized.POST("/signout", authHandler.SignOutHandler)
+		authorized.POST("/signout/all", authHandler.SignOutAllHandler)
+		authorized.PUT("/me/email", authHandler.UpdateEmailHandler)
+		authorized.POST("/email/verification", authHandler.ResendEmailVerificationHandler)
+		authorized.POST("/me/2fa/enroll", authHandler.EnrollTwoFactorHandler)
+		authorized.POST("/me/2fa/confirm", authHandler.ConfirmTwoFactorHandler)
+		authorized.POST("/me/2fa/disable", authHandler.DisableTwoFactorHandler)
+		authorized.POST("/me/api-keys", apiKeysHandler.CreateAPIKeyHandler)
+		authorized.GET("/me/api-keys", apiKeysHandler.ListAPIKeysHandler)
+		authorized.DELETE("/me/api-keys/:id", apiKeysHandler.RevokeAPIKeyHandler)
+		authorized.PUT("/me/password", accountHandler.ChangePasswordHandler)
+		authorized.PUT("/me/username", accountHandler.ChangeUsernameHandler)
+		authorized.DELETE("/me", accountHandler.DeleteAccountHandler)
+		authorized.GET("/me/sessions", sessionsHandler.ListSessionsHandler)
+		authorized.DELETE("/me/sessions/:id", sessionsHandler.RevokeSessionHandler)
 	}
 
+	admin := authorized.Group("/admin")
+	{
+		admin.GET("/users", RequirePermission(rbac.PermUserRead), adminHandler.ListUsersHandler)
+		admin.GET("/users/:username", RequirePermission(rbac.PermUserRead), adminHandler.GetUserHandler)
+		admin.GET("/users/:username/recipes", RequirePermission(rbac.PermRecipeReadAny), adminHandler.ListUserRecipesHandler)
+		admin.PUT("/users/:username/role", RequirePermission(rbac.PermUserManageRoles), adminHandler.UpdateRoleHandler)
+		admin.POST("/users/:username/ban", RequirePermission(rbac.PermUserBan), adminHandler.BanUserHandler)
+		admin.POST("/users/:username/suspend", RequirePermission(rbac.PermUserBan), adminHandler.SuspendUserHandler)
+		admin.POST("/users/:username/reinstate", RequirePermission(rbac.PermUserBan), adminHandler.ReinstateUserHandler)
+		admin.POST("/users/:username/revoke-sessions", RequirePermission(rbac.PermUserRevokeTokens), adminHandler.RevokeSessionsHandler)
+		admin.POST("/users/:username/unlock", RequirePermission(rbac.PermUserUnlock), adminHandler.UnlockUserHandler)
+		admin.POST("/users/:username/restore", RequirePermission(rbac.PermUserRestore), adminHandler.RestoreUserHandler)
+	}
+
+	// permanently delete the accounts whose deletion grace period is over
+	go purgeDeletedAccounts(config.AccountPurgeInterval)
+
 	addr := fmt.Sprintf(":%s", config.ServerPort)
 	router.Run(addr)
 }
@@ -0,0 +1,70 @@
+// Package migrations holds the one-off data migrations, they are applied in order on startup
+package migrations
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const migrationCollectionName string = "migrations"
+
+// Migration : a named, idempotent change to the data
+// Up has to be safe to run again, an interrupted run is retried on the next startup
+type Migration struct {
+	ID          string
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// appliedMigration : the record kept in the `migrations` collection for every applied migration
+type appliedMigration struct {
+	ID        string    `bson:"_id"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// All : every migration, in the order they are applied, new migrations are appended
+var All = []Migration{
+	backfillRecipeAuthorID,
+	indexRecipeFeed,
+	indexRecipeFilters,
+	indexRecipeText,
+	classifyRecipes,
+	indexRecipeMetadata,
+	exemptExistingUsersFromEmailVerification,
+	indexUserUsernameUnique,
+	noticeRecipeDietaryLabels,
+	indexRefreshTokens,
+	hideRecipesOfDeletedUsers,
+}
+
+// Run : applies the migrations that have not been applied yet
+func Run(ctx context.Context, db *mongo.Database, migrations []Migration) error {
+	collection := db.Collection(migrationCollectionName)
+
+	for _, migration := range migrations {
+		err := collection.FindOne(ctx, bson.M{"_id": migration.ID}).Err()
+		if err == nil {
+			continue
+		}
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		log.Printf("applying migration %s: %s\n", migration.ID, migration.Description)
+		err = migration.Up(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		_, err = collection.InsertOne(ctx, appliedMigration{ID: migration.ID, AppliedAt: time.Now()})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
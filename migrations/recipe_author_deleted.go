@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// hideRecipesOfDeletedUsers : the recipes of a deleted user are hidden until the account is purged,
+// the users deleted before that get their recipes hidden once
+var hideRecipesOfDeletedUsers = Migration{
+	ID:          "20261017_hide_recipes_of_deleted_users",
+	Description: "flag the recipes of the soft deleted users with authorDeletedAt",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		cursor, err := db.Collection("users").Find(ctx, bson.M{"deleted_at": bson.M{"$ne": nil}})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var user models.User
+			err = cursor.Decode(&user)
+			if err != nil {
+				return err
+			}
+
+			_, err = db.Collection("recipes").UpdateMany(ctx,
+				bson.M{"authorId": user.ID},
+				bson.M{"$set": bson.M{"authorDeletedAt": user.DeletedAt}},
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return cursor.Err()
+	},
+}
@@ -0,0 +1,95 @@
+package migrations
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// backfillRecipeAuthorID : recipes used to point at their author by username only,
+// the ID of the user with that username is copied to `authorId`
+var backfillRecipeAuthorID = Migration{
+	ID:          "20261017_backfill_recipe_author_id",
+	Description: "backfill authorId on recipes and index it",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		recipes := db.Collection("recipes")
+		users := db.Collection("users")
+
+		withoutAuthor := bson.M{"authorId": bson.M{"$in": bson.A{nil, primitive.NilObjectID}}}
+		usernames, err := recipes.Distinct(ctx, "username", withoutAuthor)
+		if err != nil {
+			return err
+		}
+
+		for _, value := range usernames {
+			username, ok := value.(string)
+			if !ok {
+				continue
+			}
+
+			// deleted users that have not been purged yet still own their recipes
+			var user models.User
+			err := users.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+			if err != nil {
+				if err == mongo.ErrNoDocuments {
+					err = logRecipesWithoutAuthor(ctx, recipes, username)
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				return err
+			}
+
+			_, err = recipes.UpdateMany(ctx,
+				bson.M{"username": username, "authorId": bson.M{"$in": bson.A{nil, primitive.NilObjectID}}},
+				bson.M{"$set": bson.M{"authorId": user.ID}},
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = recipes.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "authorId", Value: 1}}})
+		return err
+	},
+}
+
+// logRecipesWithoutAuthor : the recipes of a username that matches no user are left without an author, they can only be
+// removed by an admin, their IDs are logged so that they can be reassigned or removed by hand
+func logRecipesWithoutAuthor(ctx context.Context, recipes *mongo.Collection, username string) error {
+	cur, err := recipes.Find(ctx,
+		bson.M{"username": username, "authorId": bson.M{"$in": bson.A{nil, primitive.NilObjectID}}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	ids := make([]string, 0)
+	for cur.Next(ctx) {
+		var recipe struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		err = cur.Decode(&recipe)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, recipe.ID.Hex())
+	}
+
+	err = cur.Err()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("no user found for the %d recipe(s) of %q, leaving them without an author: %s\n", len(ids), username, strings.Join(ids, ", "))
+	return nil
+}
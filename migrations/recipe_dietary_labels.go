@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/skamranahmed/smilecook/dietary"
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// classifyRecipes : the allergens and diets are derived when a recipe is saved,
+// the recipes saved before that are classified once and indexed for the diet filter of the list
+var classifyRecipes = Migration{
+	ID:          "20261017_classify_recipes",
+	Description: "derive the allergens and diets of the recipes and index the diets",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		recipes := db.Collection("recipes")
+
+		cursor, err := recipes.Find(ctx, bson.M{"diets": bson.M{"$exists": false}})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var recipe models.Recipe
+			err = cursor.Decode(&recipe)
+			if err != nil {
+				return err
+			}
+
+			allergens, diets := dietary.Classify(recipe.Ingredients, recipe.DietaryOverrides)
+			_, err = recipes.UpdateOne(ctx,
+				bson.M{"_id": recipe.ID},
+				bson.M{"$set": bson.M{"allergens": allergens, "diets": diets}},
+			)
+			if err != nil {
+				return err
+			}
+		}
+		if err = cursor.Err(); err != nil {
+			return err
+		}
+
+		_, err = recipes.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "isPrivate", Value: 1}, {Key: "diets", Value: 1}, {Key: "publishedAt", Value: -1}},
+		})
+		return err
+	},
+}
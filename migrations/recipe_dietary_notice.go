@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/skamranahmed/smilecook/dietary"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// noticeRecipeDietaryLabels : the recipes saved before the notice was added get it too, their labels are guessed the same way
+var noticeRecipeDietaryLabels = Migration{
+	ID:          "20261017_notice_recipe_dietary_labels",
+	Description: "mark the allergens and diets of the recipes as guessed from the ingredients",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("recipes").UpdateMany(ctx,
+			bson.M{"dietaryNotice": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"dietaryNotice": dietary.Notice}},
+		)
+		return err
+	},
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// indexRecipeFeed : the paginated list of public recipes seeks on publishedAt then _id, both descending
+var indexRecipeFeed = Migration{
+	ID:          "20261017_index_recipe_feed",
+	Description: "index recipes on isPrivate, publishedAt and _id for the paginated list",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("recipes").Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "isPrivate", Value: 1}, {Key: "publishedAt", Value: -1}, {Key: "_id", Value: -1}},
+		})
+		return err
+	},
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// indexRecipeFilters : the recipe list filters by tag and author and sorts by name,
+// publishedAt is already covered in both directions by the feed index
+var indexRecipeFilters = Migration{
+	ID:          "20261017_index_recipe_filters",
+	Description: "index recipes on tags, username and name for the filters and the sorting of the list",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("recipes").Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "isPrivate", Value: 1}, {Key: "tags", Value: 1}, {Key: "publishedAt", Value: -1}}},
+			{Keys: bson.D{{Key: "isPrivate", Value: 1}, {Key: "username", Value: 1}, {Key: "publishedAt", Value: -1}}},
+			{Keys: bson.D{{Key: "isPrivate", Value: 1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		})
+		return err
+	},
+}
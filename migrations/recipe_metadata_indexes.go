@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// indexRecipeMetadata : the recipe list filters by cuisine and course and by a range of total time
+var indexRecipeMetadata = Migration{
+	ID:          "20261017_index_recipe_metadata",
+	Description: "index recipes on cuisine, course and total time for the filters of the list",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("recipes").Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "isPrivate", Value: 1}, {Key: "cuisine", Value: 1}, {Key: "publishedAt", Value: -1}}},
+			{Keys: bson.D{{Key: "isPrivate", Value: 1}, {Key: "course", Value: 1}, {Key: "publishedAt", Value: -1}}},
+			{Keys: bson.D{{Key: "isPrivate", Value: 1}, {Key: "totalTime", Value: 1}}},
+		})
+		return err
+	},
+}
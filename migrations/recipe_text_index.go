@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexRecipeText : the text index behind the mongo search backend, mongo allows a single text index per collection
+var indexRecipeText = Migration{
+	ID:          "20261017_index_recipe_text",
+	Description: "text index on the name, tags, ingredients and instructions of the recipes",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("recipes").Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "name", Value: "text"},
+				{Key: "tags", Value: "text"},
+				{Key: "ingredients", Value: "text"},
+				{Key: "instructions", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("recipes_text").
+				SetDefaultLanguage("english").
+				SetWeights(bson.D{
+					{Key: "name", Value: 10},
+					{Key: "tags", Value: 5},
+					{Key: "ingredients", Value: 3},
+					{Key: "instructions", Value: 1},
+				}),
+		})
+		return err
+	},
+}
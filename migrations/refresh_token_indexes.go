@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexRefreshTokens : a refresh is looked up by the hash of its token, a family and the tokens of a user are revoked together,
+// and mongo removes a token once it has expired, whether it was rotated, revoked or never used
+var indexRefreshTokens = Migration{
+	ID:          "20261017_index_refresh_tokens",
+	Description: "index refresh_tokens on token_hash, unique, on family_id and user_id, and expire them on expires_at",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("refresh_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "family_id", Value: 1}}},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		})
+		return err
+	},
+}
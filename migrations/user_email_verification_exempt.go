@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// exemptExistingUsersFromEmailVerification : creating a recipe requires a verified email,
+// the accounts created before that requirement have no email to verify and keep creating recipes
+var exemptExistingUsersFromEmailVerification = Migration{
+	ID:          "20261017_exempt_existing_users_from_email_verification",
+	Description: "let the users that existed before the email verification create recipes without a verified email",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("users").UpdateMany(ctx,
+			bson.M{"email_verified": bson.M{"$ne": true}},
+			bson.M{"$set": bson.M{"email_verification_exempt": true}},
+		)
+		return err
+	},
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexUserUsernameUnique : the username of a user is unique, a rename relies on the index to reject a taken username
+var indexUserUsernameUnique = Migration{
+	ID:          "20261017_index_user_username_unique",
+	Description: "index users on username, unique",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		return err
+	},
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey : a long-lived personal key used by scripts and integrations, only the hash of the key is persisted
+type APIKey struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Username   string             `json:"username" bson:"username"`
+	Name       string             `json:"name" bson:"name"`
+	Prefix     string             `json:"prefix" bson:"prefix"` // the non secret start of the key, used to identify it
+	KeyHash    string             `json:"-" bson:"key_hash"`
+	Scopes     []string           `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt  *time.Time         `json:"expires_at" bson:"expires_at"`
+	LastUsedAt *time.Time         `json:"last_used_at" bson:"last_used_at"`
+	RevokedAt  *time.Time         `json:"revoked_at" bson:"revoked_at"`
+}
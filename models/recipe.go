@@ -3,16 +3,58 @@ package models
 import (
 	"time"
 
+	"github.com/skamranahmed/smilecook/dietary"
+	"github.com/skamranahmed/smilecook/duration"
+	"github.com/skamranahmed/smilecook/ingredient"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Recipe struct {
 	ID           primitive.ObjectID `json:"id" bson:"_id"`
 	Name         string             `json:"name" bson:"name"`
-	Username     string             `json:"username" bson:"username"`
+	AuthorID     primitive.ObjectID `json:"author_id" bson:"authorId"`
+	Username     string             `json:"username" bson:"username"` // display name of the author, AuthorID is the ownership key
 	Tags         []string           `json:"tags" bson:"tags"`
 	Ingredients  []string           `json:"ingredients" bson:"ingredients"`
 	Instructions []string           `json:"instructions" bson:"instructions"`
+	Servings     int                `json:"servings,omitempty" bson:"servings,omitempty" binding:"min=0"`
 	PublishedAt  time.Time          `json:"published_at" bson:"publishedAt"`
 	IsPrivate    bool               `json:"is_private" bson:"isPrivate"`
+
+	// set while the account of the author is deleted and can still be restored, the recipe is hidden from the lists,
+	// the search and the autocomplete meanwhile
+	AuthorDeletedAt *time.Time `json:"-" bson:"authorDeletedAt,omitempty"`
+
+	// optional metadata, the times are ISO 8601 durations and the total time defaults to the prep time plus the cook time
+	PrepTime   *duration.Duration `json:"prep_time,omitempty" bson:"prepTime,omitempty"`
+	CookTime   *duration.Duration `json:"cook_time,omitempty" bson:"cookTime,omitempty"`
+	TotalTime  *duration.Duration `json:"total_time,omitempty" bson:"totalTime,omitempty"`
+	Difficulty Difficulty         `json:"difficulty,omitempty" bson:"difficulty,omitempty"`
+	Cuisine    string             `json:"cuisine,omitempty" bson:"cuisine,omitempty" binding:"max=50"`
+	Course     Course             `json:"course,omitempty" bson:"course,omitempty"`
+	Yield      *Yield             `json:"yield,omitempty" bson:"yield,omitempty"`
+	Equipment  []string           `json:"equipment,omitempty" bson:"equipment,omitempty" binding:"max=30,dive,max=50"`
+
+	// derived from the ingredients whenever the recipe is saved, the overrides of the author take precedence.
+	// The labels are a heuristic, DietaryNotice tells the clients so
+	Allergens        []dietary.Allergen `json:"allergens" bson:"allergens"`
+	Diets            []dietary.Diet     `json:"diets" bson:"diets"`
+	DietaryOverrides *dietary.Overrides `json:"dietary_overrides,omitempty" bson:"dietaryOverrides,omitempty"`
+	DietaryNotice    string             `json:"dietary_notice,omitempty" bson:"dietaryNotice,omitempty"`
+
+	structuredIngredients []ingredient.Ingredient // parsed from Ingredients on first use
+}
+
+// IsListed : reports whether the recipe shows up in the lists, the search and the autocomplete
+func (r *Recipe) IsListed() bool {
+	return !r.IsPrivate && r.AuthorDeletedAt == nil
+}
+
+// StructuredIngredients : the ingredients split into quantities, units and items, the lines are parsed on first use
+// and every ingredient keeps the line it comes from
+func (r *Recipe) StructuredIngredients() []ingredient.Ingredient {
+	if r.structuredIngredients == nil {
+		r.structuredIngredients = ingredient.ParseAll(r.Ingredients)
+	}
+	return r.structuredIngredients
 }
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skamranahmed/smilecook/duration"
+)
+
+// Difficulty : how hard a recipe is to make
+type Difficulty string
+
+const (
+	DifficultyEasy   Difficulty = "easy"
+	DifficultyMedium Difficulty = "medium"
+	DifficultyHard   Difficulty = "hard"
+)
+
+// Difficulties : every difficulty, from the easiest
+var Difficulties = []Difficulty{DifficultyEasy, DifficultyMedium, DifficultyHard}
+
+// IsValid : reports whether the difficulty is one of the supported ones
+func (d Difficulty) IsValid() bool {
+	for _, difficulty := range Difficulties {
+		if d == difficulty {
+			return true
+		}
+	}
+	return false
+}
+
+// Course : the part of a meal a recipe is served as
+type Course string
+
+const (
+	CourseBreakfast Course = "breakfast"
+	CourseAppetizer Course = "appetizer"
+	CourseSoup      Course = "soup"
+	CourseSalad     Course = "salad"
+	CourseMain      Course = "main"
+	CourseSide      Course = "side"
+	CourseDessert   Course = "dessert"
+	CourseSnack     Course = "snack"
+	CourseDrink     Course = "drink"
+	CourseSauce     Course = "sauce"
+)
+
+// Courses : every course, in the order of a meal
+var Courses = []Course{CourseBreakfast, CourseAppetizer, CourseSoup, CourseSalad, CourseMain, CourseSide, CourseDessert, CourseSnack, CourseDrink, CourseSauce}
+
+// IsValid : reports whether the course is one of the supported ones
+func (c Course) IsValid() bool {
+	for _, course := range Courses {
+		if c == course {
+			return true
+		}
+	}
+	return false
+}
+
+// Yield : what a recipe makes, e.g. 24 cookies or 2 loaves
+type Yield struct {
+	Quantity float64 `json:"quantity" bson:"quantity" binding:"gt=0"`
+	Unit     string  `json:"unit" bson:"unit" binding:"required,max=30"`
+}
+
+// maxRecipeTime : no recipe takes longer, a longer time is a typo
+const maxRecipeTime time.Duration = 30 * 24 * time.Hour
+
+// Validate : validates the fields of a recipe that the binding tags cannot, it also normalizes the cuisine and the equipment
+// and fills the total time from the prep and cook times when it is missing
+func (r *Recipe) Validate() error {
+	err := r.DietaryOverrides.Validate()
+	if err != nil {
+		return err
+	}
+
+	times := []struct {
+		name  string
+		value *duration.Duration
+	}{{"prep_time", r.PrepTime}, {"cook_time", r.CookTime}, {"total_time", r.TotalTime}}
+	for _, t := range times {
+		if t.value != nil && (t.value.Std() < 0 || t.value.Std() > maxRecipeTime) {
+			return fmt.Errorf("%s must be between PT0S and %s", t.name, duration.Format(maxRecipeTime))
+		}
+	}
+
+	if r.TotalTime != nil {
+		parts := []struct {
+			name  string
+			value *duration.Duration
+		}{{"prep_time", r.PrepTime}, {"cook_time", r.CookTime}}
+		for _, part := range parts {
+			if part.value != nil && r.TotalTime.Std() < part.value.Std() {
+				return fmt.Errorf("total_time must be at least %s (%s)", part.name, part.value)
+			}
+		}
+	}
+
+	if r.PrepTime != nil && r.CookTime != nil {
+		activeTime := r.PrepTime.Std() + r.CookTime.Std()
+		if r.TotalTime == nil {
+			totalTime := duration.Duration(activeTime)
+			r.TotalTime = &totalTime
+		} else if r.TotalTime.Std() < activeTime {
+			return fmt.Errorf("total_time must be at least prep_time plus cook_time (%s)", duration.Format(activeTime))
+		}
+	}
+
+	if r.Difficulty != "" && !r.Difficulty.IsValid() {
+		return fmt.Errorf("difficulty must be one of %s", joinDifficulties(Difficulties))
+	}
+
+	if r.Course != "" && !r.Course.IsValid() {
+		return fmt.Errorf("course must be one of %s", joinCourses(Courses))
+	}
+
+	r.Cuisine = strings.ToLower(strings.TrimSpace(r.Cuisine))
+
+	equipment := make([]string, 0, len(r.Equipment))
+	for _, item := range r.Equipment {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return fmt.Errorf("equipment must not contain empty items")
+		}
+		equipment = append(equipment, item)
+	}
+	r.Equipment = equipment
+
+	return nil
+}
+
+// joinDifficulties : the names of the difficulties, comma separated
+func joinDifficulties(difficulties []Difficulty) string {
+	names := make([]string, 0, len(difficulties))
+	for _, difficulty := range difficulties {
+		names = append(names, string(difficulty))
+	}
+	return strings.Join(names, ", ")
+}
+
+// joinCourses : the names of the courses, comma separated
+func joinCourses(courses []Course) string {
+	names := make([]string, 0, len(courses))
+	for _, course := range courses {
+		names = append(names, string(course))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/skamranahmed/smilecook/duration"
+)
+
+func minutes(m int) *duration.Duration {
+	d := duration.Duration(time.Duration(m) * time.Minute)
+	return &d
+}
+
+func TestValidateTimes(t *testing.T) {
+	tests := []struct {
+		name      string
+		prep      *duration.Duration
+		cook      *duration.Duration
+		total     *duration.Duration
+		wantTotal *duration.Duration
+		wantErr   bool
+	}{
+		{name: "no times"},
+		{name: "total filled from prep and cook", prep: minutes(10), cook: minutes(20), wantTotal: minutes(30)},
+		{name: "total with prep and cook", prep: minutes(10), cook: minutes(20), total: minutes(45), wantTotal: minutes(45)},
+		{name: "total shorter than prep plus cook", prep: minutes(10), cook: minutes(20), total: minutes(25), wantErr: true},
+		{name: "total shorter than prep", prep: minutes(30), total: minutes(20), wantErr: true},
+		{name: "total shorter than cook", cook: minutes(30), total: minutes(20), wantErr: true},
+		{name: "total with prep only", prep: minutes(20), total: minutes(20), wantTotal: minutes(20)},
+		{name: "prep only", prep: minutes(20)},
+		{name: "time too long", cook: minutes(31 * 24 * 60), wantErr: true},
+		{name: "negative time", prep: minutes(-1), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recipe := &Recipe{PrepTime: test.prep, CookTime: test.cook, TotalTime: test.total}
+			err := recipe.Validate()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Validate() error = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if (recipe.TotalTime == nil) != (test.wantTotal == nil) || (recipe.TotalTime != nil && *recipe.TotalTime != *test.wantTotal) {
+				t.Errorf("Validate() total_time = %v, want %v", recipe.TotalTime, test.wantTotal)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken : a long-lived, opaque refresh token, only the hash of the token is persisted
+// all the tokens that descend from the same sign-in share the same FamilyID, a family is what the users see as a session
+type RefreshToken struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	FamilyID  primitive.ObjectID `json:"family_id" bson:"family_id"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Username  string             `json:"username" bson:"username"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	RotatedAt *time.Time         `json:"rotated_at" bson:"rotated_at"`
+	RevokedAt *time.Time         `json:"revoked_at" bson:"revoked_at"`
+
+	// session details, copied to every token of the family on rotation
+	SessionStartedAt time.Time `json:"session_started_at" bson:"session_started_at"`
+	UserAgent        string    `json:"user_agent" bson:"user_agent"`
+	IP               string    `json:"ip" bson:"ip"`
+	LastSeenAt       time.Time `json:"last_seen_at" bson:"last_seen_at"`
+	LastSeenIP       string    `json:"last_seen_ip" bson:"last_seen_ip"`
+}
+
+// Session : the device a user signed in from, a view on the current refresh token of a family
+type Session struct {
+	ID         primitive.ObjectID `json:"id"`
+	UserAgent  string             `json:"user_agent"`
+	IP         string             `json:"ip"`
+	CreatedAt  time.Time          `json:"created_at"`
+	LastSeenAt time.Time          `json:"last_seen_at"`
+	LastSeenIP string             `json:"last_seen_ip"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	Current    bool               `json:"current"`
+}
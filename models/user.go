@@ -12,6 +12,59 @@ type User struct {
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
 	DeletedAt *time.Time         `json:"deleted_at" bson:"deleted_at"`
 	Username  string             `json:"username" bson:"username"`
-	Password  string             `json:"password" bson:"password"`
+	Password  string             `json:"-" bson:"password"`
 	IsAdmin   bool               `json:"is_admin" bson:"is_admin"`
+	Role      string             `json:"role" bson:"role"`
+
+	Email           string     `json:"email" bson:"email"`
+	EmailVerified   bool       `json:"email_verified" bson:"email_verified"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" bson:"email_verified_at"`
+
+	// set on the accounts that existed before a verified email was required, they keep their access without one
+	EmailVerificationExempt bool `json:"-" bson:"email_verification_exempt"`
+
+	TOTPEnabled       bool     `json:"totp_enabled" bson:"totp_enabled"`
+	TOTPSecret        string   `json:"-" bson:"totp_secret"`
+	TOTPPendingSecret string   `json:"-" bson:"totp_pending_secret"`
+	RecoveryCodes     []string `json:"-" bson:"recovery_codes"` // hashed, every code can only be used once
+
+	BannedAt         *time.Time `json:"banned_at" bson:"banned_at"`
+	SuspendedUntil   *time.Time `json:"suspended_until" bson:"suspended_until"`
+	ModerationReason string     `json:"moderation_reason" bson:"moderation_reason"`
+
+	Identities []Identity `json:"identities" bson:"identities"`
+
+	// set while the new username is copied to the records of the user, see AccountService.ChangeUsername
+	PendingRename *UsernameChange `json:"-" bson:"pending_rename,omitempty"`
+
+	// set when a user with two-factor authentication signs in with an outdated password hash, applied once the second factor is verified
+	PendingRehash *PasswordRehash `json:"-" bson:"pending_rehash,omitempty"`
+}
+
+// PasswordRehash : an upgraded hash of the password and the hash it replaces, it only applies while the password is unchanged
+type PasswordRehash struct {
+	From string `bson:"from"`
+	To   string `bson:"to"`
+}
+
+// UsernameChange : a rename whose cascade to the other records of the user has not completed yet
+type UsernameChange struct {
+	From string `bson:"from"`
+	To   string `bson:"to"`
+}
+
+// Identity : an account at an external OpenID Connect provider that is linked to the user
+type Identity struct {
+	Provider string    `json:"provider" bson:"provider"`
+	Subject  string    `json:"-" bson:"subject"`
+	Email    string    `json:"email" bson:"email"`
+	LinkedAt time.Time `json:"linked_at" bson:"linked_at"`
+}
+
+// IsBlocked : checks whether the user is banned or currently suspended
+func (u *User) IsBlocked(now time.Time) bool {
+	if u.BannedAt != nil {
+		return true
+	}
+	return u.SuspendedUntil != nil && now.Before(*u.SuspendedUntil)
 }
@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/skamranahmed/smilecook/models"
+)
+
+// Notification : a notification written by the fileNotifier
+type Notification struct {
+	Kind      string    `json:"kind"`
+	Username  string    `json:"username"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// NewFileNotifier : returns a fileNotifier struct that implements the Notifier interface
+// every notification is appended as a JSON line to the file at path
+// meant for local development and tests only, the tokens are written in plain text
+func NewFileNotifier(path string) Notifier {
+	return &fileNotifier{
+		path: path,
+	}
+}
+
+type fileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+// SendPasswordReset : writes the password reset token for the user
+func (fn *fileNotifier) SendPasswordReset(u *models.User, token string, expiresAt time.Time) error {
+	return fn.write(Notification{
+		Kind:      "password_reset",
+		Username:  u.Username,
+		Token:     token,
+		ExpiresAt: expiresAt,
+		SentAt:    time.Now(),
+	})
+}
+
+func (fn *fileNotifier) write(n Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	// the notification carries a token, it must never end up in the logs
+	if fn.path == "" {
+		return errors.New("the file notifier needs an outbox path")
+	}
+
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+
+	f, err := os.OpenFile(fn.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/skamranahmed/smilecook/mailer"
+	"github.com/skamranahmed/smilecook/models"
+)
+
+// NewMailNotifier : returns a mailNotifier struct that implements the Notifier interface
+// users with a verified email receive the notification by mail, everyone else goes through the fallback notifier,
+// without a fallback their notifications are dropped
+func NewMailNotifier(m mailer.Mailer, fallback Notifier) Notifier {
+	return &mailNotifier{
+		mailer:   m,
+		fallback: fallback,
+	}
+}
+
+type mailNotifier struct {
+	mailer   mailer.Mailer
+	fallback Notifier
+}
+
+// SendPasswordReset : mails the password reset token to the user
+func (mn *mailNotifier) SendPasswordReset(u *models.User, token string, expiresAt time.Time) error {
+	if u.Email == "" || !u.EmailVerified {
+		if mn.fallback == nil {
+			log.Printf("password reset for %s not delivered, the user has no verified email\n", u.Username)
+			return nil
+		}
+		return mn.fallback.SendPasswordReset(u, token, expiresAt)
+	}
+
+	return mn.mailer.Send(mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your SmileCook password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the following token to reset your password: %s\n\nThe token expires at %s.\n",
+			u.Username, token, expiresAt.UTC().Format(time.RFC1123)),
+	})
+}
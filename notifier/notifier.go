@@ -0,0 +1,12 @@
+package notifier
+
+import (
+	"time"
+
+	"github.com/skamranahmed/smilecook/models"
+)
+
+// Notifier : delivers account related notifications to a user
+type Notifier interface {
+	SendPasswordReset(user *models.User, token string, expiresAt time.Time) error
+}
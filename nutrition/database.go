@@ -0,0 +1,200 @@
+// Package nutrition estimates the nutrients of a recipe from its ingredients with an offline food composition table
+package nutrition
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/skamranahmed/smilecook/search"
+)
+
+//go:embed data/foods.csv
+var bundledFoods string
+
+// Food : a food of the composition table, the nutrients are per 100 g
+type Food struct {
+	Name         string
+	Aliases      []string
+	Per100g      Nutrients
+	GramsPerMl   float64 // zero if the food is not measured by volume
+	GramsPerUnit float64 // zero if the food does not come in pieces
+}
+
+// Database : the food composition table and the terms each food is matched by
+type Database struct {
+	foods    []*Food
+	matchers []foodMatcher
+}
+
+// foodMatcher : the stemmed terms of a name or an alias of a food, a line matches if it contains all of them
+type foodMatcher struct {
+	food  *Food
+	terms []string
+}
+
+// csvColumns : the columns of the table, in order
+var csvColumns = []string{
+	"name", "aliases", "kcal", "protein_g", "fat_g", "carbohydrates_g", "fiber_g", "sugar_g",
+	"sodium_mg", "calcium_mg", "iron_mg", "potassium_mg", "vitamin_c_mg", "grams_per_ml", "grams_per_unit",
+}
+
+// Bundled : returns the trimmed USDA table that ships with the binary
+func Bundled() *Database {
+	db, err := read(strings.NewReader(bundledFoods))
+	if err != nil {
+		panic(fmt.Sprintf("invalid bundled nutrition dataset: %v", err))
+	}
+	return db
+}
+
+// Load : reads a table with the same columns as the bundled one, lines starting with # are ignored
+func Load(path string) (*Database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return read(f)
+}
+
+func read(r io.Reader) (*Database, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = len(csvColumns)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i, column := range csvColumns {
+		if strings.TrimSpace(header[i]) != column {
+			return nil, fmt.Errorf("column %d must be %s, got: %s", i+1, column, header[i])
+		}
+	}
+
+	db := &Database{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]float64, len(record))
+		for i := 2; i < len(record); i++ {
+			field := strings.TrimSpace(record[i])
+			if field == "" {
+				continue
+			}
+			values[i], err = strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid %s: %s", record[0], csvColumns[i], field)
+			}
+		}
+
+		food := &Food{
+			Name: strings.TrimSpace(record[0]),
+			Per100g: Nutrients{
+				Calories:      values[2],
+				Protein:       values[3],
+				Fat:           values[4],
+				Carbohydrates: values[5],
+				Fiber:         values[6],
+				Sugar:         values[7],
+				Sodium:        values[8],
+				Calcium:       values[9],
+				Iron:          values[10],
+				Potassium:     values[11],
+				VitaminC:      values[12],
+			},
+			GramsPerMl:   values[13],
+			GramsPerUnit: values[14],
+		}
+		if aliases := strings.TrimSpace(record[1]); aliases != "" {
+			food.Aliases = strings.Split(aliases, "|")
+		}
+
+		db.foods = append(db.foods, food)
+		for _, name := range append([]string{food.Name}, food.Aliases...) {
+			if terms := search.Terms(name); len(terms) > 0 {
+				db.matchers = append(db.matchers, foodMatcher{food: food, terms: terms})
+			}
+		}
+	}
+
+	return db, nil
+}
+
+// Len : the number of foods of the table
+func (db *Database) Len() int {
+	return len(db.foods)
+}
+
+// descriptors : the words of an ingredient that say how it is prepared rather than what it is,
+// they do not count against the score of a match
+var descriptors = map[string]bool{}
+
+func init() {
+	for _, word := range []string{
+		"fresh", "large", "medium", "small", "chopped", "minced", "sliced", "grated", "shredded", "peeled",
+		"boneless", "skinless", "ground", "dried", "frozen", "ripe", "raw", "cooked", "finely", "roughly",
+		"thinly", "softened", "melted", "cold", "warm", "room", "temperature", "organic", "whole", "unsalted", "salted",
+	} {
+		descriptors[search.Stem(word)] = true
+	}
+}
+
+// Match : finds the food of an ingredient item, the most specific name wins ("egg white" over "egg"),
+// the score is the share of the words of the item that the name covers, 0 if no food matches
+func (db *Database) Match(item string) (*Food, float64) {
+	itemTerms := search.Terms(item)
+	present := make(map[string]bool, len(itemTerms))
+	significant := 0
+	for _, term := range itemTerms {
+		present[term] = true
+		if !descriptors[term] {
+			significant++
+		}
+	}
+
+	var best *foodMatcher
+	for i := range db.matchers {
+		matcher := &db.matchers[i]
+		if best != nil && len(matcher.terms) <= len(best.terms) {
+			continue
+		}
+		if containsAll(present, matcher.terms) {
+			best = matcher
+		}
+	}
+
+	if best == nil {
+		return nil, 0
+	}
+
+	if significant == 0 {
+		return best.food, 1
+	}
+	score := float64(len(best.terms)) / float64(significant)
+	if score > 1 {
+		score = 1
+	}
+	return best.food, score
+}
+
+func containsAll(present map[string]bool, terms []string) bool {
+	for _, term := range terms {
+		if !present[term] {
+			return false
+		}
+	}
+	return true
+}
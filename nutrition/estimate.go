@@ -0,0 +1,203 @@
+package nutrition
+
+import (
+	"math"
+
+	"github.com/skamranahmed/smilecook/ingredient"
+)
+
+// Nutrients : calories in kcal, macros in grams and micronutrients in milligrams
+type Nutrients struct {
+	Calories      float64 `json:"calories_kcal"`
+	Protein       float64 `json:"protein_g"`
+	Fat           float64 `json:"fat_g"`
+	Carbohydrates float64 `json:"carbohydrates_g"`
+	Fiber         float64 `json:"fiber_g"`
+	Sugar         float64 `json:"sugar_g"`
+	Sodium        float64 `json:"sodium_mg"`
+	Calcium       float64 `json:"calcium_mg"`
+	Iron          float64 `json:"iron_mg"`
+	Potassium     float64 `json:"potassium_mg"`
+	VitaminC      float64 `json:"vitamin_c_mg"`
+}
+
+// add : adds the nutrients of the grams of a food with the nutrients per 100 g
+func (n *Nutrients) add(per100g Nutrients, grams float64) {
+	factor := grams / 100
+	n.Calories += per100g.Calories * factor
+	n.Protein += per100g.Protein * factor
+	n.Fat += per100g.Fat * factor
+	n.Carbohydrates += per100g.Carbohydrates * factor
+	n.Fiber += per100g.Fiber * factor
+	n.Sugar += per100g.Sugar * factor
+	n.Sodium += per100g.Sodium * factor
+	n.Calcium += per100g.Calcium * factor
+	n.Iron += per100g.Iron * factor
+	n.Potassium += per100g.Potassium * factor
+	n.VitaminC += per100g.VitaminC * factor
+}
+
+// divide : the nutrients split into equal parts
+func (n Nutrients) divide(parts float64) Nutrients {
+	result := Nutrients{}
+	result.add(n, 100/parts)
+	return result
+}
+
+// rounded : calories to whole numbers, the rest to one decimal
+func (n Nutrients) rounded() Nutrients {
+	round := func(value float64) float64 { return math.Round(value*10) / 10 }
+	return Nutrients{
+		Calories:      math.Round(n.Calories),
+		Protein:       round(n.Protein),
+		Fat:           round(n.Fat),
+		Carbohydrates: round(n.Carbohydrates),
+		Fiber:         round(n.Fiber),
+		Sugar:         round(n.Sugar),
+		Sodium:        round(n.Sodium),
+		Calcium:       round(n.Calcium),
+		Iron:          round(n.Iron),
+		Potassium:     round(n.Potassium),
+		VitaminC:      round(n.VitaminC),
+	}
+}
+
+// LineEstimate : how an ingredient line has been accounted for, Confidence goes from 0 (ignored) to 1 (weighed and matched exactly)
+type LineEstimate struct {
+	Original   string  `json:"original"`
+	Food       string  `json:"food,omitempty"`
+	Grams      float64 `json:"grams"`
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason,omitempty"` // why the confidence is low
+}
+
+// Estimate : the nutrients of a recipe, Confidence is the average confidence of its lines
+type Estimate struct {
+	Servings        int            `json:"servings"`
+	ServingsAssumed bool           `json:"servings_assumed,omitempty"` // the recipe does not say, the whole recipe is one serving
+	PerServing      Nutrients      `json:"per_serving"`
+	Total           Nutrients      `json:"total"`
+	Confidence      float64        `json:"confidence"`
+	Lines           []LineEstimate `json:"lines"`
+}
+
+// the confidence in the weight of an ingredient, by how it has been measured
+const (
+	weighedConfidence         float64 = 1
+	densityConfidence         float64 = 0.9
+	pieceConfidence           float64 = 0.8
+	standardUnitConfidence    float64 = 0.7
+	assumedWaterConfidence    float64 = 0.6
+	guessedPieceConfidence    float64 = 0.5
+	missingQuantityConfidence float64 = 0.3
+)
+
+// standardUnitGrams : the usual weight of the units that do not depend on the food
+var standardUnitGrams = map[string]float64{
+	"pinch":   0.4,
+	"dash":    0.6,
+	"can":     400,
+	"stick":   113,
+	"package": 250,
+	"handful": 30,
+	"sprig":   1,
+	"bunch":   100,
+}
+
+// pieceUnitGrams : the weight of a piece when the food does not say, "1 slice" of something
+var pieceUnitGrams = map[string]float64{
+	"clove": 3,
+	"slice": 30,
+	"piece": 50,
+	"head":  500,
+}
+
+// Estimate : the nutrients of the ingredients for the servings, the lines that match no food or have no usable quantity
+// count with a lower confidence
+func (db *Database) Estimate(ingredients []ingredient.Ingredient, servings int) *Estimate {
+	estimate := &Estimate{Servings: servings, Lines: make([]LineEstimate, 0, len(ingredients))}
+	if servings < 1 {
+		estimate.Servings = 1
+		estimate.ServingsAssumed = true
+	}
+
+	var total Nutrients
+	var confidence float64
+	for _, line := range ingredients {
+		lineEstimate := LineEstimate{Original: line.Original}
+
+		food, score := db.Match(line.Item)
+		if food == nil {
+			lineEstimate.Reason = "no matching food"
+		} else {
+			lineEstimate.Food = food.Name
+			grams, unitConfidence, reason := toGrams(line, food)
+			lineEstimate.Grams = math.Round(grams*10) / 10
+			lineEstimate.Confidence = math.Round(score*unitConfidence*100) / 100
+			lineEstimate.Reason = reason
+			total.add(food.Per100g, grams)
+		}
+
+		confidence += lineEstimate.Confidence
+		estimate.Lines = append(estimate.Lines, lineEstimate)
+	}
+
+	estimate.Total = total.rounded()
+	estimate.PerServing = total.divide(float64(estimate.Servings)).rounded()
+	if len(ingredients) > 0 {
+		estimate.Confidence = math.Round(confidence/float64(len(ingredients))*100) / 100
+	}
+
+	return estimate
+}
+
+// toGrams : the weight of the ingredient, the confidence in that weight and why it is not certain
+func toGrams(line ingredient.Ingredient, food *Food) (float64, float64, string) {
+	if line.Quantity == nil {
+		return 0, missingQuantityConfidence, "no quantity"
+	}
+
+	// a range counts as its middle
+	amount := line.Quantity.Value
+	if line.Quantity.IsRange() {
+		amount = (line.Quantity.Value + line.Quantity.Max) / 2
+	}
+
+	if line.Unit == "" {
+		if food.GramsPerUnit > 0 {
+			return amount * food.GramsPerUnit, pieceConfidence, ""
+		}
+		return 0, 0, "unknown weight of a piece"
+	}
+
+	unit, ok := ingredient.LookupUnit(line.Unit)
+	if !ok {
+		return 0, 0, "unknown unit"
+	}
+
+	switch unit.Kind {
+	case ingredient.Mass:
+		grams, _ := ingredient.Convert(amount, unit.Name, "g", "")
+		return grams, weighedConfidence, ""
+	case ingredient.Volume:
+		ml, _ := ingredient.Convert(amount, unit.Name, "ml", "")
+		if food.GramsPerMl > 0 {
+			return ml * food.GramsPerMl, densityConfidence, ""
+		}
+		if grams, err := ingredient.Convert(amount, unit.Name, "g", line.Item); err == nil {
+			return grams, densityConfidence, ""
+		}
+		return ml, assumedWaterConfidence, "density assumed to be that of water"
+	}
+
+	if grams, ok := standardUnitGrams[unit.Name]; ok {
+		return amount * grams, standardUnitConfidence, "standard weight of a " + unit.Name
+	}
+	if food.GramsPerUnit > 0 {
+		return amount * food.GramsPerUnit, pieceConfidence, ""
+	}
+	if grams, ok := pieceUnitGrams[unit.Name]; ok {
+		return amount * grams, guessedPieceConfidence, "guessed weight of a " + unit.Name
+	}
+	return 0, 0, "unknown unit"
+}
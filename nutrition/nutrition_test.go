@@ -0,0 +1,175 @@
+package nutrition
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/skamranahmed/smilecook/ingredient"
+)
+
+// testFoods : round numbers so that the expected values can be worked out by hand
+const testFoods = `name,aliases,kcal,protein_g,fat_g,carbohydrates_g,fiber_g,sugar_g,sodium_mg,calcium_mg,iron_mg,potassium_mg,vitamin_c_mg,grams_per_ml,grams_per_unit
+egg,eggs,100,10,10,0,0,0,100,0,0,0,0,1,50
+egg white,egg whites,50,10,0,0,0,0,100,0,0,0,0,1,30
+flour,all-purpose flour,400,10,0,80,0,0,0,0,0,0,0,0.5,
+water,,0,0,0,0,0,0,0,0,0,0,0,1,
+salt,,0,0,0,0,0,0,40000,0,0,0,0,1.2,
+# a comment line
+lemon juice,,20,0,0,5,0,0,0,0,0,0,40,,
+`
+
+func testDatabase(t *testing.T) *Database {
+	t.Helper()
+
+	db, err := read(strings.NewReader(testFoods))
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+	return db
+}
+
+func TestBundled(t *testing.T) {
+	db := Bundled()
+	if db.Len() < 50 {
+		t.Fatalf("Bundled() has %d foods, want the whole table", db.Len())
+	}
+
+	if food, _ := db.Match("egg whites"); food == nil || food.Name != "egg white" {
+		t.Fatalf("Bundled().Match(%q) = %v, want egg white", "egg whites", food)
+	}
+}
+
+func TestReadRejectsAnotherHeader(t *testing.T) {
+	_, err := read(strings.NewReader("name,kcal\negg,100\n"))
+	if err == nil {
+		t.Fatalf("read() with other columns succeeded, want an error")
+	}
+
+	_, err = read(strings.NewReader(strings.Replace(testFoods, "egg,eggs,100", "egg,eggs,lots", 1)))
+	if err == nil {
+		t.Fatalf("read() with an invalid number succeeded, want an error")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	db := testDatabase(t)
+
+	tests := []struct {
+		item      string
+		wantFood  string
+		wantScore float64
+	}{
+		{"egg", "egg", 1},
+		{"eggs", "egg", 1},
+		// the longest name wins over a name that is part of it
+		{"egg whites", "egg white", 1},
+		// descriptors do not lower the score
+		{"large fresh eggs", "egg", 1},
+		// other words do
+		{"duck eggs", "egg", 0.5},
+		{"all-purpose flour", "flour", 1},
+		{"chicken", "", 0},
+		{"", "", 0},
+	}
+
+	for _, tt := range tests {
+		food, score := db.Match(tt.item)
+		name := ""
+		if food != nil {
+			name = food.Name
+		}
+
+		if name != tt.wantFood || math.Abs(score-tt.wantScore) > 1e-9 {
+			t.Errorf("Match(%q) = %q, %v, want %q, %v", tt.item, name, score, tt.wantFood, tt.wantScore)
+		}
+	}
+}
+
+func TestToGrams(t *testing.T) {
+	db := testDatabase(t)
+
+	tests := []struct {
+		line           string
+		food           string
+		wantGrams      float64
+		wantConfidence float64
+		wantReason     string
+	}{
+		{"200 g flour", "flour", 200, weighedConfidence, ""},
+		{"1 kg flour", "flour", 1000, weighedConfidence, ""},
+		{"100 ml water", "water", 100, densityConfidence, ""},
+		{"200 ml flour", "flour", 100, densityConfidence, ""},
+		{"100 ml lemon juice", "lemon juice", 100, assumedWaterConfidence, "density assumed to be that of water"},
+		{"2 eggs", "egg", 100, pieceConfidence, ""},
+		{"2-4 eggs", "egg", 150, pieceConfidence, ""},
+		{"1 pinch salt", "salt", 0.4, standardUnitConfidence, "standard weight of a pinch"},
+		{"2 slices egg", "egg", 100, pieceConfidence, ""},
+		{"2 slices flour", "flour", 60, guessedPieceConfidence, "guessed weight of a slice"},
+		{"2 flour", "flour", 0, 0, "unknown weight of a piece"},
+		{"flour", "flour", 0, missingQuantityConfidence, "no quantity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			food, _ := db.Match(tt.food)
+			if food == nil {
+				t.Fatalf("no food %q", tt.food)
+			}
+
+			grams, confidence, reason := toGrams(ingredient.Parse(tt.line), food)
+			if math.Abs(grams-tt.wantGrams) > 0.01 || confidence != tt.wantConfidence || reason != tt.wantReason {
+				t.Fatalf("toGrams(%q) = %v, %v, %q, want %v, %v, %q", tt.line, grams, confidence, reason, tt.wantGrams, tt.wantConfidence, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	db := testDatabase(t)
+
+	lines := []ingredient.Ingredient{
+		ingredient.Parse("250 g flour"),
+		ingredient.Parse("2 eggs"),
+		ingredient.Parse("1 cup chopped walnuts"),
+	}
+
+	estimate := db.Estimate(lines, 2)
+
+	// 250 g of flour and 100 g of egg, the walnuts are unknown
+	wantTotal := Nutrients{Calories: 1100, Protein: 35, Fat: 10, Carbohydrates: 200, Sodium: 100}
+	if estimate.Total != wantTotal {
+		t.Fatalf("Total = %+v, want %+v", estimate.Total, wantTotal)
+	}
+
+	wantPerServing := Nutrients{Calories: 550, Protein: 17.5, Fat: 5, Carbohydrates: 100, Sodium: 50}
+	if estimate.PerServing != wantPerServing {
+		t.Fatalf("PerServing = %+v, want %+v", estimate.PerServing, wantPerServing)
+	}
+
+	if len(estimate.Lines) != 3 || estimate.Lines[2].Reason != "no matching food" || estimate.Lines[2].Confidence != 0 {
+		t.Fatalf("Lines = %+v, want the walnuts without a food", estimate.Lines)
+	}
+
+	// (1 + 0.8 + 0) / 3
+	if estimate.Confidence != 0.6 {
+		t.Fatalf("Confidence = %v, want 0.6", estimate.Confidence)
+	}
+
+	if estimate.Servings != 2 || estimate.ServingsAssumed {
+		t.Fatalf("Servings = %d, assumed %t, want 2 given", estimate.Servings, estimate.ServingsAssumed)
+	}
+}
+
+func TestEstimateWithoutServings(t *testing.T) {
+	db := testDatabase(t)
+
+	estimate := db.Estimate([]ingredient.Ingredient{ingredient.Parse("100 g flour")}, 0)
+	if estimate.Servings != 1 || !estimate.ServingsAssumed || estimate.PerServing != estimate.Total {
+		t.Fatalf("Estimate() = %+v, want the whole recipe as one assumed serving", estimate)
+	}
+
+	if empty := db.Estimate(nil, 4); empty.Confidence != 0 || empty.Total != (Nutrients{}) {
+		t.Fatalf("Estimate() without ingredients = %+v, want nothing", empty)
+	}
+}
@@ -0,0 +1,124 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// ErrInvalidIDToken : the ID token failed validation
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// IDTokenClaims : the claims of a validated ID token that are used to link or provision an account
+type IDTokenClaims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// VerifyIDToken : validates the signature of the ID token against the provider JWKS as well as its issuer, audience, expiry and nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (*IDTokenClaims, error) {
+	_, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		key, err := p.keys.key(ctx, keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		// the algorithm of the token must match the type of the key, `none` and HMAC are never accepted
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+			}
+		case *ecdsa.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+			}
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIDToken, err)
+	}
+
+	if !token.Valid {
+		return nil, ErrInvalidIDToken
+	}
+
+	// MapClaims.Valid checks exp, iat and nbf, the rest has to be checked by hand
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidIDToken)
+	}
+
+	if stringClaim(claims, "iss") != p.config.Issuer {
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrInvalidIDToken)
+	}
+
+	if !audienceContains(claims["aud"], p.config.ClientID) {
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidIDToken)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stringClaim(claims, "nonce")), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	subject := stringClaim(claims, "sub")
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+
+	return &IDTokenClaims{
+		Issuer:            p.config.Issuer,
+		Subject:           subject,
+		Email:             stringClaim(claims, "email"),
+		EmailVerified:     boolClaim(claims, "email_verified"),
+		Name:              stringClaim(claims, "name"),
+		PreferredUsername: stringClaim(claims, "preferred_username"),
+	}, nil
+}
+
+// audienceContains : the aud claim can either be a single string or an array of strings
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+// boolClaim : some providers send email_verified as a string
+func boolClaim(claims jwt.MapClaims, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// the keys are refetched at most once per interval when an unknown kid shows up, this handles provider key rotation
+const jwksRefreshInterval time.Duration = time.Minute
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+type keySet struct {
+	url     string
+	getJSON func(ctx context.Context, url string, v interface{}) error
+
+	mu          sync.Mutex
+	keys        map[string]interface{}
+	lastFetched time.Time
+}
+
+func newKeySet(url string, getJSON func(ctx context.Context, url string, v interface{}) error) *keySet {
+	return &keySet{
+		url:     url,
+		getJSON: getJSON,
+		keys:    map[string]interface{}{},
+	}
+}
+
+// key : returns the public key with the kid, refetching the JWKS if the kid is unknown
+func (ks *keySet) key(ctx context.Context, keyID string) (interface{}, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if key, ok := ks.keys[keyID]; ok {
+		return key, nil
+	}
+
+	if time.Since(ks.lastFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown key id: %q", keyID)
+	}
+
+	err := ks.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, ok := ks.keys[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id: %q", keyID)
+}
+
+func (ks *keySet) refresh(ctx context.Context) error {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	ks.lastFetched = time.Now()
+	err := ks.getJSON(ctx, ks.url, &document)
+	if err != nil {
+		return fmt.Errorf("unable to fetch the provider jwks, error: %s", err)
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			// skip keys we do not understand instead of failing the whole set
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+
+	ks.keys = keys
+	return nil
+}
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", jwk.Curve)
+		}
+
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, errors.New("unsupported key type: " + jwk.KeyType)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,267 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// mockProvider : an in-process OpenID Connect provider, the authorization step is simulated by calling authorize directly
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	keyID  string
+
+	mu       sync.Mutex
+	codes    map[string]mockAuthorization
+	audience interface{}
+}
+
+type mockAuthorization struct {
+	codeChallenge string
+	nonce         string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mp := &mockProvider{t: t, key: key, keyID: "key-1", codes: map[string]mockAuthorization{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 mp.server.URL,
+			"authorization_endpoint": mp.server.URL + "/authorize",
+			"token_endpoint":         mp.server.URL + "/token",
+			"jwks_uri":               mp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		mp.mu.Lock()
+		defer mp.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": mp.keyID,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(mp.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(mp.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", mp.token)
+	mp.server = httptest.NewServer(mux)
+	t.Cleanup(mp.server.Close)
+	return mp
+}
+
+// authorize : simulates the user signing in at the provider, returns the authorization code
+func (mp *mockProvider) authorize(authCodeURL string) (string, string) {
+	u, err := url.Parse(authCodeURL)
+	if err != nil {
+		mp.t.Fatal(err)
+	}
+
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		mp.t.Fatalf("expected S256 code challenge method, got: %q", query.Get("code_challenge_method"))
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	code := "code-" + query.Get("state")
+	mp.codes[code] = mockAuthorization{codeChallenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	return code, query.Get("state")
+}
+
+func (mp *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	mp.mu.Lock()
+	authorization, ok := mp.codes[r.Form.Get("code")]
+	delete(mp.codes, r.Form.Get("code"))
+	audience := mp.audience
+	mp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.codeChallenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	if audience == nil {
+		audience = r.Form.Get("client_id")
+	}
+
+	json.NewEncoder(w).Encode(TokenResponse{
+		AccessToken: "access-token",
+		TokenType:   "Bearer",
+		IDToken: mp.sign(jwt.MapClaims{
+			"iss":            mp.server.URL,
+			"sub":            "subject-123",
+			"aud":            audience,
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          authorization.nonce,
+			"email":          "alice@example.com",
+			"email_verified": true,
+		}),
+	})
+}
+
+func (mp *mockProvider) sign(claims jwt.MapClaims) string {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = mp.keyID
+	signed, err := token.SignedString(mp.key)
+	if err != nil {
+		mp.t.Fatal(err)
+	}
+	return signed
+}
+
+func (mp *mockProvider) provider() *Provider {
+	return NewProvider(Config{
+		Name:        "mock",
+		Issuer:      mp.server.URL,
+		ClientID:    "smilecook",
+		RedirectURL: "http://localhost/oauth/mock/callback",
+	}, mp.server.Client())
+}
+
+// startLogin : runs the flow up to the callback, returns the code, the verifier and the nonce
+func startLogin(t *testing.T, mp *mockProvider, p *Provider) (string, string, string) {
+	verifier, challenge, err := GeneratePKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authCodeURL, err := p.AuthCodeURL(context.Background(), "state-1", "nonce-1", challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, state := mp.authorize(authCodeURL)
+	if state != "state-1" {
+		t.Fatalf("expected the state to round trip, got: %q", state)
+	}
+	return code, verifier, "nonce-1"
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	mp := newMockProvider(t)
+	p := mp.provider()
+
+	code, verifier, nonce := startLogin(t, mp, p)
+	tokenResponse, err := p.Exchange(context.Background(), code, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := p.VerifyIDToken(context.Background(), tokenResponse.IDToken, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != "subject-123" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	mp := newMockProvider(t)
+	p := mp.provider()
+
+	code, _, _ := startLogin(t, mp, p)
+	_, err := p.Exchange(context.Background(), code, "not-the-verifier")
+	if err == nil {
+		t.Fatal("expected the exchange to fail")
+	}
+}
+
+func TestVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	mp := newMockProvider(t)
+	p := mp.provider()
+
+	valid := jwt.MapClaims{
+		"iss":   mp.server.URL,
+		"sub":   "subject-123",
+		"aud":   []interface{}{"other-client", "smilecook"},
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": "nonce-1",
+	}
+
+	_, err := p.VerifyIDToken(context.Background(), mp.sign(valid), "nonce-1")
+	if err != nil {
+		t.Fatalf("expected an audience array containing the client id to be accepted, got: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims jwt.MapClaims)
+	}{
+		{"wrong nonce", func(claims jwt.MapClaims) { claims["nonce"] = "other-nonce" }},
+		{"wrong audience", func(claims jwt.MapClaims) { claims["aud"] = "other-client" }},
+		{"wrong issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"missing subject", func(claims jwt.MapClaims) { delete(claims, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := jwt.MapClaims{}
+			for k, v := range valid {
+				claims[k] = v
+			}
+			tt.modify(claims)
+
+			_, err := p.VerifyIDToken(context.Background(), mp.sign(claims), "nonce-1")
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("expected ErrInvalidIDToken, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsHMAC(t *testing.T) {
+	mp := newMockProvider(t)
+	p := mp.provider()
+
+	// an attacker signing with HS256 and the public key as the secret must be rejected
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":   mp.server.URL,
+		"sub":   "subject-123",
+		"aud":   "smilecook",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": "nonce-1",
+	})
+	token.Header["kid"] = mp.keyID
+	signed, err := token.SignedString(mp.key.N.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.VerifyIDToken(context.Background(), signed, "nonce-1")
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected ErrInvalidIDToken, got: %v", err)
+	}
+}
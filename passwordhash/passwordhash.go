@@ -0,0 +1,221 @@
+// Package passwordhash hashes passwords with bcrypt or Argon2id and stores the parameters next to the hash,
+// so that hashes created with older parameters can be recognised and upgraded
+package passwordhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   string = "bcrypt"
+	AlgorithmArgon2id string = "argon2id"
+)
+
+var (
+	// ErrMismatch : the password does not match the hash
+	ErrMismatch = errors.New("password does not match the hash")
+
+	// ErrUnknownFormat : the hash is neither a bcrypt hash nor an Argon2id hash in the PHC string format
+	ErrUnknownFormat = errors.New("unknown password hash format")
+)
+
+// Params : the algorithm new hashes are created with and its cost parameters
+type Params struct {
+	Algorithm string
+
+	// BcryptCost : log2 of the number of bcrypt rounds
+	BcryptCost int
+
+	// Argon2Time : number of passes over the memory
+	Argon2Time uint32
+	// Argon2MemoryKiB : memory used per hash, in KiB
+	Argon2MemoryKiB uint32
+	// Argon2Threads : degree of parallelism
+	Argon2Threads uint8
+	// Argon2KeyLength : length of the derived key, in bytes
+	Argon2KeyLength uint32
+	// Argon2SaltLength : length of the random salt, in bytes
+	Argon2SaltLength uint32
+}
+
+// DefaultParams : Argon2id with the parameters recommended by OWASP, bcrypt keeps its default cost
+var DefaultParams = Params{
+	Algorithm:        AlgorithmArgon2id,
+	BcryptCost:       bcrypt.DefaultCost,
+	Argon2Time:       2,
+	Argon2MemoryKiB:  19 * 1024,
+	Argon2Threads:    1,
+	Argon2KeyLength:  32,
+	Argon2SaltLength: 16,
+}
+
+// Validate : checks that the parameters can be used to create hashes
+func (p Params) Validate() error {
+	switch p.Algorithm {
+	case AlgorithmBcrypt:
+		if p.BcryptCost < bcrypt.MinCost || p.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case AlgorithmArgon2id:
+		if p.Argon2Time < 1 || p.Argon2MemoryKiB < 8*uint32(p.Argon2Threads) || p.Argon2Threads < 1 {
+			return errors.New("argon2id needs at least 1 pass, 1 thread and 8 KiB of memory per thread")
+		}
+		if p.Argon2KeyLength < 16 || p.Argon2SaltLength < 8 {
+			return errors.New("argon2id needs a key of at least 16 bytes and a salt of at least 8 bytes")
+		}
+	default:
+		return fmt.Errorf("unsupported password hash algorithm: %q", p.Algorithm)
+	}
+	return nil
+}
+
+// Hash : hashes the password, bcrypt hashes use the modular crypt format ($2a$...),
+// Argon2id hashes use the PHC string format ($argon2id$v=19$m=...,t=...,p=...$salt$key)
+func Hash(plainTextPassword string, p Params) (string, error) {
+	switch p.Algorithm {
+	case AlgorithmBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(plainTextPassword), p.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+
+	case AlgorithmArgon2id:
+		salt := make([]byte, p.Argon2SaltLength)
+		_, err := rand.Read(salt)
+		if err != nil {
+			return "", err
+		}
+
+		key := argon2.IDKey([]byte(plainTextPassword), salt, p.Argon2Time, p.Argon2MemoryKiB, p.Argon2Threads, p.Argon2KeyLength)
+		return argon2Hash{
+			time:      p.Argon2Time,
+			memoryKiB: p.Argon2MemoryKiB,
+			threads:   p.Argon2Threads,
+			salt:      salt,
+			key:       key,
+		}.String(), nil
+	}
+
+	return "", fmt.Errorf("unsupported password hash algorithm: %q", p.Algorithm)
+}
+
+// Verify : checks the password against a hash in any of the supported formats, returns ErrMismatch if it does not match
+func Verify(plainTextPassword string, encodedHash string) error {
+	switch algorithmOf(encodedHash) {
+	case AlgorithmBcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(plainTextPassword))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return ErrMismatch
+		}
+		return err
+
+	case AlgorithmArgon2id:
+		h, err := parseArgon2Hash(encodedHash)
+		if err != nil {
+			return err
+		}
+
+		key := argon2.IDKey([]byte(plainTextPassword), h.salt, h.time, h.memoryKiB, h.threads, uint32(len(h.key)))
+		if subtle.ConstantTimeCompare(key, h.key) != 1 {
+			return ErrMismatch
+		}
+		return nil
+	}
+
+	return ErrUnknownFormat
+}
+
+// NeedsRehash : checks whether the hash was created with another algorithm or other parameters than the current ones
+func NeedsRehash(encodedHash string, p Params) bool {
+	if algorithmOf(encodedHash) != p.Algorithm {
+		return true
+	}
+
+	switch p.Algorithm {
+	case AlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(encodedHash))
+		return err != nil || cost != p.BcryptCost
+
+	case AlgorithmArgon2id:
+		h, err := parseArgon2Hash(encodedHash)
+		return err != nil ||
+			h.time != p.Argon2Time ||
+			h.memoryKiB != p.Argon2MemoryKiB ||
+			h.threads != p.Argon2Threads ||
+			uint32(len(h.key)) != p.Argon2KeyLength ||
+			uint32(len(h.salt)) != p.Argon2SaltLength
+	}
+
+	return false
+}
+
+// algorithmOf : detects the algorithm from the prefix of the hash
+func algorithmOf(encodedHash string) string {
+	switch {
+	case strings.HasPrefix(encodedHash, "$2a$"), strings.HasPrefix(encodedHash, "$2b$"), strings.HasPrefix(encodedHash, "$2y$"):
+		return AlgorithmBcrypt
+	case strings.HasPrefix(encodedHash, "$argon2id$"):
+		return AlgorithmArgon2id
+	}
+	return ""
+}
+
+// argon2Hash : the parts of an Argon2id hash in the PHC string format
+type argon2Hash struct {
+	time      uint32
+	memoryKiB uint32
+	threads   uint8
+	salt      []byte
+	key       []byte
+}
+
+// String : formats the hash as $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>, salt and key are unpadded base64
+func (h argon2Hash) String() string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.memoryKiB, h.time, h.threads,
+		base64.RawStdEncoding.EncodeToString(h.salt),
+		base64.RawStdEncoding.EncodeToString(h.key),
+	)
+}
+
+func parseArgon2Hash(encodedHash string) (argon2Hash, error) {
+	var h argon2Hash
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return h, ErrUnknownFormat
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return h, fmt.Errorf("unsupported argon2 version: %s", parts[2])
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memoryKiB, &h.time, &h.threads)
+	if err != nil {
+		return h, ErrUnknownFormat
+	}
+
+	h.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return h, ErrUnknownFormat
+	}
+
+	h.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(h.key) == 0 {
+		return h, ErrUnknownFormat
+	}
+
+	return h, nil
+}
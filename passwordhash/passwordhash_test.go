@@ -0,0 +1,184 @@
+package passwordhash
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams : cheap parameters so that the tests stay fast
+var testParams = Params{
+	Algorithm:        AlgorithmArgon2id,
+	BcryptCost:       bcrypt.MinCost,
+	Argon2Time:       1,
+	Argon2MemoryKiB:  64,
+	Argon2Threads:    1,
+	Argon2KeyLength:  32,
+	Argon2SaltLength: 16,
+}
+
+func TestHashAndVerify(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			p := testParams
+			p.Algorithm = algorithm
+
+			hash, err := Hash("correct horse battery staple", p)
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+
+			if got := algorithmOf(hash); got != algorithm {
+				t.Fatalf("algorithmOf(%q) = %q, want %q", hash, got, algorithm)
+			}
+
+			if err := Verify("correct horse battery staple", hash); err != nil {
+				t.Fatalf("Verify() with the right password error = %v", err)
+			}
+
+			if err := Verify("correct horse battery stapler", hash); err != ErrMismatch {
+				t.Fatalf("Verify() with a wrong password error = %v, want ErrMismatch", err)
+			}
+		})
+	}
+}
+
+func TestVerifyKnownHashes(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+	}{
+		// $2a$ and $2b$ only differ in the prefix for passwords shorter than 255 bytes
+		{"bcrypt 2a", "$2a$04$.UnFTiVzxs062iv0waRcpOtKukJf7iimMQKWVaTrW4rqYNJcFvuci"},
+		{"bcrypt 2b", "$2b$04$.UnFTiVzxs062iv0waRcpOtKukJf7iimMQKWVaTrW4rqYNJcFvuci"},
+		{"argon2id", "$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$55PWTvddWPUD1GMbKxSff4ASfF85k9ibHJt4HlHQtBM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify("password", tt.hash); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if err := Verify("Password", tt.hash); err != ErrMismatch {
+				t.Fatalf("Verify() with a wrong password error = %v, want ErrMismatch", err)
+			}
+		})
+	}
+}
+
+func TestParseArgon2Hash(t *testing.T) {
+	h, err := parseArgon2Hash("$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHRzb21lc2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U")
+	if err != nil {
+		t.Fatalf("parseArgon2Hash() error = %v", err)
+	}
+
+	if h.memoryKiB != 65536 || h.time != 3 || h.threads != 4 {
+		t.Fatalf("parseArgon2Hash() params = m=%d,t=%d,p=%d, want m=65536,t=3,p=4", h.memoryKiB, h.time, h.threads)
+	}
+
+	if string(h.salt) != "somesaltsomesalt" {
+		t.Fatalf("parseArgon2Hash() salt = %q, want %q", h.salt, "somesaltsomesalt")
+	}
+
+	if len(h.key) != 32 {
+		t.Fatalf("parseArgon2Hash() key length = %d, want 32", len(h.key))
+	}
+
+	if got := h.String(); got != "$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHRzb21lc2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U" {
+		t.Fatalf("String() = %q, the hash does not round trip", got)
+	}
+}
+
+func TestParseArgon2HashRejectsMalformedHashes(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"empty", ""},
+		{"other algorithm", "$argon2i$v=19$m=65536,t=3,p=4$c29tZXNhbHQ$a2V5"},
+		{"missing key", "$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHQ"},
+		{"empty key", "$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHQ$"},
+		{"unsupported version", "$argon2id$v=16$m=65536,t=3,p=4$c29tZXNhbHQ$a2V5"},
+		{"missing parameter", "$argon2id$v=19$m=65536,t=3$c29tZXNhbHQ$a2V5"},
+		{"padded salt", "$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHQ=$a2V5"},
+		{"invalid key encoding", "$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHQ$!!!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseArgon2Hash(tt.hash)
+			if err == nil {
+				t.Fatalf("parseArgon2Hash(%q) succeeded, want an error", tt.hash)
+			}
+		})
+	}
+}
+
+func TestVerifyUnknownFormat(t *testing.T) {
+	for _, hash := range []string{"", "plaintext", "$1$abc$def", "$scrypt$ln=15,r=8,p=1$c2FsdA$a2V5"} {
+		if err := Verify("password", hash); err != ErrUnknownFormat {
+			t.Errorf("Verify() with hash %q error = %v, want ErrUnknownFormat", hash, err)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon2Hash, err := Hash("password", testParams)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	bcryptParams := testParams
+	bcryptParams.Algorithm = AlgorithmBcrypt
+	bcryptHash, err := Hash("password", bcryptParams)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	moreMemory := testParams
+	moreMemory.Argon2MemoryKiB *= 2
+
+	higherCost := bcryptParams
+	higherCost.BcryptCost++
+
+	tests := []struct {
+		name string
+		hash string
+		p    Params
+		want bool
+	}{
+		{"same argon2id parameters", argon2Hash, testParams, false},
+		{"more argon2id memory", argon2Hash, moreMemory, true},
+		{"same bcrypt cost", bcryptHash, bcryptParams, false},
+		{"higher bcrypt cost", bcryptHash, higherCost, true},
+		{"bcrypt to argon2id", bcryptHash, testParams, true},
+		{"argon2id to bcrypt", argon2Hash, bcryptParams, true},
+		{"unknown format", "plaintext", testParams, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsRehash(tt.hash, tt.p); got != tt.want {
+				t.Fatalf("NeedsRehash() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashUsesARandomSalt(t *testing.T) {
+	first, err := Hash("password", testParams)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	second, err := Hash("password", testParams)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	if first == second || !strings.HasPrefix(first, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("Hash() = %q and %q, want two different hashes with the parameters in the prefix", first, second)
+	}
+}
@@ -0,0 +1,44 @@
+package rbac
+
+import (
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Subject : the authenticated caller a policy is evaluated for
+type Subject struct {
+	UserID   primitive.ObjectID
+	Username string
+	Role     Role
+}
+
+// CanReadRecipe : public recipes can be read by anyone, private ones only by their author or with recipe:read:any
+func CanReadRecipe(s Subject, recipe *models.Recipe) bool {
+	if !recipe.IsPrivate {
+		return true
+	}
+	return authorize(s, recipe, PermRecipeReadOwn, PermRecipeReadAny)
+}
+
+// CanUpdateRecipe : checks whether the subject may update the recipe
+func CanUpdateRecipe(s Subject, recipe *models.Recipe) bool {
+	return authorize(s, recipe, PermRecipeUpdateOwn, PermRecipeUpdateAny)
+}
+
+// CanDeleteRecipe : checks whether the subject may delete the recipe
+func CanDeleteRecipe(s Subject, recipe *models.Recipe) bool {
+	return authorize(s, recipe, PermRecipeDeleteOwn, PermRecipeDeleteAny)
+}
+
+// authorize : the `any` permission applies to every recipe, the `own` permission only to the recipes of the subject
+func authorize(s Subject, recipe *models.Recipe, own Permission, any Permission) bool {
+	if s.Role.Can(any) {
+		return true
+	}
+	return s.Role.Can(own) && isAuthor(s, recipe)
+}
+
+// isAuthor : ownership is decided by the stable user ID, usernames can change
+func isAuthor(s Subject, recipe *models.Recipe) bool {
+	return !s.UserID.IsZero() && recipe.AuthorID == s.UserID
+}
@@ -0,0 +1,136 @@
+// Package rbac holds the roles, the permissions they grant and the policies built on top of them
+package rbac
+
+import "github.com/skamranahmed/smilecook/models"
+
+// Role : name of a role
+type Role string
+
+// Permission : name of a permission, formatted as `resource:action[:scope]`
+type Permission string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+const (
+	PermRecipeCreate     Permission = "recipe:create"
+	PermRecipeReadOwn    Permission = "recipe:read:own"
+	PermRecipeReadAny    Permission = "recipe:read:any"
+	PermRecipeUpdateOwn  Permission = "recipe:update:own"
+	PermRecipeUpdateAny  Permission = "recipe:update:any"
+	PermRecipeDeleteOwn  Permission = "recipe:delete:own"
+	PermRecipeDeleteAny  Permission = "recipe:delete:any"
+	PermUserRead         Permission = "user:read"
+	PermUserBan          Permission = "user:ban"
+	PermUserUnlock       Permission = "user:unlock"
+	PermUserManageRoles  Permission = "user:manage_roles"
+	PermUserRevokeTokens Permission = "user:revoke_tokens"
+	PermUserRestore      Permission = "user:restore"
+)
+
+// Scope : restricts what an api key can be used for, on top of the role of its owner
+type Scope string
+
+const (
+	ScopeRecipesRead  Scope = "recipes:read"
+	ScopeRecipesWrite Scope = "recipes:write"
+)
+
+// Scopes : every scope an api key can be granted
+var Scopes = []Scope{ScopeRecipesRead, ScopeRecipesWrite}
+
+// IsValid : checks whether the scope is one of the known scopes
+func (s Scope) IsValid() bool {
+	for _, scope := range Scopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+// rolePermissions : every role inherits the permissions of the roles below it
+var rolePermissions = map[Role][]Permission{
+	RoleUser: {
+		PermRecipeCreate,
+		PermRecipeReadOwn,
+		PermRecipeUpdateOwn,
+		PermRecipeDeleteOwn,
+	},
+	RoleModerator: {
+		PermRecipeReadAny,
+		PermRecipeUpdateAny,
+		PermRecipeDeleteAny,
+		PermUserRead,
+		PermUserBan,
+		PermUserUnlock,
+	},
+	RoleAdmin: {
+		PermUserManageRoles,
+		PermUserRevokeTokens,
+		PermUserRestore,
+	},
+}
+
+// roleHierarchy : lowest to highest
+var roleHierarchy = []Role{RoleUser, RoleModerator, RoleAdmin}
+
+// IsValid : checks whether the role is one of the known roles
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can : checks whether the role grants the permission, an unknown role grants nothing
+func (r Role) Can(p Permission) bool {
+	if !r.IsValid() {
+		return false
+	}
+
+	for _, role := range roleHierarchy {
+		for _, permission := range rolePermissions[role] {
+			if permission == p {
+				return true
+			}
+		}
+
+		if role == r {
+			break
+		}
+	}
+	return false
+}
+
+// Outranks : checks whether the role is strictly higher than the other role
+func (r Role) Outranks(other Role) bool {
+	return r.rank() > other.rank()
+}
+
+func (r Role) rank() int {
+	for i, role := range roleHierarchy {
+		if role == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// Resolve : falls back on the legacy is_admin flag for users and tokens that predate roles
+func Resolve(role string, isAdmin bool) Role {
+	if Role(role).IsValid() {
+		return Role(role)
+	}
+
+	if isAdmin {
+		return RoleAdmin
+	}
+	return RoleUser
+}
+
+// RoleOf : returns the effective role of the user
+func RoleOf(u *models.User) Role {
+	return Resolve(u.Role, u.IsAdmin)
+}
@@ -0,0 +1,46 @@
+package rbac
+
+import "testing"
+
+// allPermissions : every permission granted by any role
+func allPermissions() []Permission {
+	permissions := make([]Permission, 0)
+	for _, role := range roleHierarchy {
+		permissions = append(permissions, rolePermissions[role]...)
+	}
+	return permissions
+}
+
+func TestUnknownRoleHasNoPermissions(t *testing.T) {
+	for _, role := range []Role{"", "superuser", "Admin", "admin ", "owner"} {
+		for _, permission := range allPermissions() {
+			if role.Can(permission) {
+				t.Fatalf("Role(%q).Can(%s) = true, want false", role, permission)
+			}
+		}
+	}
+}
+
+func TestRoleCan(t *testing.T) {
+	tests := []struct {
+		role       Role
+		permission Permission
+		want       bool
+	}{
+		{RoleUser, PermRecipeCreate, true},
+		{RoleUser, PermRecipeDeleteAny, false},
+		{RoleUser, PermUserManageRoles, false},
+		{RoleModerator, PermRecipeCreate, true},
+		{RoleModerator, PermUserBan, true},
+		{RoleModerator, PermUserManageRoles, false},
+		{RoleAdmin, PermRecipeCreate, true},
+		{RoleAdmin, PermUserBan, true},
+		{RoleAdmin, PermUserManageRoles, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.Can(tt.permission); got != tt.want {
+			t.Errorf("Role(%q).Can(%s) = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
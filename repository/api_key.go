@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const apiKeyCollectionName string = "api_keys"
+
+// NewAPIKeyRepository : returns an apiKeyRepo struct that implements the APIKeyRepository interface
+func NewAPIKeyRepository(ctx context.Context, apiKeyCollection *mongo.Collection) APIKeyRepository {
+	return &apiKeyRepo{
+		ctx:        ctx,
+		collection: apiKeyCollection,
+	}
+}
+
+type apiKeyRepo struct {
+	ctx        context.Context
+	collection *mongo.Collection
+}
+
+// Create : inserts a new api key record in the `api_keys` collection
+func (akr *apiKeyRepo) Create(k *models.APIKey) error {
+	if !akr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := akr.collection.InsertOne(akr.ctx, k)
+	return err
+}
+
+// FindOneByHash : finds an api key record with the provided key hash
+func (akr *apiKeyRepo) FindOneByHash(keyHash string) (*models.APIKey, error) {
+	if !akr.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	cur := akr.collection.FindOne(akr.ctx, bson.M{"key_hash": keyHash})
+	if cur.Err() != nil {
+		return nil, cur.Err()
+	}
+
+	var apiKey models.APIKey
+	err := cur.Decode(&apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+// FetchAllByUserID : fetches every api key record of the user, newest first
+func (akr *apiKeyRepo) FetchAllByUserID(userID primitive.ObjectID) ([]*models.APIKey, error) {
+	if !akr.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	cur, err := akr.collection.Find(akr.ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(akr.ctx)
+
+	apiKeys := make([]*models.APIKey, 0)
+	for cur.Next(akr.ctx) {
+		var apiKey models.APIKey
+		err := cur.Decode(&apiKey)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, &apiKey)
+	}
+
+	return apiKeys, nil
+}
+
+// Revoke : revokes the api key with the provided ID, only if it belongs to the user
+func (akr *apiKeyRepo) Revoke(documentObjectID primitive.ObjectID, userID primitive.ObjectID) (bool, error) {
+	if !akr.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	result, err := akr.collection.UpdateOne(akr.ctx,
+		bson.M{"_id": documentObjectID, "user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// UpdateLastUsed : sets the last used timestamp of the api key
+func (akr *apiKeyRepo) UpdateLastUsed(documentObjectID primitive.ObjectID, lastUsedAt time.Time) error {
+	if !akr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := akr.collection.UpdateOne(akr.ctx,
+		bson.M{"_id": documentObjectID},
+		bson.M{"$set": bson.M{"last_used_at": lastUsedAt}},
+	)
+	return err
+}
+
+// UpdateUsername : updates the username on every api key of the user
+func (akr *apiKeyRepo) UpdateUsername(userID primitive.ObjectID, newUsername string) error {
+	if !akr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := akr.collection.UpdateMany(akr.ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$set": bson.M{"username": newUsername}},
+	)
+	return err
+}
+
+// RevokeAllForUser : revokes every api key of the user
+func (akr *apiKeyRepo) RevokeAllForUser(userID primitive.ObjectID) error {
+	if !akr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := akr.collection.UpdateMany(akr.ctx,
+		bson.M{"user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// DeleteAllForUser : deletes every api key of the user
+func (akr *apiKeyRepo) DeleteAllForUser(userID primitive.ObjectID) error {
+	if !akr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := akr.collection.DeleteMany(akr.ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// isCollectionNameCorrect : verifies the collection name for the api key queries
+func (akr *apiKeyRepo) isCollectionNameCorrect() bool {
+	return akr.collection.Name() == apiKeyCollectionName
+}
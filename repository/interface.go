@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/skamranahmed/smilecook/models"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -10,13 +12,78 @@ type UserRepository interface {
 	Create(user *models.User) error
 	FindOne(username string) (*models.User, error)
 	DoesUsernameAlreadyExist(username string) (bool, error)
+	UpdatePassword(username string, hashedPassword string) (bool, error)
+	ReplacePasswordHash(username string, oldHashedPassword string, newHashedPassword string) (bool, error)
+	SetPendingRehash(username string, rehash models.PasswordRehash) (bool, error)
+	DoesEmailAlreadyExist(email string) (bool, error)
+	UpdateEmail(username string, email string) (bool, error)
+	MarkEmailVerified(username string, email string) (bool, error)
+	SetPendingTOTPSecret(username string, secret string) (bool, error)
+	EnableTOTP(username string, secret string, hashedRecoveryCodes []string) (bool, error)
+	DisableTOTP(username string) (bool, error)
+	ConsumeRecoveryCode(username string, hashedRecoveryCode string) (bool, error)
+	List(search string, skip int64, limit int64) ([]*models.User, int64, error)
+	UpdateRole(username string, role string, isAdmin bool) (bool, error)
+	UpdateModeration(username string, bannedAt *time.Time, suspendedUntil *time.Time, reason string) (bool, error)
+	FindOneByID(documentObjectID primitive.ObjectID) (*models.User, error)
+	FindOneByEmail(email string) (*models.User, error)
+	FindOneByIdentity(provider string, subject string) (*models.User, error)
+	AddIdentity(username string, identity models.Identity) (bool, error)
+	UpdateUsername(oldUsername string, newUsername string) (bool, error)
+	FetchPendingRenames() ([]*models.User, error)
+	ClearPendingRename(documentObjectID primitive.ObjectID, change models.UsernameChange) error
+	SoftDelete(username string, deletedAt time.Time) (bool, error)
+	Restore(username string) (bool, error)
+	FindOneDeleted(username string) (*models.User, error)
+	FetchDeletedBefore(cutoff time.Time) ([]*models.User, error)
+	Delete(documentObjectID primitive.ObjectID) (bool, error)
 }
 
 // RecipeRepository : defines the methods that can be performed on the recipe object in the repository layer
 type RecipeRepository interface {
 	Create(recipe *models.Recipe) error
 	FindOne(documentObjectID primitive.ObjectID) (*models.Recipe, error)
-	FetchAll() ([]*models.Recipe, error)
+	FetchAll(query RecipeQuery) ([]*models.Recipe, error)
+	FetchPage(query RecipeQuery, after *RecipeCursor, limit int64) ([]*models.Recipe, error)
+	FetchByIDs(documentObjectIDs []primitive.ObjectID) ([]*models.Recipe, error)
+	FetchAllByAuthorID(authorID primitive.ObjectID) ([]*models.Recipe, error)
 	Update(documentObjectID primitive.ObjectID, recipe *models.Recipe) (bool, error)
 	Delete(documentObjectID primitive.ObjectID) (bool, error)
+	UpdateUsername(authorID primitive.ObjectID, newUsername string) (int64, error)
+	SetAuthorDeleted(authorID primitive.ObjectID, deletedAt *time.Time) (int64, error)
+	DeleteAllByAuthorID(authorID primitive.ObjectID) (int64, error)
+}
+
+// RefreshTokenRepository : defines the methods that can be performed on the refresh token object in the repository layer
+type RefreshTokenRepository interface {
+	Create(refreshToken *models.RefreshToken) error
+	FindOneByHash(tokenHash string) (*models.RefreshToken, error)
+	MarkRotated(documentObjectID primitive.ObjectID, rotatedAt time.Time) (bool, error)
+	RevokeFamily(familyID primitive.ObjectID) error
+	RevokeAllForUser(userID primitive.ObjectID) error
+	UpdateUsername(userID primitive.ObjectID, newUsername string) error
+	DeleteAllForUser(userID primitive.ObjectID) error
+	FetchActiveByUserID(userID primitive.ObjectID, now time.Time) ([]*models.RefreshToken, error)
+	RevokeFamilyOfUser(familyID primitive.ObjectID, userID primitive.ObjectID) (bool, error)
+	UpdateLastSeen(familyID primitive.ObjectID, lastSeenAt time.Time, ip string) error
+}
+
+// PasswordResetTokenRepository : defines the methods that can be performed on the password reset token object in the repository layer
+type PasswordResetTokenRepository interface {
+	Create(passwordResetToken *models.PasswordResetToken) error
+	FindOneByHash(tokenHash string) (*models.PasswordResetToken, error)
+	MarkUsed(documentObjectID primitive.ObjectID, usedAt time.Time) (bool, error)
+	MarkAllUsedForUser(userID primitive.ObjectID, usedAt time.Time) error
+}
+
+// APIKeyRepository : defines the methods that can be performed on the api key object in the repository layer
+type APIKeyRepository interface {
+	Create(apiKey *models.APIKey) error
+	FindOneByHash(keyHash string) (*models.APIKey, error)
+	FetchAllByUserID(userID primitive.ObjectID) ([]*models.APIKey, error)
+	Revoke(documentObjectID primitive.ObjectID, userID primitive.ObjectID) (bool, error)
+	UpdateLastUsed(documentObjectID primitive.ObjectID, lastUsedAt time.Time) error
+	UpdateUsername(userID primitive.ObjectID, newUsername string) error
+	RevokeAllForUser(userID primitive.ObjectID) error
+	DeleteAllForUser(userID primitive.ObjectID) error
 }
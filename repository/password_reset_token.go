@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const passwordResetTokenCollectionName string = "password_reset_tokens"
+
+// NewPasswordResetTokenRepository : returns a passwordResetTokenRepo struct that implements the PasswordResetTokenRepository interface
+func NewPasswordResetTokenRepository(ctx context.Context, passwordResetTokenCollection *mongo.Collection) PasswordResetTokenRepository {
+	return &passwordResetTokenRepo{
+		ctx:        ctx,
+		collection: passwordResetTokenCollection,
+	}
+}
+
+type passwordResetTokenRepo struct {
+	ctx        context.Context
+	collection *mongo.Collection
+}
+
+// Create : inserts a new password reset token record in the `password_reset_tokens` collection
+func (prr *passwordResetTokenRepo) Create(prt *models.PasswordResetToken) error {
+	if !prr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := prr.collection.InsertOne(prr.ctx, prt)
+	return err
+}
+
+// FindOneByHash : finds a password reset token record with the provided token hash
+func (prr *passwordResetTokenRepo) FindOneByHash(tokenHash string) (*models.PasswordResetToken, error) {
+	if !prr.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	cur := prr.collection.FindOne(prr.ctx, bson.M{"token_hash": tokenHash})
+	if cur.Err() != nil {
+		return nil, cur.Err()
+	}
+
+	var passwordResetToken models.PasswordResetToken
+	err := cur.Decode(&passwordResetToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &passwordResetToken, nil
+}
+
+// MarkUsed : marks a password reset token as used, it only succeeds if the token was not used before
+func (prr *passwordResetTokenRepo) MarkUsed(documentObjectID primitive.ObjectID, usedAt time.Time) (bool, error) {
+	if !prr.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	result, err := prr.collection.UpdateOne(prr.ctx,
+		bson.M{"_id": documentObjectID, "used_at": nil},
+		bson.M{"$set": bson.M{"used_at": usedAt}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// MarkAllUsedForUser : marks every password reset token of the user that was not used yet as used
+func (prr *passwordResetTokenRepo) MarkAllUsedForUser(userID primitive.ObjectID, usedAt time.Time) error {
+	if !prr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := prr.collection.UpdateMany(prr.ctx,
+		bson.M{"user_id": userID, "used_at": nil},
+		bson.M{"$set": bson.M{"used_at": usedAt}},
+	)
+	return err
+}
+
+// isCollectionNameCorrect : verifies the collection name for the password reset token queries
+func (prr *passwordResetTokenRepo) isCollectionNameCorrect() bool {
+	return prr.collection.Name() == passwordResetTokenCollectionName
+}
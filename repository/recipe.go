@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/skamranahmed/smilecook/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const recipeCollectionName string = "recipes"
@@ -53,12 +55,100 @@ func (rr *recipeRepo) FindOne(documentObjectID primitive.ObjectID) (*models.Reci
 }
 
 // FetchAll : fetches all public recipe records
-func (rr *recipeRepo) FetchAll() ([]*models.Recipe, error) {
+func (rr *recipeRepo) FetchAll(query RecipeQuery) ([]*models.Recipe, error) {
 	if !rr.isCollectionNameCorrect() {
 		return nil, errors.New("incorrect collection name")
 	}
 
-	cur, err := rr.collection.Find(rr.ctx, bson.M{"isPrivate": false})
+	opts := options.Find()
+	if sort := query.sort(); sort != nil {
+		opts.SetSort(sort)
+	}
+
+	cur, err := rr.collection.Find(rr.ctx, query.filter(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(rr.ctx)
+
+	recipes := make([]*models.Recipe, 0)
+	for cur.Next(rr.ctx) {
+		var recipe models.Recipe
+		cur.Decode(&recipe)
+		recipes = append(recipes, &recipe)
+	}
+
+	return recipes, nil
+}
+
+// FetchPage : fetches up to limit public recipe records of the query that come after the cursor (from the start if it is nil),
+// the query must be sorted, the _id breaks the ties between recipes with the same value of the sort field
+func (rr *recipeRepo) FetchPage(query RecipeQuery, after *RecipeCursor, limit int64) ([]*models.Recipe, error) {
+	if !rr.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	if !query.Sort.IsValid() {
+		return nil, errors.New("a page of recipes needs a sorted query")
+	}
+
+	filter := query.filter()
+	if after != nil {
+		filter["$or"] = query.seek(after)
+	}
+
+	opts := options.Find().SetSort(query.sort()).SetLimit(limit)
+	cur, err := rr.collection.Find(rr.ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(rr.ctx)
+
+	recipes := make([]*models.Recipe, 0, limit)
+	for cur.Next(rr.ctx) {
+		var recipe models.Recipe
+		err := cur.Decode(&recipe)
+		if err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, &recipe)
+	}
+
+	return recipes, nil
+}
+
+// FetchByIDs : fetches the recipe records with the provided IDs, in no particular order, missing recipes are skipped
+func (rr *recipeRepo) FetchByIDs(documentObjectIDs []primitive.ObjectID) ([]*models.Recipe, error) {
+	if !rr.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	cur, err := rr.collection.Find(rr.ctx, bson.M{"_id": bson.M{"$in": documentObjectIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(rr.ctx)
+
+	recipes := make([]*models.Recipe, 0, len(documentObjectIDs))
+	for cur.Next(rr.ctx) {
+		var recipe models.Recipe
+		err := cur.Decode(&recipe)
+		if err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, &recipe)
+	}
+
+	return recipes, nil
+}
+
+// FetchAllByAuthorID : fetches every recipe record of the user, including the private ones
+func (rr *recipeRepo) FetchAllByAuthorID(authorID primitive.ObjectID) ([]*models.Recipe, error) {
+	if !rr.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	cur, err := rr.collection.Find(rr.ctx, bson.M{"authorId": authorID})
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +178,19 @@ func (rr *recipeRepo) Update(documentObjectID primitive.ObjectID, recipe *models
 				{Key: "instructions", Value: recipe.Instructions},
 				{Key: "ingredients", Value: recipe.Ingredients},
 				{Key: "tags", Value: recipe.Tags},
+				{Key: "servings", Value: recipe.Servings},
+				{Key: "allergens", Value: recipe.Allergens},
+				{Key: "diets", Value: recipe.Diets},
+				{Key: "dietaryOverrides", Value: recipe.DietaryOverrides},
+				{Key: "dietaryNotice", Value: recipe.DietaryNotice},
+				{Key: "prepTime", Value: recipe.PrepTime},
+				{Key: "cookTime", Value: recipe.CookTime},
+				{Key: "totalTime", Value: recipe.TotalTime},
+				{Key: "difficulty", Value: recipe.Difficulty},
+				{Key: "cuisine", Value: recipe.Cuisine},
+				{Key: "course", Value: recipe.Course},
+				{Key: "yield", Value: recipe.Yield},
+				{Key: "equipment", Value: recipe.Equipment},
 			},
 			},
 		},
@@ -121,6 +224,57 @@ func (rr *recipeRepo) Delete(documentObjectID primitive.ObjectID) (bool, error)
 	return true, nil
 }
 
+// SetAuthorDeleted : flags every recipe of the user while their account is deleted, a nil time clears the flag,
+// returns the number of updated recipes
+func (rr *recipeRepo) SetAuthorDeleted(authorID primitive.ObjectID, deletedAt *time.Time) (int64, error) {
+	if !rr.isCollectionNameCorrect() {
+		return 0, errors.New("incorrect collection name")
+	}
+
+	update := bson.M{"$unset": bson.M{"authorDeletedAt": ""}}
+	if deletedAt != nil {
+		update = bson.M{"$set": bson.M{"authorDeletedAt": *deletedAt}}
+	}
+
+	result, err := rr.collection.UpdateMany(rr.ctx, bson.M{"authorId": authorID}, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// UpdateUsername : updates the author display name on every recipe of the user, returns the number of updated recipes
+func (rr *recipeRepo) UpdateUsername(authorID primitive.ObjectID, newUsername string) (int64, error) {
+	if !rr.isCollectionNameCorrect() {
+		return 0, errors.New("incorrect collection name")
+	}
+
+	result, err := rr.collection.UpdateMany(rr.ctx,
+		bson.M{"authorId": authorID},
+		bson.M{"$set": bson.M{"username": newUsername}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// DeleteAllByAuthorID : deletes every recipe of the user, returns the number of deleted recipes
+func (rr *recipeRepo) DeleteAllByAuthorID(authorID primitive.ObjectID) (int64, error) {
+	if !rr.isCollectionNameCorrect() {
+		return 0, errors.New("incorrect collection name")
+	}
+
+	result, err := rr.collection.DeleteMany(rr.ctx, bson.M{"authorId": authorID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
 // isCollectionNameCorrect : verifies the collection name for the recipe queries
 func (rr *recipeRepo) isCollectionNameCorrect() bool {
 	return rr.collection.Name() == recipeCollectionName
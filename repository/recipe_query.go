@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/skamranahmed/smilecook/dietary"
+	"github.com/skamranahmed/smilecook/duration"
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecipeSort : the order the recipes are listed in
+type RecipeSort string
+
+const (
+	RecipeSortNewest RecipeSort = "newest"
+	RecipeSortOldest RecipeSort = "oldest"
+	RecipeSortName   RecipeSort = "name"
+)
+
+// IsValid : reports whether the sort is one of the supported ones
+func (s RecipeSort) IsValid() bool {
+	switch s {
+	case RecipeSortNewest, RecipeSortOldest, RecipeSortName:
+		return true
+	}
+	return false
+}
+
+// field : the recipe field the recipes are ordered by, the _id breaks the ties
+func (s RecipeSort) field() string {
+	if s == RecipeSortName {
+		return "name"
+	}
+	return "publishedAt"
+}
+
+// direction : 1 for ascending, -1 for descending
+func (s RecipeSort) direction() int {
+	if s == RecipeSortNewest {
+		return -1
+	}
+	return 1
+}
+
+// RecipeQuery : the filters and the order of a list of public recipes, the zero value lists every public recipe
+type RecipeQuery struct {
+	Tags            []string
+	MatchAllTags    bool // by default a recipe matches if it has any of the tags
+	Username        string
+	Ingredient      string // case insensitive substring of any of the ingredients
+	PublishedAfter  *time.Time
+	PublishedBefore *time.Time
+	Diets           []dietary.Diet     // a recipe matches if it is suitable for all of the diets
+	ExcludeAllergen []dietary.Allergen // a recipe matches if it contains none of the allergens
+	PrepTime        DurationRange
+	CookTime        DurationRange
+	TotalTime       DurationRange
+	Difficulties    []models.Difficulty // a recipe matches if it has any of the difficulties
+	Cuisines        []string            // a recipe matches if it has any of the cuisines, lower cased
+	Courses         []models.Course     // a recipe matches if it has any of the courses
+	Sort            RecipeSort          // empty keeps the natural order, FetchPage defaults to newest
+}
+
+// DurationRange : the inclusive bounds of a recipe time, a nil bound is open
+type DurationRange struct {
+	Min *time.Duration
+	Max *time.Duration
+}
+
+// filter : the mongo filter of the range on a time stored in seconds, nil if the range is open on both ends
+func (r DurationRange) filter() bson.M {
+	if r.Min == nil && r.Max == nil {
+		return nil
+	}
+
+	filter := bson.M{}
+	if r.Min != nil {
+		filter["$gte"] = duration.Seconds(*r.Min)
+	}
+	if r.Max != nil {
+		filter["$lte"] = duration.Seconds(*r.Max)
+	}
+	return filter
+}
+
+// RecipeCursor : the position after the last recipe of a page, the value of the sort field of that recipe and its ID
+type RecipeCursor struct {
+	PublishedAt time.Time
+	Name        string
+	ID          primitive.ObjectID
+}
+
+// filter : the mongo filter that matches the recipes of the query
+func (q RecipeQuery) filter() bson.M {
+	// a nil authorDeletedAt matches the recipes whose author is not deleted
+	filter := bson.M{"isPrivate": false, "authorDeletedAt": nil}
+
+	if len(q.Tags) == 1 {
+		filter["tags"] = q.Tags[0]
+	} else if len(q.Tags) > 1 {
+		operator := "$in"
+		if q.MatchAllTags {
+			operator = "$all"
+		}
+		filter["tags"] = bson.M{operator: q.Tags}
+	}
+
+	if q.Username != "" {
+		filter["username"] = q.Username
+	}
+
+	if q.Ingredient != "" {
+		filter["ingredients"] = primitive.Regex{Pattern: regexp.QuoteMeta(q.Ingredient), Options: "i"}
+	}
+
+	if q.PublishedAfter != nil || q.PublishedBefore != nil {
+		publishedAt := bson.M{}
+		if q.PublishedAfter != nil {
+			publishedAt["$gte"] = *q.PublishedAfter
+		}
+		if q.PublishedBefore != nil {
+			publishedAt["$lte"] = *q.PublishedBefore
+		}
+		filter["publishedAt"] = publishedAt
+	}
+
+	if len(q.Diets) > 0 {
+		filter["diets"] = bson.M{"$all": q.Diets}
+	}
+
+	if len(q.ExcludeAllergen) > 0 {
+		filter["allergens"] = bson.M{"$nin": q.ExcludeAllergen}
+	}
+
+	// the recipes that do not say how long they take do not match a time range
+	for field, timeRange := range map[string]DurationRange{"prepTime": q.PrepTime, "cookTime": q.CookTime, "totalTime": q.TotalTime} {
+		if rangeFilter := timeRange.filter(); rangeFilter != nil {
+			filter[field] = rangeFilter
+		}
+	}
+
+	if len(q.Difficulties) > 0 {
+		filter["difficulty"] = bson.M{"$in": q.Difficulties}
+	}
+
+	if len(q.Cuisines) > 0 {
+		filter["cuisine"] = bson.M{"$in": q.Cuisines}
+	}
+
+	if len(q.Courses) > 0 {
+		filter["course"] = bson.M{"$in": q.Courses}
+	}
+
+	return filter
+}
+
+// sort : the mongo sort of the query, nil if the query keeps the natural order
+func (q RecipeQuery) sort() bson.D {
+	if q.Sort == "" {
+		return nil
+	}
+	return bson.D{{Key: q.Sort.field(), Value: q.Sort.direction()}, {Key: "_id", Value: q.Sort.direction()}}
+}
+
+// seek : the mongo filter that matches the recipes that come after the cursor in the order of the query
+func (q RecipeQuery) seek(after *RecipeCursor) bson.A {
+	operator := "$gt"
+	if q.Sort.direction() < 0 {
+		operator = "$lt"
+	}
+
+	var value interface{} = after.PublishedAt
+	if q.Sort == RecipeSortName {
+		value = after.Name
+	}
+
+	field := q.Sort.field()
+	return bson.A{
+		bson.M{field: bson.M{operator: value}},
+		bson.M{field: value, "_id": bson.M{operator: after.ID}},
+	}
+}
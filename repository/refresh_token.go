@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const refreshTokenCollectionName string = "refresh_tokens"
+
+// NewRefreshTokenRepository : returns a refreshTokenRepo struct that implements the RefreshTokenRepository interface
+func NewRefreshTokenRepository(ctx context.Context, refreshTokenCollection *mongo.Collection) RefreshTokenRepository {
+	return &refreshTokenRepo{
+		ctx:        ctx,
+		collection: refreshTokenCollection,
+	}
+}
+
+type refreshTokenRepo struct {
+	ctx        context.Context
+	collection *mongo.Collection
+}
+
+// Create : inserts a new refresh token record in the `refresh_tokens` collection
+func (rtr *refreshTokenRepo) Create(rt *models.RefreshToken) error {
+	if !rtr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := rtr.collection.InsertOne(rtr.ctx, rt)
+	return err
+}
+
+// FindOneByHash : finds a refresh token record with the provided token hash
+func (rtr *refreshTokenRepo) FindOneByHash(tokenHash string) (*models.RefreshToken, error) {
+	if !rtr.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	cur := rtr.collection.FindOne(rtr.ctx, bson.M{"token_hash": tokenHash})
+	if cur.Err() != nil {
+		return nil, cur.Err()
+	}
+
+	var refreshToken models.RefreshToken
+	err := cur.Decode(&refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &refreshToken, nil
+}
+
+// MarkRotated : marks a refresh token as rotated, it only succeeds if the token was neither rotated nor revoked before
+// this makes sure that two concurrent requests cannot rotate the same token
+func (rtr *refreshTokenRepo) MarkRotated(documentObjectID primitive.ObjectID, rotatedAt time.Time) (bool, error) {
+	if !rtr.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	result, err := rtr.collection.UpdateOne(rtr.ctx,
+		bson.M{"_id": documentObjectID, "rotated_at": nil, "revoked_at": nil},
+		bson.M{"$set": bson.M{"rotated_at": rotatedAt}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// RevokeFamily : revokes every refresh token that belongs to the provided token family
+func (rtr *refreshTokenRepo) RevokeFamily(familyID primitive.ObjectID) error {
+	if !rtr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := rtr.collection.UpdateMany(rtr.ctx,
+		bson.M{"family_id": familyID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// RevokeAllForUser : revokes every refresh token that belongs to the provided user
+func (rtr *refreshTokenRepo) RevokeAllForUser(userID primitive.ObjectID) error {
+	if !rtr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := rtr.collection.UpdateMany(rtr.ctx,
+		bson.M{"user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return err
+}
+
+// UpdateUsername : moves every refresh token of the user to the new username
+func (rtr *refreshTokenRepo) UpdateUsername(userID primitive.ObjectID, newUsername string) error {
+	if !rtr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := rtr.collection.UpdateMany(rtr.ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$set": bson.M{"username": newUsername}},
+	)
+	return err
+}
+
+// DeleteAllForUser : deletes every refresh token of the user
+func (rtr *refreshTokenRepo) DeleteAllForUser(userID primitive.ObjectID) error {
+	if !rtr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := rtr.collection.DeleteMany(rtr.ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// FetchActiveByUserID : fetches the current refresh token of every session of the user, most recently seen first
+func (rtr *refreshTokenRepo) FetchActiveByUserID(userID primitive.ObjectID, now time.Time) ([]*models.RefreshToken, error) {
+	if !rtr.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	cur, err := rtr.collection.Find(rtr.ctx,
+		bson.M{"user_id": userID, "rotated_at": nil, "revoked_at": nil, "expires_at": bson.M{"$gt": now}},
+		options.Find().SetSort(bson.M{"last_seen_at": -1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(rtr.ctx)
+
+	refreshTokens := make([]*models.RefreshToken, 0)
+	for cur.Next(rtr.ctx) {
+		var refreshToken models.RefreshToken
+		err := cur.Decode(&refreshToken)
+		if err != nil {
+			return nil, err
+		}
+		refreshTokens = append(refreshTokens, &refreshToken)
+	}
+
+	return refreshTokens, nil
+}
+
+// RevokeFamilyOfUser : revokes the token family, only if it belongs to the user and is still active
+func (rtr *refreshTokenRepo) RevokeFamilyOfUser(familyID primitive.ObjectID, userID primitive.ObjectID) (bool, error) {
+	if !rtr.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	result, err := rtr.collection.UpdateMany(rtr.ctx,
+		bson.M{"family_id": familyID, "user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// UpdateLastSeen : sets the last seen time and ip on the current refresh token of the family
+func (rtr *refreshTokenRepo) UpdateLastSeen(familyID primitive.ObjectID, lastSeenAt time.Time, ip string) error {
+	if !rtr.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := rtr.collection.UpdateOne(rtr.ctx,
+		bson.M{"family_id": familyID, "rotated_at": nil, "revoked_at": nil},
+		bson.M{"$set": bson.M{"last_seen_at": lastSeenAt, "last_seen_ip": ip}},
+	)
+	return err
+}
+
+// isCollectionNameCorrect : verifies the collection name for the refresh token queries
+func (rtr *refreshTokenRepo) isCollectionNameCorrect() bool {
+	return rtr.collection.Name() == refreshTokenCollectionName
+}
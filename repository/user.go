@@ -3,10 +3,14 @@ package repository
 import (
 	"context"
 	"errors"
+	"regexp"
+	"time"
 
 	"github.com/skamranahmed/smilecook/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const userCollectionName string = "users"
@@ -35,13 +39,378 @@ func (ur *userRepo) Create(u *models.User) error {
 	return err
 }
 
-// FindOne : finds a user record with the provided username
+// FindOne : finds a user record with the provided username, deleted users are ignored
 func (ur *userRepo) FindOne(username string) (*models.User, error) {
+	return ur.findOne(bson.M{"username": username})
+}
+
+// DoesUsernameAlreadyExist: checks whether a user with the provided username exists or not
+func (ur *userRepo) DoesUsernameAlreadyExist(username string) (bool, error) {
+	if !ur.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	cur := ur.collection.FindOne(ur.ctx, bson.M{
+		"username": username,
+	})
+
+	if cur.Err() != mongo.ErrNoDocuments {
+		// this means a user with the provided username already exists
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// UpdatePassword : replaces the password hash of the user with the provided username
+func (ur *userRepo) UpdatePassword(username string, hashedPassword string) (bool, error) {
+	if !ur.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	result, err := ur.collection.UpdateOne(ur.ctx,
+		bson.M{"username": username},
+		bson.M{"$set": bson.M{"password": hashedPassword, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// ReplacePasswordHash : replaces the password hash of the user only if it is still the provided old hash,
+// so that an upgraded hash never overwrites a password that was changed in the meantime
+func (ur *userRepo) ReplacePasswordHash(username string, oldHashedPassword string, newHashedPassword string) (bool, error) {
+	if !ur.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	result, err := ur.collection.UpdateOne(ur.ctx,
+		bson.M{"username": username, "password": oldHashedPassword},
+		bson.M{
+			"$set":   bson.M{"password": newHashedPassword, "updated_at": time.Now()},
+			"$unset": bson.M{"pending_rehash": ""},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// SetPendingRehash : stores an upgraded password hash to apply later, only if the password hash is still the one it replaces
+func (ur *userRepo) SetPendingRehash(username string, rehash models.PasswordRehash) (bool, error) {
+	return ur.updateOne(bson.M{"username": username, "password": rehash.From}, bson.M{"pending_rehash": rehash})
+}
+
+// DoesEmailAlreadyExist : checks whether a user with the provided email exists or not
+func (ur *userRepo) DoesEmailAlreadyExist(email string) (bool, error) {
+	if !ur.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	cur := ur.collection.FindOne(ur.ctx, bson.M{"email": email})
+	if cur.Err() != nil {
+		if cur.Err() == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, cur.Err()
+	}
+
+	return true, nil
+}
+
+// UpdateEmail : sets a new, not yet verified, email for the user with the provided username
+func (ur *userRepo) UpdateEmail(username string, email string) (bool, error) {
+	if !ur.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	result, err := ur.collection.UpdateOne(ur.ctx,
+		bson.M{"username": username},
+		bson.M{"$set": bson.M{
+			"email":             email,
+			"email_verified":    false,
+			"email_verified_at": nil,
+			"updated_at":        time.Now(),
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// MarkEmailVerified : marks the email of the user as verified, it only succeeds if the user still has the same email
+func (ur *userRepo) MarkEmailVerified(username string, email string) (bool, error) {
+	if !ur.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	now := time.Now()
+	result, err := ur.collection.UpdateOne(ur.ctx,
+		bson.M{"username": username, "email": email},
+		bson.M{"$set": bson.M{
+			"email_verified":    true,
+			"email_verified_at": now,
+			"updated_at":        now,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// SetPendingTOTPSecret : stores a totp secret that still has to be confirmed by the user
+func (ur *userRepo) SetPendingTOTPSecret(username string, secret string) (bool, error) {
+	return ur.updateOne(bson.M{"username": username}, bson.M{
+		"totp_pending_secret": secret,
+		"updated_at":          time.Now(),
+	})
+}
+
+// EnableTOTP : turns on two-factor authentication for the user
+func (ur *userRepo) EnableTOTP(username string, secret string, hashedRecoveryCodes []string) (bool, error) {
+	return ur.updateOne(bson.M{"username": username}, bson.M{
+		"totp_enabled":        true,
+		"totp_secret":         secret,
+		"totp_pending_secret": "",
+		"recovery_codes":      hashedRecoveryCodes,
+		"updated_at":          time.Now(),
+	})
+}
+
+// DisableTOTP : turns off two-factor authentication for the user and drops the secret and the recovery codes
+func (ur *userRepo) DisableTOTP(username string) (bool, error) {
+	return ur.updateOne(bson.M{"username": username}, bson.M{
+		"totp_enabled":        false,
+		"totp_secret":         "",
+		"totp_pending_secret": "",
+		"recovery_codes":      []string{},
+		"updated_at":          time.Now(),
+	})
+}
+
+// ConsumeRecoveryCode : removes the recovery code from the user, it only succeeds if the user still had the code
+func (ur *userRepo) ConsumeRecoveryCode(username string, hashedRecoveryCode string) (bool, error) {
+	if !ur.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	result, err := ur.collection.UpdateOne(ur.ctx,
+		bson.M{"username": username, "recovery_codes": hashedRecoveryCode},
+		bson.M{"$pull": bson.M{"recovery_codes": hashedRecoveryCode}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if result.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// List : lists the users whose username or email starts with the search term (every user if it is empty), sorted by username
+// returns the requested page of users and the total number of matching users
+func (ur *userRepo) List(search string, skip int64, limit int64) ([]*models.User, int64, error) {
+	if !ur.isCollectionNameCorrect() {
+		return nil, 0, errors.New("incorrect collection name")
+	}
+
+	filter := bson.M{}
+	if search != "" {
+		pattern := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(search), Options: "i"}
+		filter = bson.M{"$or": bson.A{
+			bson.M{"username": pattern},
+			bson.M{"email": pattern},
+		}}
+	}
+
+	total, err := ur.collection.CountDocuments(ur.ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"username": 1}).SetSkip(skip).SetLimit(limit)
+	cur, err := ur.collection.Find(ur.ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ur.ctx)
+
+	users := make([]*models.User, 0)
+	for cur.Next(ur.ctx) {
+		var user models.User
+		err := cur.Decode(&user)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, total, nil
+}
+
+// UpdateRole : sets the role of the user, the legacy is_admin flag is kept in sync
+func (ur *userRepo) UpdateRole(username string, role string, isAdmin bool) (bool, error) {
+	return ur.updateOne(bson.M{"username": username}, bson.M{
+		"role":       role,
+		"is_admin":   isAdmin,
+		"updated_at": time.Now(),
+	})
+}
+
+// UpdateModeration : sets the ban and suspension state of the user, nil values lift the ban or the suspension
+func (ur *userRepo) UpdateModeration(username string, bannedAt *time.Time, suspendedUntil *time.Time, reason string) (bool, error) {
+	return ur.updateOne(bson.M{"username": username}, bson.M{
+		"banned_at":         bannedAt,
+		"suspended_until":   suspendedUntil,
+		"moderation_reason": reason,
+		"updated_at":        time.Now(),
+	})
+}
+
+// FindOneByID : finds a user record with the provided ID, deleted users are ignored
+func (ur *userRepo) FindOneByID(documentObjectID primitive.ObjectID) (*models.User, error) {
+	return ur.findOne(bson.M{"_id": documentObjectID})
+}
+
+// FindOneByEmail : finds a user record with the provided email, deleted users are ignored
+func (ur *userRepo) FindOneByEmail(email string) (*models.User, error) {
+	return ur.findOne(bson.M{"email": email})
+}
+
+// FindOneByIdentity : finds the user record that the account at the external provider is linked to, deleted users are ignored
+func (ur *userRepo) FindOneByIdentity(provider string, subject string) (*models.User, error) {
+	return ur.findOne(bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}})
+}
+
+// AddIdentity : links the account at the external provider to the user, an account can only be linked once
+func (ur *userRepo) AddIdentity(username string, identity models.Identity) (bool, error) {
+	if !ur.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	result, err := ur.collection.UpdateOne(ur.ctx,
+		bson.M{
+			"username":   username,
+			"identities": bson.M{"$not": bson.M{"$elemMatch": bson.M{"provider": identity.Provider, "subject": identity.Subject}}},
+		},
+		bson.M{
+			"$push": bson.M{"identities": identity},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// UpdateUsername : renames the user and records the rename as pending until ClearPendingRename is called,
+// it fails if the new username is taken, even by a deleted user, or if another rename is still pending.
+// The unique index on the username rejects a concurrent rename or sign up to the same username
+func (ur *userRepo) UpdateUsername(oldUsername string, newUsername string) (bool, error) {
+	renamed, err := ur.updateOne(bson.M{"username": oldUsername, "deleted_at": nil, "pending_rename": nil}, bson.M{
+		"username":       newUsername,
+		"pending_rename": models.UsernameChange{From: oldUsername, To: newUsername},
+		"updated_at":     time.Now(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return renamed, err
+}
+
+// FetchPendingRenames : fetches the users whose last rename has not been copied to all their records
+func (ur *userRepo) FetchPendingRenames() ([]*models.User, error) {
 	if !ur.isCollectionNameCorrect() {
 		return nil, errors.New("incorrect collection name")
 	}
 
-	cur := ur.collection.FindOne(ur.ctx, bson.M{"username": username})
+	cur, err := ur.collection.Find(ur.ctx, bson.M{"pending_rename": bson.M{"$ne": nil}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ur.ctx)
+
+	users := make([]*models.User, 0)
+	for cur.Next(ur.ctx) {
+		var user models.User
+		err := cur.Decode(&user)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, nil
+}
+
+// ClearPendingRename : marks the rename of the user as complete
+func (ur *userRepo) ClearPendingRename(documentObjectID primitive.ObjectID, change models.UsernameChange) error {
+	if !ur.isCollectionNameCorrect() {
+		return errors.New("incorrect collection name")
+	}
+
+	_, err := ur.collection.UpdateOne(ur.ctx,
+		bson.M{"_id": documentObjectID, "pending_rename": change},
+		bson.M{"$unset": bson.M{"pending_rename": ""}},
+	)
+	return err
+}
+
+// SoftDelete : marks the user as deleted, the record is kept until it is purged
+func (ur *userRepo) SoftDelete(username string, deletedAt time.Time) (bool, error) {
+	return ur.updateOne(bson.M{"username": username, "deleted_at": nil}, bson.M{
+		"deleted_at": deletedAt,
+		"updated_at": deletedAt,
+	})
+}
+
+// Restore : lifts the soft delete of the user
+func (ur *userRepo) Restore(username string) (bool, error) {
+	return ur.updateOne(bson.M{"username": username, "deleted_at": bson.M{"$ne": nil}}, bson.M{
+		"deleted_at": nil,
+		"updated_at": time.Now(),
+	})
+}
+
+// FindOneDeleted : finds the soft deleted user record with the provided username
+func (ur *userRepo) FindOneDeleted(username string) (*models.User, error) {
+	if !ur.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	cur := ur.collection.FindOne(ur.ctx, bson.M{"username": username, "deleted_at": bson.M{"$ne": nil}})
 	if cur.Err() != nil {
 		return nil, cur.Err()
 	}
@@ -55,22 +424,88 @@ func (ur *userRepo) FindOne(username string) (*models.User, error) {
 	return &user, nil
 }
 
-// DoesUsernameAlreadyExist: checks whether a user with the provided username exists or not
-func (ur *userRepo) DoesUsernameAlreadyExist(username string) (bool, error) {
+// FetchDeletedBefore : fetches the users that were soft deleted before the cutoff
+func (ur *userRepo) FetchDeletedBefore(cutoff time.Time) ([]*models.User, error) {
+	if !ur.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	cur, err := ur.collection.Find(ur.ctx, bson.M{"deleted_at": bson.M{"$ne": nil, "$lt": cutoff}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ur.ctx)
+
+	users := make([]*models.User, 0)
+	for cur.Next(ur.ctx) {
+		var user models.User
+		err := cur.Decode(&user)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, nil
+}
+
+// Delete : permanently deletes the user record with the provided ID
+func (ur *userRepo) Delete(documentObjectID primitive.ObjectID) (bool, error) {
 	if !ur.isCollectionNameCorrect() {
 		return false, errors.New("incorrect collection name")
 	}
 
-	cur := ur.collection.FindOne(ur.ctx, bson.M{
-		"username": username,
-	})
+	result, err := ur.collection.DeleteOne(ur.ctx, bson.M{"_id": documentObjectID})
+	if err != nil {
+		return false, err
+	}
 
-	if cur.Err() != mongo.ErrNoDocuments {
-		// this means a user with the provided username already exists
-		return true, nil
+	if result.DeletedCount == 0 {
+		return false, nil
 	}
 
-	return false, nil
+	return true, nil
+}
+
+// findOne : finds the user record matching the filter, soft deleted users are never returned
+func (ur *userRepo) findOne(filter bson.M) (*models.User, error) {
+	if !ur.isCollectionNameCorrect() {
+		return nil, errors.New("incorrect collection name")
+	}
+
+	// a nil filter matches both a null and a missing deleted_at
+	filter["deleted_at"] = nil
+
+	cur := ur.collection.FindOne(ur.ctx, filter)
+	if cur.Err() != nil {
+		return nil, cur.Err()
+	}
+
+	var user models.User
+	err := cur.Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// updateOne : sets the fields on the user matching the filter, returns false if no user matched
+func (ur *userRepo) updateOne(filter bson.M, fields bson.M) (bool, error) {
+	if !ur.isCollectionNameCorrect() {
+		return false, errors.New("incorrect collection name")
+	}
+
+	result, err := ur.collection.UpdateOne(ur.ctx, filter, bson.M{"$set": fields})
+	if err != nil {
+		return false, err
+	}
+
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	return true, nil
 }
 
 // isCollectionNameCorrect : verifies the collection name for the user queries
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token : an indexed term and the byte offsets of the word it comes from
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true, "by": true,
+	"for": true, "from": true, "if": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"of": true, "on": true, "or": true, "so": true, "than": true, "that": true, "the": true, "then": true,
+	"there": true, "these": true, "this": true, "to": true, "was": true, "with": true,
+}
+
+// Analyze : splits the text into words, lower cases them, drops the stop words and stems the rest
+func Analyze(text string) []Token {
+	tokens := make([]Token, 0)
+
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := strings.ToLower(text[start:end])
+		if !stopWords[word] {
+			tokens = append(tokens, Token{Term: Stem(word), Start: start, End: end})
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+
+	return tokens
+}
+
+// Terms : the distinct terms of the text, in the order they first appear
+func Terms(text string) []string {
+	seen := make(map[string]bool)
+	terms := make([]string, 0)
+	for _, token := range Analyze(text) {
+		if seen[token.Term] {
+			continue
+		}
+		seen[token.Term] = true
+		terms = append(terms, token.Term)
+	}
+	return terms
+}
@@ -0,0 +1,236 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/skamranahmed/smilecook/models"
+)
+
+// the kinds of autocomplete suggestions
+const (
+	KindName       string = "name"
+	KindTag        string = "tag"
+	KindIngredient string = "ingredient"
+)
+
+// Entry : a text that can be suggested, Count is the number of public recipes that use it
+type Entry struct {
+	Text  string `json:"text"`
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+}
+
+// Suggestion : an entry that completes the typed text, Distance is the number of typos that were corrected to find it
+type Suggestion struct {
+	Text     string `json:"text"`
+	Kind     string `json:"kind"`
+	Count    int    `json:"count"`
+	Distance int    `json:"distance"`
+}
+
+// Autocompleter : suggests recipe names, tags and ingredients as the user types and corrects misspelled words
+type Autocompleter struct {
+	entries []Entry
+	phrases *trie    // the entries, a multi word entry is also found by the start of any of its words
+	words   *trie    // the words of the entries, used to correct misspellings
+	vocab   []string // the words of the words trie by index
+	counts  []int    // how many recipes use each word of vocab
+}
+
+// BuildEntries : the autocomplete entries of the recipes, the popularity of an entry is the number of recipes that use it
+func BuildEntries(recipes []*models.Recipe) []Entry {
+	type key struct{ kind, text string }
+	counts := make(map[key]int)
+	display := make(map[key]string)
+
+	for _, recipe := range recipes {
+		seen := make(map[key]bool)
+		add := func(kind string, text string) {
+			normalized := normalize(text)
+			if normalized == "" {
+				return
+			}
+			k := key{kind: kind, text: normalized}
+			if seen[k] {
+				return
+			}
+			seen[k] = true
+			counts[k]++
+			if _, ok := display[k]; !ok {
+				display[k] = strings.TrimSpace(text)
+			}
+		}
+
+		add(KindName, recipe.Name)
+		for _, tag := range recipe.Tags {
+			add(KindTag, tag)
+		}
+		for _, structured := range recipe.StructuredIngredients() {
+			add(KindIngredient, structured.Item)
+		}
+	}
+
+	entries := make([]Entry, 0, len(counts))
+	for k, count := range counts {
+		entries = append(entries, Entry{Text: display[k], Kind: k.kind, Count: count})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Text < entries[j].Text
+	})
+
+	return entries
+}
+
+// NewAutocompleter : builds the prefix trees of the entries
+func NewAutocompleter(entries []Entry) *Autocompleter {
+	a := &Autocompleter{
+		entries: entries,
+		phrases: newTrie(),
+		words:   newTrie(),
+	}
+
+	wordIndexes := make(map[string]int)
+	for i, entry := range entries {
+		words := strings.Fields(normalize(entry.Text))
+		for start := range words {
+			a.phrases.insert(strings.Join(words[start:], " "), i)
+		}
+
+		for _, word := range words {
+			index, ok := wordIndexes[word]
+			if !ok {
+				index = len(a.vocab)
+				wordIndexes[word] = index
+				a.vocab = append(a.vocab, word)
+				a.counts = append(a.counts, 0)
+				a.words.insert(word, index)
+			}
+			a.counts[index] += entry.Count
+		}
+	}
+
+	return a
+}
+
+// Complete : the most popular entries that start with the text, when there are not enough of them
+// the entries that start with a close misspelling of the text are added after them
+func (a *Autocompleter) Complete(text string, limit int) []Suggestion {
+	text = normalize(text)
+	if text == "" {
+		return []Suggestion{}
+	}
+
+	matches := a.phrases.withPrefix(text)
+	if maxDistance := maxEditDistance(text); maxDistance > 0 && uniqueEntries(matches) < limit {
+		matches = append(matches, a.phrases.fuzzy(text, maxDistance, true)...)
+	}
+
+	// an entry may be found more than once, through several of its words or with a different distance
+	distances := make(map[int]int)
+	for _, match := range matches {
+		distance, ok := distances[match.entry]
+		if !ok || match.distance < distance {
+			distances[match.entry] = match.distance
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(distances))
+	for index, distance := range distances {
+		entry := a.entries[index]
+		suggestions = append(suggestions, Suggestion{Text: entry.Text, Kind: entry.Kind, Count: entry.Count, Distance: distance})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Distance != suggestions[j].Distance {
+			return suggestions[i].Distance < suggestions[j].Distance
+		}
+		if suggestions[i].Count != suggestions[j].Count {
+			return suggestions[i].Count > suggestions[j].Count
+		}
+		return suggestions[i].Text < suggestions[j].Text
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// Correct : replaces the unknown words of the text with the closest, most popular known word,
+// it returns false if there is nothing to correct
+func (a *Autocompleter) Correct(text string) (string, bool) {
+	words := strings.Fields(normalize(text))
+	corrected := false
+
+	for i, word := range words {
+		if a.words.contains(word) {
+			continue
+		}
+
+		maxDistance := maxEditDistance(word)
+		if maxDistance == 0 {
+			continue
+		}
+
+		best, bestDistance := -1, 0
+		for _, match := range a.words.fuzzy(word, maxDistance, false) {
+			if best < 0 || match.distance < bestDistance || (match.distance == bestDistance && a.isMorePopular(match.entry, best)) {
+				best, bestDistance = match.entry, match.distance
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		words[i] = a.vocab[best]
+		corrected = true
+	}
+
+	if !corrected {
+		return "", false
+	}
+	return strings.Join(words, " "), true
+}
+
+// isMorePopular : compares two words by popularity, then alphabetically so that the corrections are stable
+func (a *Autocompleter) isMorePopular(word int, other int) bool {
+	if a.counts[word] != a.counts[other] {
+		return a.counts[word] > a.counts[other]
+	}
+	return a.vocab[word] < a.vocab[other]
+}
+
+// maxEditDistance : the number of typos tolerated in a text, short texts have too many close neighbours to tolerate any
+func maxEditDistance(text string) int {
+	length := len([]rune(text))
+	switch {
+	case length < 3:
+		return 0
+	case length < 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// normalize : lower cases the text and keeps its words separated by single spaces
+func normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '\''
+	}), " ")
+}
+
+// uniqueEntries : the number of distinct entries of the matches
+func uniqueEntries(matches []trieMatch) int {
+	seen := make(map[int]bool, len(matches))
+	for _, match := range matches {
+		seen[match.entry] = true
+	}
+	return len(seen)
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/skamranahmed/smilecook/models"
+)
+
+func testAutocompleter() *Autocompleter {
+	return NewAutocompleter(BuildEntries([]*models.Recipe{
+		{Name: "Tomato soup", Tags: []string{"soup", "vegetarian"}},
+		{Name: "Tomato salad", Tags: []string{"salad", "vegetarian"}},
+		{Name: "Chicken soup", Tags: []string{"soup"}},
+		{Name: "Chocolate cake", Tags: []string{"dessert"}},
+	}))
+}
+
+func TestBuildEntriesCountsRecipes(t *testing.T) {
+	entries := BuildEntries([]*models.Recipe{
+		{Name: "Soup", Tags: []string{"Quick", "quick "}},
+		{Name: "soup", Tags: []string{"quick"}},
+	})
+
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		counts[entry.Kind+":"+entry.Text] = entry.Count
+	}
+
+	// the same tag twice on a recipe counts once, names and tags are compared without case
+	if counts["name:Soup"] != 2 || counts["tag:Quick"] != 2 || len(entries) != 2 {
+		t.Fatalf("BuildEntries() = %+v, want the name and the tag counted once per recipe", entries)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	a := testAutocompleter()
+
+	suggestions := a.Complete("tom", 10)
+	if len(suggestions) != 2 || suggestions[0].Distance != 0 || suggestions[1].Distance != 0 {
+		t.Fatalf("Complete(%q) = %+v, want the two tomato recipes", "tom", suggestions)
+	}
+
+	// the entry is also found by the start of its second word, the popular tag comes first
+	suggestions = a.Complete("sou", 10)
+	if len(suggestions) != 3 || suggestions[0].Text != "soup" || suggestions[0].Count != 2 {
+		t.Fatalf("Complete(%q) = %+v, want the soup tag first", "sou", suggestions)
+	}
+
+	// a misspelling is corrected after the exact matches
+	suggestions = a.Complete("chocolat cak", 10)
+	if len(suggestions) != 1 || suggestions[0].Text != "Chocolate cake" || suggestions[0].Distance != 1 {
+		t.Fatalf("Complete(%q) = %+v, want the chocolate cake at distance 1", "chocolat cak", suggestions)
+	}
+
+	if suggestions = a.Complete("tom", 1); len(suggestions) != 1 {
+		t.Fatalf("Complete() with limit 1 = %+v, want 1 suggestion", suggestions)
+	}
+
+	// short texts tolerate no typos
+	if suggestions = a.Complete("xo", 10); len(suggestions) != 0 {
+		t.Fatalf("Complete(%q) = %+v, want none", "xo", suggestions)
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	a := testAutocompleter()
+
+	tests := []struct {
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"tomatto soop", "tomato soup", true},
+		{"tomatto sopu", "tomato sopu", true},
+		{"chiken", "chicken", true},
+		{"tomato soup", "", false},
+		{"zzzzzzzz", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := a.Correct(tt.text)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Correct(%q) = %q, %t, want %q, %t", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
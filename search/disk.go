@@ -0,0 +1,449 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the indexed fields of a recipe
+const (
+	fieldName = iota
+	fieldTags
+	fieldIngredients
+	fieldInstructions
+	fieldCount
+)
+
+// fieldWeights : a match in the name counts more than a match in the instructions
+var fieldWeights = [fieldCount]float64{3, 2, 1.5, 1}
+
+// BM25 parameters
+const (
+	bm25K1 float64 = 1.2
+	bm25B  float64 = 0.75
+)
+
+// minCompactionLogEntries : the log is folded into the snapshot once it holds this many entries, or more entries than
+// there are documents, so that the cost of writing the snapshot is spread over at least as many changes
+const minCompactionLogEntries int = 1000
+
+// maxLogEntrySize : a larger length can only come from a damaged log
+const maxLogEntrySize uint32 = 64 << 20
+
+// diskDocument : the term frequencies of the fields of an indexed recipe
+type diskDocument struct {
+	Terms   [fieldCount]map[string]int
+	Lengths [fieldCount]int
+
+	// Fingerprint : a hash of the indexed text, a recipe whose fingerprint matches does not need to be indexed again
+	Fingerprint string
+}
+
+// diskLogEntry : a change of the index, a nil document removes the recipe
+type diskLogEntry struct {
+	ID  primitive.ObjectID
+	Doc *diskDocument
+}
+
+// DiskIndex : an embedded inverted index that lives in memory, every change is appended to a log file next to a snapshot
+// of the documents and the log is folded into the snapshot once it grows large, recipes are ranked with BM25F over their fields
+type DiskIndex struct {
+	mu         sync.RWMutex
+	path       string
+	log        *os.File
+	logEntries int
+	docs       map[primitive.ObjectID]*diskDocument
+	postings   map[string]map[primitive.ObjectID]bool
+	lengths    [fieldCount]int // the summed length of every field over all the documents
+}
+
+// OpenDiskIndex : loads the snapshot saved at path and replays the log saved next to it,
+// the index starts empty if there are no files yet
+func OpenDiskIndex(path string) (*DiskIndex, error) {
+	index := &DiskIndex{
+		path:     path,
+		docs:     make(map[primitive.ObjectID]*diskDocument),
+		postings: make(map[string]map[primitive.ObjectID]bool),
+	}
+
+	err := index.loadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	index.log, err = os.OpenFile(index.logPath(), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = index.replayLog()
+	if err != nil {
+		index.log.Close()
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// Close : closes the log file, the index must not be used afterwards
+func (di *DiskIndex) Close() error {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+
+	return di.log.Close()
+}
+
+// Count : the number of indexed recipes
+func (di *DiskIndex) Count() int {
+	di.mu.RLock()
+	defer di.mu.RUnlock()
+
+	return len(di.docs)
+}
+
+// Index : adds the recipe to the index or replaces it
+func (di *DiskIndex) Index(recipe *models.Recipe) error {
+	doc := newDiskDocument(recipe)
+
+	di.mu.Lock()
+	defer di.mu.Unlock()
+
+	di.remove(recipe.ID)
+	di.add(recipe.ID, doc)
+	return di.commit([]diskLogEntry{{ID: recipe.ID, Doc: doc}})
+}
+
+// Delete : removes the recipe from the index, it is not an error if it was not indexed
+func (di *DiskIndex) Delete(documentObjectID primitive.ObjectID) error {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+
+	if _, ok := di.docs[documentObjectID]; !ok {
+		return nil
+	}
+
+	di.remove(documentObjectID)
+	return di.commit([]diskLogEntry{{ID: documentObjectID}})
+}
+
+// Reconcile : makes the index hold exactly the provided recipes, the recipes whose text changed since they were indexed
+// are indexed again and the recipes that are not provided are removed. Returns the number of indexed and removed recipes
+func (di *DiskIndex) Reconcile(recipes []*models.Recipe) (int, int, error) {
+	docs := make(map[primitive.ObjectID]*diskDocument, len(recipes))
+	for _, recipe := range recipes {
+		docs[recipe.ID] = newDiskDocument(recipe)
+	}
+
+	di.mu.Lock()
+	defer di.mu.Unlock()
+
+	entries := make([]diskLogEntry, 0)
+	indexed, removed := 0, 0
+	for id, doc := range docs {
+		if current, ok := di.docs[id]; ok && current.Fingerprint == doc.Fingerprint {
+			continue
+		}
+		di.remove(id)
+		di.add(id, doc)
+		entries = append(entries, diskLogEntry{ID: id, Doc: doc})
+		indexed++
+	}
+
+	for id := range di.docs {
+		if _, ok := docs[id]; ok {
+			continue
+		}
+		di.remove(id)
+		entries = append(entries, diskLogEntry{ID: id})
+		removed++
+	}
+
+	if len(entries) == 0 {
+		return 0, 0, nil
+	}
+
+	return indexed, removed, di.commit(entries)
+}
+
+// Search : ranks the recipes that contain any of the terms of the query, a recipe that contains more of them ranks higher
+func (di *DiskIndex) Search(query string, limit int64) ([]Hit, error) {
+	di.mu.RLock()
+	defer di.mu.RUnlock()
+
+	total := float64(len(di.docs))
+	if total == 0 {
+		return []Hit{}, nil
+	}
+
+	var averageLengths [fieldCount]float64
+	for field := range averageLengths {
+		averageLengths[field] = math.Max(float64(di.lengths[field])/total, 1)
+	}
+
+	scores := make(map[primitive.ObjectID]float64)
+	for _, term := range Terms(query) {
+		matching := di.postings[term]
+		if len(matching) == 0 {
+			continue
+		}
+
+		n := float64(len(matching))
+		idf := math.Log(1 + (total-n+0.5)/(n+0.5))
+
+		for id := range matching {
+			doc := di.docs[id]
+
+			// BM25F: the length normalized frequencies of the fields are weighted and summed before saturating
+			var frequency float64
+			for field := 0; field < fieldCount; field++ {
+				tf := float64(doc.Terms[field][term])
+				if tf == 0 {
+					continue
+				}
+				norm := 1 - bm25B + bm25B*float64(doc.Lengths[field])/averageLengths[field]
+				frequency += fieldWeights[field] * tf / norm
+			}
+
+			scores[id] += idf * frequency / (bm25K1 + frequency)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID.Hex() > hits[j].ID.Hex()
+	})
+
+	if int64(len(hits)) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits, nil
+}
+
+// add : adds the document to the postings, the caller holds the lock
+func (di *DiskIndex) add(id primitive.ObjectID, doc *diskDocument) {
+	di.docs[id] = doc
+	for field := 0; field < fieldCount; field++ {
+		di.lengths[field] += doc.Lengths[field]
+		for term := range doc.Terms[field] {
+			if di.postings[term] == nil {
+				di.postings[term] = make(map[primitive.ObjectID]bool)
+			}
+			di.postings[term][id] = true
+		}
+	}
+}
+
+// remove : removes the document from the postings, the caller holds the lock
+func (di *DiskIndex) remove(id primitive.ObjectID) {
+	doc, ok := di.docs[id]
+	if !ok {
+		return
+	}
+
+	delete(di.docs, id)
+	for field := 0; field < fieldCount; field++ {
+		di.lengths[field] -= doc.Lengths[field]
+		for term := range doc.Terms[field] {
+			delete(di.postings[term], id)
+			if len(di.postings[term]) == 0 {
+				delete(di.postings, term)
+			}
+		}
+	}
+}
+
+// newDiskDocument : the term frequencies of the indexed fields of the recipe
+func newDiskDocument(recipe *models.Recipe) *diskDocument {
+	tags := strings.Join(recipe.Tags, " ")
+	ingredients := strings.Join(recipe.Ingredients, "\n")
+	instructions := strings.Join(recipe.Instructions, "\n")
+
+	doc := &diskDocument{}
+	doc.Terms[fieldName], doc.Lengths[fieldName] = termFrequencies(recipe.Name)
+	doc.Terms[fieldTags], doc.Lengths[fieldTags] = termFrequencies(tags)
+	doc.Terms[fieldIngredients], doc.Lengths[fieldIngredients] = termFrequencies(ingredients)
+	doc.Terms[fieldInstructions], doc.Lengths[fieldInstructions] = termFrequencies(instructions)
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{recipe.Name, tags, ingredients, instructions}, "\x00")))
+	doc.Fingerprint = hex.EncodeToString(sum[:])
+	return doc
+}
+
+// logPath : the log of the changes made since the snapshot was written
+func (di *DiskIndex) logPath() string {
+	return di.path + ".log"
+}
+
+// commit : appends the changes to the log and folds the log into the snapshot once it is large enough, the caller holds the lock
+func (di *DiskIndex) commit(entries []diskLogEntry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		// every entry has its own gob stream so that the log can be read back after the process restarts
+		var record bytes.Buffer
+		err := gob.NewEncoder(&record).Encode(entry)
+		if err != nil {
+			return err
+		}
+
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(record.Len()))
+		buf.Write(length[:])
+		buf.Write(record.Bytes())
+	}
+
+	_, err := di.log.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = di.log.Write(buf.Bytes())
+	}
+	if err == nil {
+		err = di.log.Sync()
+	}
+	if err != nil {
+		return err
+	}
+
+	di.logEntries += len(entries)
+	if di.logEntries < minCompactionLogEntries || di.logEntries < len(di.docs) {
+		return nil
+	}
+	return di.compact()
+}
+
+// compact : writes a new snapshot and empties the log, a crash in between only replays changes the snapshot already holds,
+// the caller holds the lock
+func (di *DiskIndex) compact() error {
+	err := di.saveSnapshot()
+	if err != nil {
+		return err
+	}
+
+	err = di.log.Truncate(0)
+	if err != nil {
+		return err
+	}
+
+	di.logEntries = 0
+	return di.log.Sync()
+}
+
+// loadSnapshot : adds the documents of the snapshot, there is no snapshot before the first compaction
+func (di *DiskIndex) loadSnapshot() error {
+	file, err := os.Open(di.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	docs := make(map[primitive.ObjectID]*diskDocument)
+	err = gob.NewDecoder(file).Decode(&docs)
+	if err != nil {
+		return err
+	}
+
+	for id, doc := range docs {
+		di.add(id, doc)
+	}
+	return nil
+}
+
+// replayLog : applies the changes of the log in order, an entry that was only partially written when the process stopped
+// is cut off together with everything after it
+func (di *DiskIndex) replayLog() error {
+	reader := bufio.NewReader(di.log)
+	var offset int64 // the end of the last complete entry
+	for {
+		var length [4]byte
+		_, err := io.ReadFull(reader, length[:])
+		if err == io.EOF {
+			return nil
+		}
+
+		var entry diskLogEntry
+		var record []byte
+		if err == nil && binary.BigEndian.Uint32(length[:]) <= maxLogEntrySize {
+			record = make([]byte, binary.BigEndian.Uint32(length[:]))
+			_, err = io.ReadFull(reader, record)
+			if err == nil {
+				err = gob.NewDecoder(bytes.NewReader(record)).Decode(&entry)
+			}
+		} else if err == nil {
+			err = errors.New("log entry too large")
+		}
+		if err != nil {
+			return di.log.Truncate(offset)
+		}
+		offset += int64(len(length) + len(record))
+
+		di.remove(entry.ID)
+		if entry.Doc != nil {
+			di.add(entry.ID, entry.Doc)
+		}
+		di.logEntries++
+	}
+}
+
+// saveSnapshot : writes the documents to a temporary file and moves it over the snapshot, so a crash never leaves a partial snapshot behind,
+// the caller holds the lock
+func (di *DiskIndex) saveSnapshot() error {
+	tmpPath := di.path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	err = gob.NewEncoder(file).Encode(di.docs)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, di.path)
+}
+
+// termFrequencies : how often every term occurs in the text and the number of terms of the text
+func termFrequencies(text string) (map[string]int, int) {
+	tokens := Analyze(text)
+	frequencies := make(map[string]int, len(tokens))
+	for _, token := range tokens {
+		frequencies[token.Term]++
+	}
+	return frequencies, len(tokens)
+}
@@ -0,0 +1,234 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func openTestIndex(t *testing.T, path string) *DiskIndex {
+	t.Helper()
+
+	index, err := OpenDiskIndex(path)
+	if err != nil {
+		t.Fatalf("OpenDiskIndex() error = %v", err)
+	}
+	t.Cleanup(func() { index.Close() })
+	return index
+}
+
+func indexRecipes(t *testing.T, index *DiskIndex, recipes ...*models.Recipe) {
+	t.Helper()
+
+	for _, recipe := range recipes {
+		if err := index.Index(recipe); err != nil {
+			t.Fatalf("Index() error = %v", err)
+		}
+	}
+}
+
+func searchIDs(t *testing.T, index *DiskIndex, query string) []primitive.ObjectID {
+	t.Helper()
+
+	hits, err := index.Search(query, 10)
+	if err != nil {
+		t.Fatalf("Search(%q) error = %v", query, err)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestDiskIndexRanksNameAboveInstructions(t *testing.T) {
+	index := openTestIndex(t, filepath.Join(t.TempDir(), "recipes.idx"))
+
+	inName := &models.Recipe{ID: primitive.NewObjectID(), Name: "Pumpkin soup", Instructions: []string{"Roast the squash and blend it."}}
+	inInstructions := &models.Recipe{ID: primitive.NewObjectID(), Name: "Squash soup", Instructions: []string{"Roast the pumpkin and blend it."}}
+	indexRecipes(t, index, inInstructions, inName)
+
+	ids := searchIDs(t, index, "pumpkin")
+	if len(ids) != 2 || ids[0] != inName.ID {
+		t.Fatalf("Search() = %v, want the recipe with pumpkin in its name first", ids)
+	}
+}
+
+func TestDiskIndexRanksRareTermsHigher(t *testing.T) {
+	index := openTestIndex(t, filepath.Join(t.TempDir(), "recipes.idx"))
+
+	saffron := &models.Recipe{ID: primitive.NewObjectID(), Name: "Saffron rice"}
+	indexRecipes(t, index,
+		&models.Recipe{ID: primitive.NewObjectID(), Name: "Fried rice with chicken"},
+		&models.Recipe{ID: primitive.NewObjectID(), Name: "Rice pudding with chicken"},
+		&models.Recipe{ID: primitive.NewObjectID(), Name: "Chicken rice bowl"},
+		saffron,
+	)
+
+	// every recipe has rice, only one has saffron, so saffron decides
+	ids := searchIDs(t, index, "saffron chicken")
+	if len(ids) != 4 || ids[0] != saffron.ID {
+		t.Fatalf("Search() = %v, want the recipe with the rare term first", ids)
+	}
+}
+
+func TestDiskIndexRanksMoreMatchingTermsHigher(t *testing.T) {
+	index := openTestIndex(t, filepath.Join(t.TempDir(), "recipes.idx"))
+
+	both := &models.Recipe{ID: primitive.NewObjectID(), Name: "Garlic butter mushrooms"}
+	indexRecipes(t, index,
+		&models.Recipe{ID: primitive.NewObjectID(), Name: "Garlic bread"},
+		both,
+		&models.Recipe{ID: primitive.NewObjectID(), Name: "Stuffed mushrooms"},
+	)
+
+	ids := searchIDs(t, index, "garlic mushrooms")
+	if len(ids) != 3 || ids[0] != both.ID {
+		t.Fatalf("Search() = %v, want the recipe matching both terms first", ids)
+	}
+}
+
+func TestDiskIndexMatchesStemmedTerms(t *testing.T) {
+	index := openTestIndex(t, filepath.Join(t.TempDir(), "recipes.idx"))
+
+	recipe := &models.Recipe{ID: primitive.NewObjectID(), Name: "Baked apples", Tags: []string{"dessert"}}
+	indexRecipes(t, index, recipe)
+
+	for _, query := range []string{"baking", "apple", "desserts"} {
+		ids := searchIDs(t, index, query)
+		if len(ids) != 1 || ids[0] != recipe.ID {
+			t.Errorf("Search(%q) = %v, want the recipe", query, ids)
+		}
+	}
+
+	if ids := searchIDs(t, index, "pear"); len(ids) != 0 {
+		t.Errorf("Search(%q) = %v, want no hits", "pear", ids)
+	}
+}
+
+func TestDiskIndexSurvivesReopening(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recipes.idx")
+	index := openTestIndex(t, path)
+
+	kept := &models.Recipe{ID: primitive.NewObjectID(), Name: "Lemon tart"}
+	deleted := &models.Recipe{ID: primitive.NewObjectID(), Name: "Lemon curd"}
+	indexRecipes(t, index, kept, deleted)
+
+	kept.Name = "Lemon meringue tart"
+	indexRecipes(t, index, kept)
+
+	if err := index.Delete(deleted.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	index.Close()
+
+	reopened := openTestIndex(t, path)
+	if reopened.Count() != 1 {
+		t.Fatalf("Count() = %d after reopening, want 1", reopened.Count())
+	}
+
+	if ids := searchIDs(t, reopened, "meringue"); len(ids) != 1 || ids[0] != kept.ID {
+		t.Fatalf("Search() = %v after reopening, want the updated recipe", ids)
+	}
+}
+
+func TestDiskIndexCompactsTheLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recipes.idx")
+	index := openTestIndex(t, path)
+
+	recipes := make([]*models.Recipe, 0, minCompactionLogEntries)
+	for i := 0; i < minCompactionLogEntries; i++ {
+		recipes = append(recipes, &models.Recipe{ID: primitive.NewObjectID(), Name: "Pancakes"})
+	}
+
+	if _, _, err := index.Reconcile(recipes); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	info, err := os.Stat(path + ".log")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size() != 0 {
+		t.Fatalf("log size = %d after compaction, want 0", info.Size())
+	}
+	index.Close()
+
+	reopened := openTestIndex(t, path)
+	if reopened.Count() != minCompactionLogEntries {
+		t.Fatalf("Count() = %d after reopening, want %d", reopened.Count(), minCompactionLogEntries)
+	}
+}
+
+func TestDiskIndexDropsAPartiallyWrittenLogEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recipes.idx")
+	index := openTestIndex(t, path)
+
+	recipe := &models.Recipe{ID: primitive.NewObjectID(), Name: "Focaccia"}
+	indexRecipes(t, index, recipe)
+	index.Close()
+
+	info, err := os.Stat(path + ".log")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+
+	// the process stopped while appending the next entry
+	file, err := os.OpenFile(path+".log", os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	file.Write([]byte{0, 0, 1, 0, 42, 42})
+	file.Close()
+
+	reopened := openTestIndex(t, path)
+	if ids := searchIDs(t, reopened, "focaccia"); len(ids) != 1 || ids[0] != recipe.ID {
+		t.Fatalf("Search() = %v, want the recipe of the complete entry", ids)
+	}
+
+	truncated, err := os.Stat(path + ".log")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if truncated.Size() != info.Size() {
+		t.Fatalf("log size = %d, want the partial entry cut off at %d", truncated.Size(), info.Size())
+	}
+}
+
+func TestDiskIndexReconcile(t *testing.T) {
+	index := openTestIndex(t, filepath.Join(t.TempDir(), "recipes.idx"))
+
+	unchanged := &models.Recipe{ID: primitive.NewObjectID(), Name: "Onion soup"}
+	changed := &models.Recipe{ID: primitive.NewObjectID(), Name: "Leek soup"}
+	removed := &models.Recipe{ID: primitive.NewObjectID(), Name: "Pea soup"}
+	indexRecipes(t, index, unchanged, changed, removed)
+
+	missed := &models.Recipe{ID: primitive.NewObjectID(), Name: "Carrot soup"}
+	changed.Name = "Leek and potato soup"
+
+	indexed, deleted, err := index.Reconcile([]*models.Recipe{unchanged, changed, missed})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	if indexed != 2 || deleted != 1 {
+		t.Fatalf("Reconcile() = %d indexed, %d removed, want 2 indexed, 1 removed", indexed, deleted)
+	}
+
+	if ids := searchIDs(t, index, "soup"); len(ids) != 3 {
+		t.Fatalf("Search() = %v, want the 3 reconciled recipes", ids)
+	}
+
+	if ids := searchIDs(t, index, "potato"); len(ids) != 1 || ids[0] != changed.ID {
+		t.Fatalf("Search() = %v, want the changed recipe", ids)
+	}
+
+	indexed, deleted, err = index.Reconcile([]*models.Recipe{unchanged, changed, missed})
+	if err != nil || indexed != 0 || deleted != 0 {
+		t.Fatalf("Reconcile() again = %d indexed, %d removed, error %v, want nothing to do", indexed, deleted, err)
+	}
+}
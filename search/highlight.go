@@ -0,0 +1,86 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	highlightOpen  string = "<mark>"
+	highlightClose string = "</mark>"
+	ellipsis       string = "…"
+)
+
+// Highlight : wraps the words of the text whose term is one of the terms in <mark> tags, the rest of the text is html escaped,
+// a text longer than maxLength bytes is cut down to a snippet around the first match,
+// it returns false if none of the words match
+func Highlight(text string, terms map[string]bool, maxLength int) (string, bool) {
+	matches := make([]Token, 0)
+	for _, token := range Analyze(text) {
+		if terms[token.Term] {
+			matches = append(matches, token)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	start, end := 0, len(text)
+	if maxLength > 0 && len(text) > maxLength {
+		// keep a bit of context before the first match
+		start = wordStart(text, matches[0].Start-maxLength/4)
+		end = wordEnd(text, start+maxLength)
+		if end < matches[0].End {
+			end = matches[0].End
+		}
+	}
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString(ellipsis)
+	}
+
+	position := start
+	for _, match := range matches {
+		if match.Start < start {
+			continue
+		}
+		if match.End > end {
+			break
+		}
+		snippet.WriteString(html.EscapeString(text[position:match.Start]))
+		snippet.WriteString(highlightOpen)
+		snippet.WriteString(html.EscapeString(text[match.Start:match.End]))
+		snippet.WriteString(highlightClose)
+		position = match.End
+	}
+	snippet.WriteString(html.EscapeString(text[position:end]))
+
+	if end < len(text) {
+		snippet.WriteString(ellipsis)
+	}
+
+	return snippet.String(), true
+}
+
+// wordStart : moves the offset back to the start of the word it is in
+func wordStart(text string, offset int) int {
+	if offset <= 0 {
+		return 0
+	}
+	for offset > 0 && text[offset-1] != ' ' {
+		offset--
+	}
+	return offset
+}
+
+// wordEnd : moves the offset forward to the end of the word it is in
+func wordEnd(text string, offset int) int {
+	if offset >= len(text) {
+		return len(text)
+	}
+	for offset < len(text) && text[offset] != ' ' {
+		offset++
+	}
+	return offset
+}
@@ -0,0 +1,40 @@
+package search
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		query     string
+		maxLength int
+		want      string
+		wantOK    bool
+	}{
+		{"marks every match", "Whisk the eggs, then add more eggs", "egg", 0, "Whisk the <mark>eggs</mark>, then add more <mark>eggs</mark>", true},
+		{"matches the stem of the word", "Bake until golden", "baking", 0, "<mark>Bake</mark> until golden", true},
+		{"no match", "Bake until golden", "fry", 0, "", false},
+		{"escapes html", "Salt & <b>pepper</b>", "pepper", 0, "Salt &amp; &lt;b&gt;<mark>pepper</mark>&lt;/b&gt;", true},
+		{"stop words are never marked", "Add the butter", "the butter", 0, "Add the <mark>butter</mark>", true},
+		{
+			"long text is cut down around the first match",
+			"Preheat the oven and line a baking sheet with parchment paper then spread the walnuts on it and toast them for eight minutes",
+			"walnut", 40,
+			"…spread the <mark>walnuts</mark> on it and toast them for…", true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms := make(map[string]bool)
+			for _, term := range Terms(tt.query) {
+				terms[term] = true
+			}
+
+			got, ok := Highlight(tt.text, terms, tt.maxLength)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("Highlight() = %q, %t, want %q, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
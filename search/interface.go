@@ -0,0 +1,27 @@
+// Package search holds the full-text search over the recipes
+package search
+
+import (
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Hit : a recipe that matches a search, a higher score is a better match
+type Hit struct {
+	ID    primitive.ObjectID
+	Score float64
+}
+
+// SearchIndex defines the methods of a full-text index of the public recipes,
+// it matches the name, the tags, the ingredients and the instructions of a recipe
+type SearchIndex interface {
+	Index(recipe *models.Recipe) error
+	Delete(documentObjectID primitive.ObjectID) error
+	Search(query string, limit int64) ([]Hit, error)
+}
+
+// Reconciler is implemented by the indexes that keep their own copy of the recipes,
+// they can drift from the database when a write to the index fails and are brought in line with Reconcile
+type Reconciler interface {
+	Reconcile(recipes []*models.Recipe) (int, int, error)
+}
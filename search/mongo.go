@@ -0,0 +1,65 @@
+package search
+
+import (
+	"context"
+
+	"github.com/skamranahmed/smilecook/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoIndex : returns a mongoIndex struct that implements the SearchIndex interface on top of the text index of the recipes collection
+func NewMongoIndex(ctx context.Context, collection *mongo.Collection) SearchIndex {
+	return &mongoIndex{
+		ctx:        ctx,
+		collection: collection,
+	}
+}
+
+type mongoIndex struct {
+	ctx        context.Context
+	collection *mongo.Collection
+}
+
+// Index : mongo keeps the text index up to date on its own
+func (mi *mongoIndex) Index(recipe *models.Recipe) error {
+	return nil
+}
+
+// Delete : mongo keeps the text index up to date on its own
+func (mi *mongoIndex) Delete(documentObjectID primitive.ObjectID) error {
+	return nil
+}
+
+// Search : runs a $text query, mongo stems the english words and ranks the recipes by its text score
+func (mi *mongoIndex) Search(query string, limit int64) ([]Hit, error) {
+	filter := bson.M{"$text": bson.M{"$search": query}, "isPrivate": false, "authorDeletedAt": nil}
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1, "score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: -1}}).
+		SetLimit(limit)
+
+	cur, err := mi.collection.Find(mi.ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(mi.ctx)
+
+	hits := make([]Hit, 0)
+	for cur.Next(mi.ctx) {
+		var result struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Score float64            `bson:"score"`
+		}
+		err := cur.Decode(&result)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, Hit{ID: result.ID, Score: result.Score})
+	}
+
+	return hits, cur.Err()
+}
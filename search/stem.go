@@ -0,0 +1,207 @@
+package search
+
+import "bytes"
+
+// Stem : reduces a lower case english word to its stem with the Porter stemming algorithm,
+// "baking", "baked" and "bakes" all become "bake"
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+
+	s := &stemmer{b: []byte(word)}
+	s.step1a()
+	s.step1b()
+	s.step1c()
+	s.replaceFirst(step2Rules, 0)
+	s.replaceFirst(step3Rules, 0)
+	s.step4()
+	s.step5()
+	return string(s.b)
+}
+
+var step2Rules = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"}, {"izer", "ize"},
+	{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"},
+	{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"},
+	{"fulness", "ful"}, {"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+	{"logi", "log"},
+}
+
+var step3Rules = [][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"}, {"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+// step4Suffixes : the longer suffixes come first, only the first suffix that matches is considered
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment", "ent",
+	"ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+type stemmer struct {
+	b []byte
+}
+
+// isConsonant : y is a consonant at the start of the word or after a vowel
+func (s *stemmer) isConsonant(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.isConsonant(i-1)
+	}
+	return true
+}
+
+// measure : the number of vowel-consonant sequences in b[:end]
+func (s *stemmer) measure(end int) int {
+	m, i := 0, 0
+	for i < end && s.isConsonant(i) {
+		i++
+	}
+	for i < end {
+		for i < end && !s.isConsonant(i) {
+			i++
+		}
+		if i >= end {
+			break
+		}
+		for i < end && s.isConsonant(i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+// hasVowel : reports whether b[:end] contains a vowel
+func (s *stemmer) hasVowel(end int) bool {
+	for i := 0; i < end; i++ {
+		if !s.isConsonant(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleConsonant : reports whether b[:end] ends with a double consonant
+func (s *stemmer) doubleConsonant(end int) bool {
+	return end >= 2 && s.b[end-1] == s.b[end-2] && s.isConsonant(end-1)
+}
+
+// cvc : reports whether b[:end] ends with consonant-vowel-consonant and the last consonant is not w, x or y
+func (s *stemmer) cvc(end int) bool {
+	if end < 3 || !s.isConsonant(end-3) || s.isConsonant(end-2) || !s.isConsonant(end-1) {
+		return false
+	}
+	last := s.b[end-1]
+	return last != 'w' && last != 'x' && last != 'y'
+}
+
+func (s *stemmer) hasSuffix(suffix string) bool {
+	return bytes.HasSuffix(s.b, []byte(suffix))
+}
+
+// stemLength : the length of the word without the suffix
+func (s *stemmer) stemLength(suffix string) int {
+	return len(s.b) - len(suffix)
+}
+
+func (s *stemmer) replace(suffix string, replacement string) {
+	s.b = append(s.b[:s.stemLength(suffix)], replacement...)
+}
+
+// replaceFirst : finds the first rule whose suffix matches and replaces it if the measure of the remaining stem is above the minimum
+func (s *stemmer) replaceFirst(rules [][2]string, minMeasure int) {
+	for _, rule := range rules {
+		if s.hasSuffix(rule[0]) {
+			if s.measure(s.stemLength(rule[0])) > minMeasure {
+				s.replace(rule[0], rule[1])
+			}
+			return
+		}
+	}
+}
+
+// step1a : plurals
+func (s *stemmer) step1a() {
+	switch {
+	case s.hasSuffix("sses"):
+		s.replace("sses", "ss")
+	case s.hasSuffix("ies"):
+		s.replace("ies", "i")
+	case s.hasSuffix("ss"):
+	case s.hasSuffix("s"):
+		s.replace("s", "")
+	}
+}
+
+// step1b : past participles and gerunds
+func (s *stemmer) step1b() {
+	if s.hasSuffix("eed") {
+		if s.measure(s.stemLength("eed")) > 0 {
+			s.replace("eed", "ee")
+		}
+		return
+	}
+
+	for _, suffix := range []string{"ed", "ing"} {
+		if !s.hasSuffix(suffix) || !s.hasVowel(s.stemLength(suffix)) {
+			continue
+		}
+
+		s.replace(suffix, "")
+		end := len(s.b)
+		switch {
+		case s.hasSuffix("at"), s.hasSuffix("bl"), s.hasSuffix("iz"):
+			s.b = append(s.b, 'e')
+		case s.doubleConsonant(end) && s.b[end-1] != 'l' && s.b[end-1] != 's' && s.b[end-1] != 'z':
+			s.b = s.b[:end-1]
+		case s.measure(end) == 1 && s.cvc(end):
+			s.b = append(s.b, 'e')
+		}
+		return
+	}
+}
+
+// step1c : a terminal y becomes i when there is another vowel in the stem
+func (s *stemmer) step1c() {
+	if s.hasSuffix("y") && s.hasVowel(len(s.b)-1) {
+		s.b[len(s.b)-1] = 'i'
+	}
+}
+
+// step4 : drops the remaining suffixes of long enough stems
+func (s *stemmer) step4() {
+	for _, suffix := range step4Suffixes {
+		if !s.hasSuffix(suffix) {
+			continue
+		}
+
+		end := s.stemLength(suffix)
+		if s.measure(end) <= 1 {
+			return
+		}
+		if suffix == "ion" && (end == 0 || (s.b[end-1] != 's' && s.b[end-1] != 't')) {
+			return
+		}
+		s.b = s.b[:end]
+		return
+	}
+}
+
+// step5 : tidies up a final e and a final double l
+func (s *stemmer) step5() {
+	if s.hasSuffix("e") {
+		end := len(s.b) - 1
+		m := s.measure(end)
+		if m > 1 || (m == 1 && !s.cvc(end)) {
+			s.b = s.b[:end]
+		}
+	}
+
+	end := len(s.b)
+	if s.b[end-1] == 'l' && s.doubleConsonant(end) && s.measure(end) > 1 {
+		s.b = s.b[:end-1]
+	}
+}
@@ -0,0 +1,63 @@
+package search
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		// step 1a, plurals
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"ties", "ti"},
+		{"caress", "caress"},
+		{"cats", "cat"},
+
+		// step 1b, past participles and gerunds
+		{"feed", "feed"},
+		{"agreed", "agre"},
+		{"plastered", "plaster"},
+		{"bled", "bled"},
+		{"motoring", "motor"},
+		{"sing", "sing"},
+		{"conflated", "conflat"},
+		{"troubled", "troubl"},
+		{"sized", "size"},
+		{"hopping", "hop"},
+		{"tanned", "tan"},
+		{"falling", "fall"},
+		{"hissing", "hiss"},
+		{"fizzed", "fizz"},
+		{"failing", "fail"},
+		{"filing", "file"},
+
+		// step 1c, a terminal y
+		{"happy", "happi"},
+		{"sky", "sky"},
+
+		// step 5, a final e and a final double l
+		{"probate", "probat"},
+		{"rate", "rate"},
+		{"cease", "ceas"},
+		{"controll", "control"},
+		{"roll", "roll"},
+
+		// the forms of a word used in recipes share a stem
+		{"baking", "bake"},
+		{"baked", "bake"},
+		{"bakes", "bake"},
+		{"tomatoes", "tomato"},
+		{"tomato", "tomato"},
+
+		// short words are left alone
+		{"a", "a"},
+		{"is", "is"},
+	}
+
+	for _, tt := range tests {
+		if got := Stem(tt.word); got != tt.want {
+			t.Errorf("Stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package search
+
+// trie : a prefix tree over the keys of the autocomplete entries, a key can hold several entries
+type trie struct {
+	root *trieNode
+}
+
+type trieNode struct {
+	children map[rune]*trieNode
+	entries  []int // indexes of the entries whose key ends here
+}
+
+// trieMatch : an entry found in the trie and the edit distance between the looked up text and its key
+type trieMatch struct {
+	entry    int
+	distance int
+}
+
+func newTrie() *trie {
+	return &trie{root: &trieNode{}}
+}
+
+// insert : adds the entry under the key
+func (t *trie) insert(key string, entry int) {
+	node := t.root
+	for _, r := range key {
+		if node.children == nil {
+			node.children = make(map[rune]*trieNode)
+		}
+		child, ok := node.children[r]
+		if !ok {
+			child = &trieNode{}
+			node.children[r] = child
+		}
+		node = child
+	}
+	node.entries = append(node.entries, entry)
+}
+
+// find : the node of the key, nil if no key starts with it
+func (t *trie) find(key string) *trieNode {
+	node := t.root
+	for _, r := range key {
+		node = node.children[r]
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// contains : reports whether the key has been inserted
+func (t *trie) contains(key string) bool {
+	node := t.find(key)
+	return node != nil && len(node.entries) > 0
+}
+
+// withPrefix : every entry whose key starts with the prefix
+func (t *trie) withPrefix(prefix string) []trieMatch {
+	matches := make([]trieMatch, 0)
+	node := t.find(prefix)
+	if node != nil {
+		node.collect(0, &matches)
+	}
+	return matches
+}
+
+// fuzzy : the entries whose key is within maxDistance edits of the text, or whose key starts with such a prefix when prefix is true,
+// it walks the trie with a row of the Levenshtein matrix per node and prunes the branches that cannot get close enough
+func (t *trie) fuzzy(text string, maxDistance int, prefix bool) []trieMatch {
+	target := []rune(text)
+	row := make([]int, len(target)+1)
+	for i := range row {
+		row[i] = i
+	}
+
+	matches := make([]trieMatch, 0)
+	for r, child := range t.root.children {
+		child.fuzzy(r, target, row, maxDistance, prefix, -1, &matches)
+	}
+	return matches
+}
+
+// fuzzy : found is the smallest distance between the text and a prefix of the path so far, -1 if there is none within maxDistance,
+// in prefix mode every key below such a prefix is a suggestion at that distance
+func (n *trieNode) fuzzy(r rune, target []rune, previous []int, maxDistance int, prefix bool, found int, matches *[]trieMatch) {
+	row := make([]int, len(previous))
+	row[0] = previous[0] + 1
+	best := row[0]
+	for i := 1; i < len(row); i++ {
+		cost := 1
+		if target[i-1] == r {
+			cost = 0
+		}
+		row[i] = min3(row[i-1]+1, previous[i]+1, previous[i-1]+cost)
+		if row[i] < best {
+			best = row[i]
+		}
+	}
+
+	distance := row[len(row)-1]
+	if !prefix {
+		if distance <= maxDistance {
+			for _, entry := range n.entries {
+				*matches = append(*matches, trieMatch{entry: entry, distance: distance})
+			}
+		}
+	} else {
+		if distance <= maxDistance && (found < 0 || distance < found) {
+			found = distance
+		}
+
+		if found >= 0 {
+			// no longer path gets closer than the smallest value of the row, the keys below are suggestions at the distance found
+			if best >= found {
+				n.collect(found, matches)
+				return
+			}
+			for _, entry := range n.entries {
+				*matches = append(*matches, trieMatch{entry: entry, distance: found})
+			}
+		}
+	}
+
+	if best > maxDistance {
+		return
+	}
+
+	for childRune, child := range n.children {
+		child.fuzzy(childRune, target, row, maxDistance, prefix, found, matches)
+	}
+}
+
+// collect : appends the entries of the node and of all the nodes below it
+func (n *trieNode) collect(distance int, matches *[]trieMatch) {
+	for _, entry := range n.entries {
+		*matches = append(*matches, trieMatch{entry: entry, distance: distance})
+	}
+	for _, child := range n.children {
+		child.collect(distance, matches)
+	}
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"sort"
+	"testing"
+)
+
+var trieTestWords = []string{"tomato", "tomatoes", "potato", "pasta", "pastry", "paste", "basil", "bagel", "cake", "cakes", "kale"}
+
+func newTestTrie() *trie {
+	t := newTrie()
+	for i, word := range trieTestWords {
+		t.insert(word, i)
+	}
+	return t
+}
+
+// levenshtein : the edit distance computed with the whole matrix, the trie walk must agree with it
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		row := make([]int, len(rb)+1)
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			row[j] = min3(row[j-1]+1, previous[j]+1, previous[j-1]+cost)
+		}
+		previous = row
+	}
+	return previous[len(rb)]
+}
+
+// matchedWords : the words of the matches with their distance, sorted
+func matchedWords(matches []trieMatch) map[string]int {
+	words := make(map[string]int)
+	for _, match := range matches {
+		word := trieTestWords[match.entry]
+		if distance, ok := words[word]; !ok || match.distance < distance {
+			words[word] = match.distance
+		}
+	}
+	return words
+}
+
+func TestTrieContainsAndPrefix(t *testing.T) {
+	tr := newTestTrie()
+
+	if !tr.contains("pasta") || tr.contains("past") || tr.contains("pastas") {
+		t.Fatalf("contains() only reports the inserted keys")
+	}
+
+	got := make([]string, 0)
+	for word := range matchedWords(tr.withPrefix("past")) {
+		got = append(got, word)
+	}
+	sort.Strings(got)
+
+	want := []string{"pasta", "paste", "pastry"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("withPrefix(%q) = %v, want %v", "past", got, want)
+	}
+
+	if matches := tr.withPrefix("xyz"); len(matches) != 0 {
+		t.Fatalf("withPrefix(%q) = %v, want no matches", "xyz", matches)
+	}
+}
+
+func TestTrieFuzzyAgreesWithLevenshtein(t *testing.T) {
+	tr := newTestTrie()
+
+	for _, text := range []string{"tomatoe", "potatto", "pasty", "basel", "cak", "kael", "bagle", "zzz"} {
+		for maxDistance := 0; maxDistance <= 2; maxDistance++ {
+			got := matchedWords(tr.fuzzy(text, maxDistance, false))
+
+			for _, word := range trieTestWords {
+				distance := levenshtein(text, word)
+				gotDistance, found := got[word]
+				if distance <= maxDistance && (!found || gotDistance != distance) {
+					t.Errorf("fuzzy(%q, %d) misses %q at distance %d, got %v", text, maxDistance, word, distance, got)
+				}
+				if distance > maxDistance && found {
+					t.Errorf("fuzzy(%q, %d) finds %q at distance %d", text, maxDistance, word, distance)
+				}
+			}
+		}
+	}
+}
+
+func TestTrieFuzzyPrefix(t *testing.T) {
+	tr := newTestTrie()
+
+	// "tomst" is one substitution away from "tomat", every key below it is a suggestion
+	got := matchedWords(tr.fuzzy("tomst", 1, true))
+	if len(got) != 2 || got["tomato"] != 1 || got["tomatoes"] != 1 {
+		t.Fatalf("fuzzy(%q, 1, prefix) = %v, want tomato and tomatoes at distance 1", "tomst", got)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"crème", "creme", 1},
+		{"same", "same", 0},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
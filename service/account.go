@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/repository"
+	"github.com/skamranahmed/smilecook/search"
+	"github.com/skamranahmed/smilecook/validation"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// recipesCacheKeys : the keys the RecipesHandler caches the whole list of recipes under, the filtered lists and the pages
+// are dropped by bumping recipesQueriesGenerationKey
+var recipesCacheKeys = []string{"recipes", "recipes:queries"}
+
+const recipesQueriesGenerationKey string = "recipes:queries:generation"
+
+// ErrUsernameTaken : another user, possibly a deleted one that has not been purged yet, has the username
+var ErrUsernameTaken = errors.New("username already exists")
+
+// NewAccountService : returns an accountService struct that implements the AccountService interface
+func NewAccountService(ctx context.Context, redisClient *redis.Client, userService UserService, userRepo repository.UserRepository, recipeRepo repository.RecipeRepository, refreshTokenRepo repository.RefreshTokenRepository, apiKeyRepo repository.APIKeyRepository, passwordResetTokenRepo repository.PasswordResetTokenRepository, revocationService TokenRevocationService, searchIndex search.SearchIndex, autocompleteService AutocompleteService, gracePeriod time.Duration) AccountService {
+	return &accountService{
+		ctx:                    ctx,
+		redisClient:            redisClient,
+		userService:            userService,
+		userRepo:               userRepo,
+		recipeRepo:             recipeRepo,
+		refreshTokenRepo:       refreshTokenRepo,
+		apiKeyRepo:             apiKeyRepo,
+		passwordResetTokenRepo: passwordResetTokenRepo,
+		revocationService:      revocationService,
+		searchIndex:            searchIndex,
+		autocompleteService:    autocompleteService,
+		gracePeriod:            gracePeriod,
+	}
+}
+
+type accountService struct {
+	ctx                    context.Context
+	redisClient            *redis.Client
+	userService            UserService
+	userRepo               repository.UserRepository
+	recipeRepo             repository.RecipeRepository
+	refreshTokenRepo       repository.RefreshTokenRepository
+	apiKeyRepo             repository.APIKeyRepository
+	passwordResetTokenRepo repository.PasswordResetTokenRepository
+	revocationService      TokenRevocationService
+	searchIndex            search.SearchIndex
+	autocompleteService    AutocompleteService
+	gracePeriod            time.Duration
+}
+
+// ChangePassword : replaces the password after checking the current one and signs the user out everywhere
+func (as *accountService) ChangePassword(u *models.User, currentPlainTextPassword string, newPlainTextPassword string) error {
+	err := as.userService.VerifyPassword(currentPlainTextPassword, u.Password)
+	if err != nil {
+		return err
+	}
+
+	err = as.userService.ValidatePassword(newPlainTextPassword, u.Username, u.Email)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := as.userService.HashPassword(newPlainTextPassword)
+	if err != nil {
+		return err
+	}
+
+	updated, err := as.userRepo.UpdatePassword(u.Username, hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return errors.New("user not found")
+	}
+
+	return as.revokeSessions(u.ID)
+}
+
+// ChangeUsername : renames the user and updates the username shown on the recipes, the refresh tokens and the api keys,
+// the access tokens carry the old username so every session of the user is revoked. The rename is recorded on the user record in the same write,
+// if copying it to the other records fails midway it is completed by CompletePendingRenames
+func (as *accountService) ChangeUsername(u *models.User, newUsername string) error {
+	err := validation.ValidateUsername(newUsername).Err()
+	if err != nil {
+		return err
+	}
+
+	// a previous rename has to be complete before the user can be renamed again
+	if u.PendingRename != nil {
+		err = as.completeRename(u.ID, *u.PendingRename)
+		if err != nil {
+			return err
+		}
+	}
+
+	renamed, err := as.userRepo.UpdateUsername(u.Username, newUsername)
+	if err != nil {
+		return err
+	}
+
+	if !renamed {
+		return ErrUsernameTaken
+	}
+
+	return as.completeRename(u.ID, models.UsernameChange{From: u.Username, To: newUsername})
+}
+
+// CompletePendingRenames : completes the renames that failed midway, returns the number of completed renames
+func (as *accountService) CompletePendingRenames() (int, error) {
+	users, err := as.userRepo.FetchPendingRenames()
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, u := range users {
+		err = as.completeRename(u.ID, *u.PendingRename)
+		if err != nil {
+			return completed, err
+		}
+		completed++
+	}
+
+	return completed, nil
+}
+
+// completeRename : copies the new username to the other records of the user and moves the redis state kept under the old username,
+// every step can be run again so a failed rename is completed by running it again
+func (as *accountService) completeRename(userID primitive.ObjectID, change models.UsernameChange) error {
+	_, err := as.recipeRepo.UpdateUsername(userID, change.To)
+	if err != nil {
+		return err
+	}
+
+	err = as.apiKeyRepo.UpdateUsername(userID, change.To)
+	if err != nil {
+		return err
+	}
+
+	err = as.refreshTokenRepo.UpdateUsername(userID, change.To)
+	if err != nil {
+		return err
+	}
+
+	// the password reset tokens point at the old username, which another user may take
+	err = as.passwordResetTokenRepo.MarkAllUsedForUser(userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = as.moveUsernameKeys(change.From, change.To)
+	if err != nil {
+		return err
+	}
+
+	as.invalidateRecipeListings()
+
+	err = as.revokeSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	return as.userRepo.ClearPendingRename(userID, change)
+}
+
+// moveUsernameKeysScript : moves each key of the first half of KEYS to the key of the second half at the same position,
+// with its type and its ttl, a missing key is skipped
+var moveUsernameKeysScript = redis.NewScript(`
+local count = #KEYS / 2
+for i = 1, count do
+	local dump = redis.call("DUMP", KEYS[i])
+	if dump then
+		local ttl = redis.call("PTTL", KEYS[i])
+		if ttl < 0 then
+			ttl = 0
+		end
+		redis.call("RESTORE", KEYS[count + i], ttl, dump, "REPLACE")
+		redis.call("DEL", KEYS[i])
+	end
+end
+return count
+`)
+
+// usernameKeys : the redis keys that hold the state of a user under their username and follow them when they are renamed,
+// the sign in throttling and the last used totp counter
+func usernameKeys(username string) []string {
+	subject := loginUserSubject(username)
+	return []string{
+		loginFailuresKeyPrefix + subject,
+		loginBackoffKeyPrefix + subject,
+		loginLockKeyPrefix + subject,
+		usedTOTPCounterKeyPrefix + username,
+	}
+}
+
+// moveUsernameKeys : moves the redis state of the user to the new username so that a rename neither resets the sign in throttling
+// nor lets a totp code be used again
+func (as *accountService) moveUsernameKeys(oldUsername string, newUsername string) error {
+	keys := append(usernameKeys(oldUsername), usernameKeys(newUsername)...)
+	return moveUsernameKeysScript.Run(as.ctx, as.redisClient, keys).Err()
+}
+
+// Delete : soft deletes the user, the account is purged once the grace period is over, returns the purge time
+// users provisioned through an external provider have no password and are not asked for one
+func (as *accountService) Delete(u *models.User, plainTextPassword string) (time.Time, error) {
+	if u.Password != "" {
+		err := as.userService.VerifyPassword(plainTextPassword, u.Password)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	now := time.Now()
+	deleted, err := as.userRepo.SoftDelete(u.Username, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !deleted {
+		return time.Time{}, errors.New("user not found")
+	}
+
+	err = as.apiKeyRepo.RevokeAllForUser(u.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	err = as.revokeSessions(u.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	err = as.hideRecipes(u.ID, &now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return now.Add(as.gracePeriod), nil
+}
+
+// Restore : lifts the soft delete of a user whose account has not been purged yet and lists their recipes again,
+// the recipes come back first so that a failed restore can be run again
+func (as *accountService) Restore(username string) (bool, error) {
+	u, err := as.userRepo.FindOneDeleted(username)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, err
+	}
+
+	err = as.hideRecipes(u.ID, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return as.userRepo.Restore(username)
+}
+
+// hideRecipes : hides the recipes of a deleted user from the lists, the search and the autocomplete until the account is purged,
+// or lists them again when deletedAt is nil
+func (as *accountService) hideRecipes(userID primitive.ObjectID, deletedAt *time.Time) error {
+	_, err := as.recipeRepo.SetAuthorDeleted(userID, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	recipes, err := as.recipeRepo.FetchAllByAuthorID(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, recipe := range recipes {
+		if recipe.IsListed() {
+			err = as.searchIndex.Index(recipe)
+		} else {
+			err = as.searchIndex.Delete(recipe.ID)
+		}
+		if err != nil {
+			log.Printf("unable to update the search index for recipe %s, err: %v\n", recipe.ID.Hex(), err)
+		}
+	}
+
+	as.invalidateRecipeListings()
+	return nil
+}
+
+// PurgeDeleted : permanently deletes the users whose grace period is over along with their recipes,
+// refresh tokens and api keys, returns the number of purged users
+func (as *accountService) PurgeDeleted() (int, error) {
+	users, err := as.userRepo.FetchDeletedBefore(time.Now().Add(-as.gracePeriod))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, u := range users {
+		// the user record goes last, a failed purge is picked up again by the next run
+		recipes, err := as.recipeRepo.FetchAllByAuthorID(u.ID)
+		if err != nil {
+			return purged, err
+		}
+
+		_, err = as.recipeRepo.DeleteAllByAuthorID(u.ID)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, recipe := range recipes {
+			err = as.searchIndex.Delete(recipe.ID)
+			if err != nil {
+				log.Printf("unable to remove recipe %s from the search index, err: %v\n", recipe.ID.Hex(), err)
+			}
+		}
+
+		err = as.apiKeyRepo.DeleteAllForUser(u.ID)
+		if err != nil {
+			return purged, err
+		}
+
+		err = as.refreshTokenRepo.DeleteAllForUser(u.ID)
+		if err != nil {
+			return purged, err
+		}
+
+		_, err = as.userRepo.Delete(u.ID)
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		as.invalidateRecipeListings()
+	}
+
+	return purged, nil
+}
+
+// revokeSessions : revokes every refresh token and every access token of the user
+func (as *accountService) revokeSessions(userID primitive.ObjectID) error {
+	err := as.refreshTokenRepo.RevokeAllForUser(userID)
+	if err != nil {
+		return err
+	}
+
+	return as.revocationService.RevokeAllForUser(userID)
+}
+
+// invalidateRecipeListings : drops the cached lists and pages of recipes and marks the autocomplete entries as stale
+// after the recipes of a user changed hands, were hidden or went away
+func (as *accountService) invalidateRecipeListings() {
+	as.invalidateRecipesCache()
+
+	err := as.autocompleteService.Invalidate()
+	if err != nil {
+		log.Printf("unable to invalidate the autocomplete entries, err: %v\n", err)
+	}
+}
+
+// invalidateRecipesCache : drops the cached lists and pages of recipes, it holds the usernames of the owners
+func (as *accountService) invalidateRecipesCache() {
+	err := as.redisClient.Del(as.ctx, recipesCacheKeys...).Err()
+	if err == nil {
+		err = as.redisClient.Incr(as.ctx, recipesQueriesGenerationKey).Err()
+	}
+	if err != nil {
+		log.Printf("unable to invalidate the recipes cache, err: %v\n", err)
+	}
+}
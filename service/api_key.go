@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// apiKeyPrefix : makes the keys easy to recognize, e.g. by secret scanners
+	apiKeyPrefix string = "sck_"
+
+	// apiKeyIdentifierLength : number of characters after apiKeyPrefix that are kept in plain text to identify the key
+	apiKeyIdentifierLength int = 8
+
+	// the last used timestamp is only written once per interval, to avoid a write on every request
+	apiKeyLastUsedInterval time.Duration = time.Minute
+)
+
+// ErrInvalidAPIKey : the api key is unknown, expired or revoked
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// NewAPIKeyService : returns an apiKeyService struct that implements the APIKeyService interface
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+}
+
+// Create : creates a new api key for the user, the plain text key is only ever returned here
+func (aks *apiKeyService) Create(u *models.User, name string, scopes []string, expiresAt *time.Time) (string, *models.APIKey, error) {
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	plainTextKey := apiKeyPrefix + secret
+	apiKey := &models.APIKey{
+		ID:        primitive.NewObjectID(),
+		UserID:    u.ID,
+		Username:  u.Username,
+		Name:      name,
+		Prefix:    plainTextKey[:len(apiKeyPrefix)+apiKeyIdentifierLength],
+		KeyHash:   hashOpaqueToken(plainTextKey),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+
+	err = aks.apiKeyRepo.Create(apiKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return plainTextKey, apiKey, nil
+}
+
+// FetchAllByUserID : fetches every api key of the user
+func (aks *apiKeyService) FetchAllByUserID(userID primitive.ObjectID) ([]*models.APIKey, error) {
+	return aks.apiKeyRepo.FetchAllByUserID(userID)
+}
+
+// Revoke : revokes an api key of the user
+func (aks *apiKeyService) Revoke(documentObjectID primitive.ObjectID, userID primitive.ObjectID) (bool, error) {
+	return aks.apiKeyRepo.Revoke(documentObjectID, userID)
+}
+
+// Authenticate : resolves a plain text api key and records its usage
+func (aks *apiKeyService) Authenticate(plainTextKey string) (*models.APIKey, error) {
+	if !strings.HasPrefix(plainTextKey, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := aks.apiKeyRepo.FindOneByHash(hashOpaqueToken(plainTextKey))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyLastUsedInterval {
+		err = aks.apiKeyRepo.UpdateLastUsed(apiKey.ID, now)
+		if err != nil {
+			// not being able to record the usage must not break the request
+			log.Printf("unable to update last used timestamp of api key: %s, err: %v\n", apiKey.ID.Hex(), err)
+		} else {
+			apiKey.LastUsedAt = &now
+		}
+	}
+
+	return apiKey, nil
+}
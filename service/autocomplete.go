@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/skamranahmed/smilecook/repository"
+	"github.com/skamranahmed/smilecook/search"
+)
+
+const (
+	autocompleteEntriesKey string = "autocomplete:entries"
+
+	// autocompleteStaleKey : set when the recipes changed, the entries are still served until one instance has rebuilt them
+	autocompleteStaleKey string = "autocomplete:stale"
+
+	// autocompleteRebuildLockKey : held by the instance that rebuilds the entries, so that a change does not make every instance
+	// fetch all the recipes at once
+	autocompleteRebuildLockKey string        = "autocomplete:rebuild_lock"
+	autocompleteRebuildLockTTL time.Duration = 30 * time.Second
+
+	// autocompleteRefreshInterval : an instance reloads the entries from redis at most once per interval,
+	// so a change made through another instance shows up within the interval
+	autocompleteRefreshInterval time.Duration = time.Minute
+)
+
+// releaseLockScript : deletes the lock only if it still holds the token of the caller, a rebuild that outlived the ttl
+// must not release the lock another instance has taken since
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AutocompleteResult : the suggestions for a typed text, DidYouMean is set when the text has misspelled words
+type AutocompleteResult struct {
+	Query       string              `json:"query"`
+	Suggestions []search.Suggestion `json:"suggestions"`
+	DidYouMean  string              `json:"did_you_mean,omitempty"`
+}
+
+// NewAutocompleteService : returns an autocompleteService struct that implements the AutocompleteService interface
+func NewAutocompleteService(ctx context.Context, redisClient *redis.Client, recipeRepo repository.RecipeRepository, cacheTTL time.Duration) AutocompleteService {
+	return &autocompleteService{
+		ctx:         ctx,
+		redisClient: redisClient,
+		recipeRepo:  recipeRepo,
+		cacheTTL:    cacheTTL,
+	}
+}
+
+type autocompleteService struct {
+	ctx         context.Context
+	redisClient *redis.Client
+	recipeRepo  repository.RecipeRepository
+	cacheTTL    time.Duration
+
+	mu            sync.RWMutex
+	autocompleter *search.Autocompleter
+	loadedAt      time.Time
+
+	// loadMu : only one request of this instance reloads the entries, the others wait for it
+	loadMu sync.Mutex
+}
+
+// Suggest : the most popular recipe names, tags and ingredients that complete the text, typos included
+func (as *autocompleteService) Suggest(text string, limit int) (*AutocompleteResult, error) {
+	autocompleter, err := as.load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AutocompleteResult{
+		Query:       text,
+		Suggestions: autocompleter.Complete(text, limit),
+	}
+	if corrected, ok := autocompleter.Correct(text); ok {
+		result.DidYouMean = corrected
+	}
+
+	return result, nil
+}
+
+// Invalidate : marks the entries as stale, they are rebuilt from the recipes by a single instance on the next suggestion
+// and served as they are until then
+func (as *autocompleteService) Invalidate() error {
+	as.mu.Lock()
+	as.loadedAt = time.Time{}
+	as.mu.Unlock()
+
+	return as.redisClient.Set(as.ctx, autocompleteStaleKey, 1, as.cacheTTL).Err()
+}
+
+// load : the autocompleter of this instance, it is reloaded from the entries cached in redis once it is older than the refresh interval,
+// and the entries are rebuilt from the recipes collection when redis does not have them or they are stale
+func (as *autocompleteService) load() (*search.Autocompleter, error) {
+	autocompleter, fresh := as.current()
+	if fresh {
+		return autocompleter, nil
+	}
+
+	as.loadMu.Lock()
+	defer as.loadMu.Unlock()
+
+	// another request may have reloaded the entries while this one was waiting
+	autocompleter, fresh = as.current()
+	if fresh {
+		return autocompleter, nil
+	}
+
+	entries, err := as.cachedEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	autocompleter = search.NewAutocompleter(entries)
+
+	// without entries another instance is still building them, they are looked up again on the next suggestion
+	as.mu.Lock()
+	as.autocompleter = autocompleter
+	if len(entries) > 0 {
+		as.loadedAt = time.Now()
+	}
+	as.mu.Unlock()
+
+	return autocompleter, nil
+}
+
+// current : the autocompleter of this instance and whether it is recent enough to be used without reloading
+func (as *autocompleteService) current() (*search.Autocompleter, bool) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	return as.autocompleter, as.autocompleter != nil && time.Since(as.loadedAt) < autocompleteRefreshInterval
+}
+
+// cachedEntries : the entries cached in redis, they are built from the public recipes if they are not cached or stale,
+// by the instance that gets the rebuild lock. The other instances keep using the stale entries meanwhile,
+// or no entries at all if there are none yet
+func (as *autocompleteService) cachedEntries() ([]search.Entry, error) {
+	var entries []search.Entry
+	val, err := as.redisClient.Get(as.ctx, autocompleteEntriesKey).Result()
+	if err == nil {
+		err = json.Unmarshal([]byte(val), &entries)
+		if err != nil {
+			log.Printf("unable to decode the cached autocomplete entries, err: %v, rebuilding them\n", err)
+			entries = nil
+		}
+	} else if err != redis.Nil {
+		log.Printf("error in retrieving the autocomplete entries from redis, err: %v, rebuilding them\n", err)
+	}
+
+	stale, err := as.redisClient.Exists(as.ctx, autocompleteStaleKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if entries != nil && stale == 0 {
+		return entries, nil
+	}
+
+	lockToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := as.redisClient.SetNX(as.ctx, autocompleteRebuildLockKey, lockToken, autocompleteRebuildLockTTL).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if !locked {
+		if entries == nil {
+			entries = []search.Entry{}
+		}
+		return entries, nil
+	}
+	defer as.releaseRebuildLock(lockToken)
+
+	// a change made while rebuilding marks the entries stale again
+	err = as.redisClient.Del(as.ctx, autocompleteStaleKey).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	recipes, err := as.recipeRepo.FetchAll(repository.RecipeQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	entries = search.BuildEntries(recipes)
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		log.Printf("unable to encode the autocomplete entries, err: %v\n", err)
+		return entries, nil
+	}
+
+	err = as.redisClient.Set(as.ctx, autocompleteEntriesKey, string(data), as.cacheTTL).Err()
+	if err != nil {
+		log.Printf("unable to cache the autocomplete entries in redis, err: %v\n", err)
+	}
+
+	return entries, nil
+}
+
+// releaseRebuildLock : releases the rebuild lock if this instance still holds it
+func (as *autocompleteService) releaseRebuildLock(lockToken string) {
+	err := releaseLockScript.Run(as.ctx, as.redisClient, []string{autocompleteRebuildLockKey}, lockToken).Err()
+	if err != nil && err != redis.Nil {
+		log.Printf("unable to release the autocomplete rebuild lock, err: %v\n", err)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/skamranahmed/smilecook/mailer"
+	"github.com/skamranahmed/smilecook/models"
+)
+
+const emailVerificationKeyPrefix string = "email_verification:"
+
+// ErrInvalidEmailVerificationToken : the email verification token is unknown, expired, already used or the email has changed since
+var ErrInvalidEmailVerificationToken = errors.New("invalid or expired email verification token")
+
+// NewEmailVerificationService : returns an emailVerificationService struct that implements the EmailVerificationService interface
+func NewEmailVerificationService(ctx context.Context, redisClient *redis.Client, userService UserService, mailer mailer.Mailer, baseURL string, ttl time.Duration) EmailVerificationService {
+	return &emailVerificationService{
+		ctx:         ctx,
+		redisClient: redisClient,
+		userService: userService,
+		mailer:      mailer,
+		baseURL:     baseURL,
+		ttl:         ttl,
+	}
+}
+
+type emailVerificationService struct {
+	ctx         context.Context
+	redisClient *redis.Client
+	userService UserService
+	mailer      mailer.Mailer
+	baseURL     string
+	ttl         time.Duration
+}
+
+// pendingEmailVerification : the value stored in redis against the hash of the token
+type pendingEmailVerification struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// SendVerification : mails a verification link to the current email of the user
+func (evs *emailVerificationService) SendVerification(u *models.User) error {
+	if u.Email == "" {
+		return errors.New("user has no email")
+	}
+
+	plainTextToken, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(pendingEmailVerification{Username: u.Username, Email: u.Email})
+	if err != nil {
+		return err
+	}
+
+	err = evs.redisClient.Set(evs.ctx, emailVerificationKeyPrefix+hashOpaqueToken(plainTextToken), data, evs.ttl).Err()
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/email/verify?token=%s", evs.baseURL, url.QueryEscape(plainTextToken))
+	return evs.mailer.Send(mailer.Message{
+		To:      u.Email,
+		Subject: "Verify your SmileCook email",
+		Body:    fmt.Sprintf("Hi %s,\n\nPlease verify your email by opening the following link:\n\n%s\n\nThe link expires in %s.\n", u.Username, link, evs.ttl),
+	})
+}
+
+// Check : reports whether the verification token is valid without consuming it, so that the link can be opened safely by mail scanners
+func (evs *emailVerificationService) Check(plainTextToken string) error {
+	exists, err := evs.redisClient.Exists(evs.ctx, emailVerificationKeyPrefix+hashOpaqueToken(plainTextToken)).Result()
+	if err != nil {
+		return err
+	}
+
+	if exists == 0 {
+		return ErrInvalidEmailVerificationToken
+	}
+
+	return nil
+}
+
+// Verify : consumes the verification token and marks the email as verified, returns the username of the user
+func (evs *emailVerificationService) Verify(plainTextToken string) (string, error) {
+	// GETDEL makes the token single-use
+	val, err := evs.redisClient.GetDel(evs.ctx, emailVerificationKeyPrefix+hashOpaqueToken(plainTextToken)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", ErrInvalidEmailVerificationToken
+		}
+		return "", err
+	}
+
+	var pending pendingEmailVerification
+	err = json.Unmarshal([]byte(val), &pending)
+	if err != nil {
+		return "", err
+	}
+
+	verified, err := evs.userService.MarkEmailVerified(pending.Username, pending.Email)
+	if err != nil {
+		return "", err
+	}
+
+	if !verified {
+		return "", ErrInvalidEmailVerificationToken
+	}
+
+	return pending.Username, nil
+}
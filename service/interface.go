@@ -1,7 +1,12 @@
 package service
 
 import (
+	"time"
+
+	"github.com/skamranahmed/smilecook/ingredient"
 	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/nutrition"
+	"github.com/skamranahmed/smilecook/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -10,15 +15,128 @@ type UserService interface {
 	Create(user *models.User) error
 	FindOne(username string) (*models.User, error)
 	DoesUsernameAlreadyExist(username string) (bool, error)
+	ValidatePassword(plainTextPassword string, userInputs ...string) error
 	HashPassword(plainTextPassword string) (string, error)
 	VerifyPassword(plainTextPassword, hashedPassword string) error
+	RehashPasswordIfNeeded(user *models.User, plainTextPassword string) (bool, error)
+	StagePasswordRehash(user *models.User, plainTextPassword string) (bool, error)
+	ApplyStagedPasswordRehash(user *models.User) (bool, error)
+	UpdatePassword(username string, hashedPassword string) (bool, error)
+	DoesEmailAlreadyExist(email string) (bool, error)
+	UpdateEmail(username string, email string) (bool, error)
+	MarkEmailVerified(username string, email string) (bool, error)
+	List(search string, skip int64, limit int64) ([]*models.User, int64, error)
+	UpdateRole(username string, role string, isAdmin bool) (bool, error)
+	UpdateModeration(username string, bannedAt *time.Time, suspendedUntil *time.Time, reason string) (bool, error)
+	FindOneByID(documentObjectID primitive.ObjectID) (*models.User, error)
+	FindOneByEmail(email string) (*models.User, error)
+	FindOneByIdentity(provider string, subject string) (*models.User, error)
+	AddIdentity(username string, identity models.Identity) (bool, error)
 }
 
 // RecipeService defines the methods that can be performed on the recipe object in the service layer
 type RecipeService interface {
 	Create(recipe *models.Recipe) error
 	FindOne(documentObjectID primitive.ObjectID) (*models.Recipe, error)
-	FetchAll() ([]*models.Recipe, error)
+	FetchAll(query repository.RecipeQuery) ([]*models.Recipe, error)
+	FetchPage(query repository.RecipeQuery, cursor string, limit int64) (*RecipePage, error)
+	FetchAllByAuthorID(authorID primitive.ObjectID) ([]*models.Recipe, error)
+	Ingredients(recipe *models.Recipe, system ingredient.System) []ingredient.Ingredient
 	Update(documentObjectID primitive.ObjectID, recipe *models.Recipe) (bool, error)
 	Delete(documentObjectID primitive.ObjectID) (bool, error)
+	Search(query string, limit int64) ([]*SearchResult, error)
+	ReconcileSearchIndex() (int, int, error)
+}
+
+// RefreshTokenService defines the methods that can be performed on the refresh token object in the service layer
+type RefreshTokenService interface {
+	Issue(user *models.User, client ClientInfo) (string, *models.RefreshToken, error)
+	Rotate(plainTextToken string, client ClientInfo) (string, *models.RefreshToken, error)
+	Revoke(plainTextToken string) error
+	RevokeAllForUser(userID primitive.ObjectID) error
+}
+
+// TokenRevocationService defines the methods that are used to revoke access tokens before they expire
+type TokenRevocationService interface {
+	RevokeToken(jti string, expiresAt time.Time) error
+	RevokeAllForUser(userID primitive.ObjectID) error
+	RevokeSession(sessionID string) error
+	IsRevoked(jti string, sessionID string, userID primitive.ObjectID, issuedAt time.Time) (bool, error)
+}
+
+// PasswordResetService defines the methods that are used to reset a forgotten password
+type PasswordResetService interface {
+	RequestReset(username string) error
+	Reset(plainTextToken string, newPlainTextPassword string) (*models.PasswordResetToken, error)
+}
+
+// EmailVerificationService defines the methods that are used to verify the email of a user
+type EmailVerificationService interface {
+	SendVerification(user *models.User) error
+	Check(plainTextToken string) error
+	Verify(plainTextToken string) (string, error)
+}
+
+// LoginThrottleService defines the methods that are used to protect the sign in against brute force attacks
+type LoginThrottleService interface {
+	Check(username string, ip string) (LoginThrottleResult, error)
+	RegisterFailure(username string, ip string) (LoginThrottleResult, error)
+	RegisterSuccess(username string) error
+	Unlock(username string) error
+}
+
+// TwoFactorService defines the methods that are used to manage the totp based two-factor authentication of a user
+type TwoFactorService interface {
+	Enroll(user *models.User) (string, string, error)
+	Confirm(user *models.User, code string) ([]string, error)
+	Disable(user *models.User, code string) error
+	Verify(user *models.User, code string) error
+}
+
+// APIKeyService defines the methods that can be performed on the api key object in the service layer
+type APIKeyService interface {
+	Create(user *models.User, name string, scopes []string, expiresAt *time.Time) (string, *models.APIKey, error)
+	FetchAllByUserID(userID primitive.ObjectID) ([]*models.APIKey, error)
+	Revoke(documentObjectID primitive.ObjectID, userID primitive.ObjectID) (bool, error)
+	Authenticate(plainTextKey string) (*models.APIKey, error)
+}
+
+// SocialLoginService defines the methods that are used to sign in through an external OpenID Connect provider
+type SocialLoginService interface {
+	BeginLogin(provider string) (string, error)
+	CompleteLogin(provider string, state string, code string) (*models.User, error)
+}
+
+// AccountService defines the methods that are used by the users to manage their own account
+type AccountService interface {
+	ChangePassword(user *models.User, currentPlainTextPassword string, newPlainTextPassword string) error
+	ChangeUsername(user *models.User, newUsername string) error
+	CompletePendingRenames() (int, error)
+	Delete(user *models.User, plainTextPassword string) (time.Time, error)
+	Restore(username string) (bool, error)
+	PurgeDeleted() (int, error)
+}
+
+// SessionService defines the methods that are used to list and revoke the sessions of a user
+type SessionService interface {
+	FetchAllByUserID(userID primitive.ObjectID) ([]*models.Session, error)
+	Revoke(sessionID primitive.ObjectID, userID primitive.ObjectID) (bool, error)
+	Touch(sessionID primitive.ObjectID, ip string) error
+}
+
+// AutocompleteService defines the methods that are used to suggest recipe names, tags and ingredients as the user types
+type AutocompleteService interface {
+	Suggest(text string, limit int) (*AutocompleteResult, error)
+	Invalidate() error
+}
+
+// ScalingService defines the methods that are used to scale a recipe to another number of servings
+type ScalingService interface {
+	Scale(recipe *models.Recipe, servings int) (*ScaledRecipe, error)
+}
+
+// NutritionService defines the methods that are used to estimate the nutrients of a recipe from its ingredients
+type NutritionService interface {
+	Estimate(recipe *models.Recipe) (*nutrition.Estimate, error)
+	Invalidate(recipeID primitive.ObjectID) error
 }
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+)
+
+const (
+	loginFailuresKeyPrefix string = "login_failures:"
+	loginBackoffKeyPrefix  string = "login_backoff:"
+	loginLockKeyPrefix     string = "login_lock:"
+
+	// failures are forgotten once no new failure happened for this long
+	loginFailureWindow time.Duration = 15 * time.Minute
+
+	// the first failures are free, the exponential backoff only kicks in afterwards
+	loginFreeAttempts int64 = 3
+
+	loginBaseBackoff time.Duration = time.Second
+	loginMaxBackoff  time.Duration = 5 * time.Minute
+
+	// a single client ip can try more passwords than a single username, since many users can share an ip
+	loginIPAttemptsMultiplier int64 = 5
+)
+
+// LoginThrottleResult : outcome of a login throttle check
+type LoginThrottleResult struct {
+	// Locked is true if the username or the ip is temporarily locked out
+	Locked bool
+
+	// RetryAfter is how long the client has to wait before trying again, zero means no wait is required
+	RetryAfter time.Duration
+}
+
+// NewLoginThrottleService : returns a loginThrottleService struct that implements the LoginThrottleService interface
+func NewLoginThrottleService(ctx context.Context, redisClient *redis.Client, maxAttempts int64, lockoutDuration time.Duration) LoginThrottleService {
+	return &loginThrottleService{
+		ctx:             ctx,
+		redisClient:     redisClient,
+		maxAttempts:     maxAttempts,
+		lockoutDuration: lockoutDuration,
+	}
+}
+
+type loginThrottleService struct {
+	ctx             context.Context
+	redisClient     *redis.Client
+	maxAttempts     int64
+	lockoutDuration time.Duration
+}
+
+// Check : checks whether a sign in attempt for the username from the ip is allowed right now
+func (lts *loginThrottleService) Check(username string, ip string) (LoginThrottleResult, error) {
+	var result LoginThrottleResult
+	for _, subject := range loginSubjects(username, ip) {
+		lockTTL, err := lts.ttl(loginLockKeyPrefix + subject)
+		if err != nil {
+			return result, err
+		}
+
+		if lockTTL > 0 {
+			result.Locked = true
+			result.RetryAfter = maxDuration(result.RetryAfter, lockTTL)
+			continue
+		}
+
+		backoffTTL, err := lts.ttl(loginBackoffKeyPrefix + subject)
+		if err != nil {
+			return result, err
+		}
+		result.RetryAfter = maxDuration(result.RetryAfter, backoffTTL)
+	}
+	return result, nil
+}
+
+// RegisterFailure : records a failed sign in attempt and applies the backoff or the lockout
+func (lts *loginThrottleService) RegisterFailure(username string, ip string) (LoginThrottleResult, error) {
+	var result LoginThrottleResult
+	for i, subject := range loginSubjects(username, ip) {
+		maxAttempts := lts.maxAttempts
+		if i == 1 {
+			maxAttempts = lts.maxAttempts * loginIPAttemptsMultiplier
+		}
+
+		failuresKey := loginFailuresKeyPrefix + subject
+		pipe := lts.redisClient.TxPipeline()
+		incr := pipe.Incr(lts.ctx, failuresKey)
+		pipe.Expire(lts.ctx, failuresKey, loginFailureWindow)
+		_, err := pipe.Exec(lts.ctx)
+		if err != nil {
+			return result, err
+		}
+
+		failures := incr.Val()
+		if failures >= maxAttempts {
+			// lock the subject out and start counting from scratch once the lock expires
+			pipe := lts.redisClient.TxPipeline()
+			pipe.Set(lts.ctx, loginLockKeyPrefix+subject, 1, lts.lockoutDuration)
+			pipe.Del(lts.ctx, failuresKey, loginBackoffKeyPrefix+subject)
+			_, err = pipe.Exec(lts.ctx)
+			if err != nil {
+				return result, err
+			}
+
+			result.Locked = true
+			result.RetryAfter = maxDuration(result.RetryAfter, lts.lockoutDuration)
+			continue
+		}
+
+		if failures >= loginFreeAttempts {
+			backoff := loginBackoff(failures - loginFreeAttempts)
+			err = lts.redisClient.Set(lts.ctx, loginBackoffKeyPrefix+subject, 1, backoff).Err()
+			if err != nil {
+				return result, err
+			}
+			result.RetryAfter = maxDuration(result.RetryAfter, backoff)
+		}
+	}
+	return result, nil
+}
+
+// RegisterSuccess : forgets the failed attempts of the username after a successful sign in
+func (lts *loginThrottleService) RegisterSuccess(username string) error {
+	subject := loginUserSubject(username)
+	return lts.redisClient.Del(lts.ctx, loginFailuresKeyPrefix+subject, loginBackoffKeyPrefix+subject).Err()
+}
+
+// Unlock : lifts the lockout of the username and forgets its failed attempts
+func (lts *loginThrottleService) Unlock(username string) error {
+	subject := loginUserSubject(username)
+	return lts.redisClient.Del(lts.ctx,
+		loginFailuresKeyPrefix+subject,
+		loginBackoffKeyPrefix+subject,
+		loginLockKeyPrefix+subject,
+	).Err()
+}
+
+// ttl : returns the remaining time to live of a key, zero if the key does not exist
+func (lts *loginThrottleService) ttl(key string) (time.Duration, error) {
+	ttl, err := lts.redisClient.PTTL(lts.ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if ttl < 0 {
+		// -2 : key does not exist, -1 : key has no expiry (which never happens for these keys)
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// loginSubjects : sign in attempts are tracked both per username and per client ip
+func loginSubjects(username string, ip string) []string {
+	return []string{loginUserSubject(username), "ip:" + ip}
+}
+
+func loginUserSubject(username string) string {
+	return "user:" + username
+}
+
+// loginBackoff : 1s, 2s, 4s, ... capped at loginMaxBackoff
+func loginBackoff(step int64) time.Duration {
+	backoff := time.Duration(float64(loginBaseBackoff) * math.Pow(2, float64(step)))
+	if backoff <= 0 || backoff > loginMaxBackoff {
+		return loginMaxBackoff
+	}
+	return backoff
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/nutrition"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const nutritionCacheKeyPrefix string = "nutrition:"
+
+// NewNutritionService : returns a nutritionService struct that implements the NutritionService interface
+func NewNutritionService(ctx context.Context, redisClient *redis.Client, foods *nutrition.Database, cacheTTL time.Duration) NutritionService {
+	return &nutritionService{
+		ctx:         ctx,
+		redisClient: redisClient,
+		foods:       foods,
+		cacheTTL:    cacheTTL,
+	}
+}
+
+type nutritionService struct {
+	ctx         context.Context
+	redisClient *redis.Client
+	foods       *nutrition.Database
+	cacheTTL    time.Duration
+}
+
+// Estimate : the calories, macros and key micronutrients per serving of the recipe, the estimate is cached until the recipe is updated
+func (ns *nutritionService) Estimate(recipe *models.Recipe) (*nutrition.Estimate, error) {
+	key := nutritionCacheKey(recipe.ID)
+
+	val, err := ns.redisClient.Get(ns.ctx, key).Result()
+	if err == nil {
+		estimate := &nutrition.Estimate{}
+		err = json.Unmarshal([]byte(val), estimate)
+		if err == nil {
+			return estimate, nil
+		}
+		log.Printf("unable to decode the cached nutrition of recipe %s, err: %v, estimating it again\n", recipe.ID.Hex(), err)
+	} else if err != redis.Nil {
+		log.Printf("error in retrieving the nutrition of recipe %s from redis, err: %v, estimating it again\n", recipe.ID.Hex(), err)
+	}
+
+	estimate := ns.foods.Estimate(recipe.StructuredIngredients(), recipe.Servings)
+
+	data, _ := json.Marshal(estimate)
+	ns.redisClient.Set(ns.ctx, key, string(data), ns.cacheTTL)
+
+	return estimate, nil
+}
+
+// Invalidate : drops the cached estimate of the recipe, it must be called whenever the ingredients or servings of the recipe change
+func (ns *nutritionService) Invalidate(recipeID primitive.ObjectID) error {
+	return ns.redisClient.Del(ns.ctx, nutritionCacheKey(recipeID)).Err()
+}
+
+func nutritionCacheKey(recipeID primitive.ObjectID) string {
+	return nutritionCacheKeyPrefix + recipeID.Hex()
+}
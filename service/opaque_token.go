@@ -0,0 +1,25 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// generateOpaqueToken : generates a random, url safe opaque token
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token, error: %s", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashOpaqueToken : opaque tokens are high entropy random values, so a plain sha256 is enough
+func hashOpaqueToken(plainTextToken string) string {
+	sum := sha256.Sum256([]byte(plainTextToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/notifier"
+	"github.com/skamranahmed/smilecook/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidPasswordResetToken : the password reset token is unknown, expired or was already used
+var ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+
+// NewPasswordResetService : returns a passwordResetService struct that implements the PasswordResetService interface
+func NewPasswordResetService(passwordResetTokenRepo repository.PasswordResetTokenRepository, userService UserService, notifier notifier.Notifier, ttl time.Duration) PasswordResetService {
+	return &passwordResetService{
+		passwordResetTokenRepo: passwordResetTokenRepo,
+		userService:            userService,
+		notifier:               notifier,
+		ttl:                    ttl,
+	}
+}
+
+type passwordResetService struct {
+	passwordResetTokenRepo repository.PasswordResetTokenRepository
+	userService            UserService
+	notifier               notifier.Notifier
+	ttl                    time.Duration
+}
+
+// RequestReset : issues a password reset token for the user and delivers it through the notifier
+// an unknown username is not reported back to the caller so that it cannot be used to enumerate the users
+func (prs *passwordResetService) RequestReset(username string) error {
+	user, err := prs.userService.FindOne(username)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Printf("password reset requested for unknown username: %s\n", username)
+			return nil
+		}
+		return err
+	}
+
+	plainTextToken, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	passwordResetToken := &models.PasswordResetToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		Username:  user.Username,
+		TokenHash: hashOpaqueToken(plainTextToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(prs.ttl),
+	}
+
+	err = prs.passwordResetTokenRepo.Create(passwordResetToken)
+	if err != nil {
+		return err
+	}
+
+	return prs.notifier.SendPasswordReset(user, plainTextToken, passwordResetToken.ExpiresAt)
+}
+
+// Reset : consumes the password reset token and sets the new password of the user, the other outstanding tokens of the user are invalidated
+func (prs *passwordResetService) Reset(plainTextToken string, newPlainTextPassword string) (*models.PasswordResetToken, error) {
+	passwordResetToken, err := prs.passwordResetTokenRepo.FindOneByHash(hashOpaqueToken(plainTextToken))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidPasswordResetToken
+		}
+		return nil, err
+	}
+
+	if passwordResetToken.UsedAt != nil || time.Now().After(passwordResetToken.ExpiresAt) {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	// a rejected password does not consume the token, the user can try again with a stronger one
+	err = prs.userService.ValidatePassword(newPlainTextPassword, passwordResetToken.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	// mark the token as used before touching the password, a token can only ever be consumed once
+	marked, err := prs.passwordResetTokenRepo.MarkUsed(passwordResetToken.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !marked {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	hashedPassword, err := prs.userService.HashPassword(newPlainTextPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := prs.userService.UpdatePassword(passwordResetToken.Username, hashedPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	// the other tokens that were requested before the reset must not be able to change the password again
+	err = prs.passwordResetTokenRepo.MarkAllUsedForUser(passwordResetToken.UserID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return passwordResetToken, nil
+}
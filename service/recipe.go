@@ -1,25 +1,43 @@
 package service
 
 import (
+	"log"
+
+	"github.com/skamranahmed/smilecook/dietary"
+	"github.com/skamranahmed/smilecook/ingredient"
 	"github.com/skamranahmed/smilecook/models"
 	"github.com/skamranahmed/smilecook/repository"
+	"github.com/skamranahmed/smilecook/search"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // NewRecipeService : returns a recipeService struct that implements the RecipeService interface
-func NewRecipeService(recipeRepo repository.RecipeRepository) RecipeService {
+func NewRecipeService(recipeRepo repository.RecipeRepository, searchIndex search.SearchIndex, nutritionService NutritionService) RecipeService {
 	return &recipeService{
-		recipeRepo: recipeRepo,
+		recipeRepo:       recipeRepo,
+		searchIndex:      searchIndex,
+		nutritionService: nutritionService,
 	}
 }
 
 type recipeService struct {
-	recipeRepo repository.RecipeRepository
+	recipeRepo       repository.RecipeRepository
+	searchIndex      search.SearchIndex
+	nutritionService NutritionService
 }
 
-// Create : creates a new recipe record
+// Create : creates a new recipe record, its allergens and diets are guessed from its ingredients
 func (rs *recipeService) Create(r *models.Recipe) error {
-	return rs.recipeRepo.Create(r)
+	r.Allergens, r.Diets = dietary.Classify(r.Ingredients, r.DietaryOverrides)
+	r.DietaryNotice = dietary.Notice
+
+	err := rs.recipeRepo.Create(r)
+	if err != nil {
+		return err
+	}
+
+	rs.syncSearchIndex(r)
+	return nil
 }
 
 // FindOne : finds a user record with the provided ID
@@ -27,17 +45,96 @@ func (rs *recipeService) FindOne(documentObjectID primitive.ObjectID) (*models.R
 	return rs.recipeRepo.FindOne(documentObjectID)
 }
 
-// FetchAll : fetches all public recipe records
-func (rs *recipeService) FetchAll() ([]*models.Recipe, error) {
-	return rs.recipeRepo.FetchAll()
+// FetchAll : fetches all public recipe records that match the query
+func (rs *recipeService) FetchAll(query repository.RecipeQuery) ([]*models.Recipe, error) {
+	return rs.recipeRepo.FetchAll(query)
+}
+
+// FetchPage : fetches the page of public recipes of the query that starts after the cursor, newest first unless the query is sorted otherwise
+func (rs *recipeService) FetchPage(query repository.RecipeQuery, cursor string, limit int64) (*RecipePage, error) {
+	if query.Sort == "" {
+		query.Sort = repository.RecipeSortNewest
+	}
+
+	after, err := decodeRecipeCursor(cursor, query.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	// one extra recipe tells whether there is a next page
+	recipes, err := rs.recipeRepo.FetchPage(query, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &RecipePage{Recipes: recipes}
+	if int64(len(recipes)) > limit {
+		page.Recipes = recipes[:limit]
+		page.HasMore = true
+		page.NextCursor = encodeRecipeCursor(page.Recipes[limit-1], query.Sort)
+	}
+
+	return page, nil
+}
+
+// Ingredients : the structured ingredients of the recipe, converted to the system unless it is empty
+func (rs *recipeService) Ingredients(recipe *models.Recipe, system ingredient.System) []ingredient.Ingredient {
+	ingredients := recipe.StructuredIngredients()
+	if system == "" {
+		return ingredients
+	}
+	return ingredient.ConvertAll(ingredients, system)
+}
+
+// FetchAllByAuthorID : fetches every recipe record of the user, including the private ones
+func (rs *recipeService) FetchAllByAuthorID(authorID primitive.ObjectID) ([]*models.Recipe, error) {
+	return rs.recipeRepo.FetchAllByAuthorID(authorID)
 }
 
-// Update : updates a recipe record with the provided ID
+// Update : updates a recipe record with the provided ID, its allergens and diets are guessed again from its ingredients
 func (rs *recipeService) Update(documentObjectID primitive.ObjectID, recipe *models.Recipe) (bool, error) {
-	return rs.recipeRepo.Update(documentObjectID, recipe)
+	recipe.Allergens, recipe.Diets = dietary.Classify(recipe.Ingredients, recipe.DietaryOverrides)
+	recipe.DietaryNotice = dietary.Notice
+
+	updated, err := rs.recipeRepo.Update(documentObjectID, recipe)
+	if err != nil || !updated {
+		return updated, err
+	}
+
+	rs.invalidateNutrition(documentObjectID)
+
+	// the update only carries the changed fields, the index needs the whole recipe
+	recipeRecord, err := rs.recipeRepo.FindOne(documentObjectID)
+	if err != nil {
+		log.Printf("unable to reindex recipe %s, error: %v\n", documentObjectID.Hex(), err)
+		return true, nil
+	}
+
+	rs.syncSearchIndex(recipeRecord)
+	return true, nil
 }
 
 // Delete : deletes a recipe record with the provided ID
 func (rs *recipeService) Delete(documentObjectID primitive.ObjectID) (bool, error) {
-	return rs.recipeRepo.Delete(documentObjectID)
+	deleted, err := rs.recipeRepo.Delete(documentObjectID)
+	if err != nil || !deleted {
+		return deleted, err
+	}
+
+	err = rs.searchIndex.Delete(documentObjectID)
+	if err != nil {
+		log.Printf("unable to remove recipe %s from the search index, error: %v\n", documentObjectID.Hex(), err)
+	}
+
+	rs.invalidateNutrition(documentObjectID)
+	return true, nil
+}
+
+// invalidateNutrition : drops the cached nutrition of a recipe after it has changed,
+// the recipe is saved already so a failure is only logged
+func (rs *recipeService) invalidateNutrition(documentObjectID primitive.ObjectID) {
+	err := rs.nutritionService.Invalidate(documentObjectID)
+	if err != nil {
+		log.Printf("unable to invalidate the nutrition of recipe %s, error: %v\n", documentObjectID.Hex(), err)
+	}
 }
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidCursor : the cursor was not issued by us or has been tampered with
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// RecipePage : a page of recipes, NextCursor fetches the following page and is empty on the last one
+type RecipePage struct {
+	Recipes    []*models.Recipe `json:"data"`
+	NextCursor string           `json:"next_cursor"`
+	HasMore    bool             `json:"has_more"`
+}
+
+// recipeCursorPayload : the content of the opaque cursor, clients must not rely on it
+type recipeCursorPayload struct {
+	Sort        repository.RecipeSort `json:"s"`
+	PublishedAt int64                 `json:"p,omitempty"` // unix milliseconds, the precision mongo stores dates with
+	Name        string                `json:"n,omitempty"`
+	ID          string                `json:"i"`
+}
+
+// encodeRecipeCursor : returns the opaque cursor that points right after the recipe in the order of the sort
+func encodeRecipeCursor(recipe *models.Recipe, sort repository.RecipeSort) string {
+	payload := recipeCursorPayload{Sort: sort, ID: recipe.ID.Hex()}
+	if sort == repository.RecipeSortName {
+		payload.Name = recipe.Name
+	} else {
+		payload.PublishedAt = recipe.PublishedAt.UnixNano() / int64(time.Millisecond)
+	}
+
+	data, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeRecipeCursor : parses an opaque cursor, an empty cursor means the first page,
+// a cursor is only valid with the sort it has been issued for
+func decodeRecipeCursor(cursor string, sort repository.RecipeSort) (*repository.RecipeCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload recipeCursorPayload
+	err = json.Unmarshal(data, &payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if payload.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := primitive.ObjectIDFromHex(payload.ID)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &repository.RecipeCursor{
+		PublishedAt: time.Unix(0, payload.PublishedAt*int64(time.Millisecond)),
+		Name:        payload.Name,
+		ID:          id,
+	}, nil
+}
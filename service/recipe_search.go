@@ -0,0 +1,122 @@
+package service
+
+import (
+	"log"
+	"strings"
+
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/repository"
+	"github.com/skamranahmed/smilecook/search"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// snippetLength : the instructions are cut down to a snippet of about this many bytes around the first match
+const snippetLength int = 160
+
+// SearchResult : a recipe that matches a search, with the matching parts of its fields highlighted in <mark> tags
+type SearchResult struct {
+	Recipe     *models.Recipe      `json:"recipe"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights"`
+}
+
+// Search : fetches the public recipes that best match the query, the most relevant first
+func (rs *recipeService) Search(query string, limit int64) ([]*SearchResult, error) {
+	hits, err := rs.searchIndex.Search(query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*SearchResult, 0, len(hits))
+	if len(hits) == 0 {
+		return results, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+
+	recipes, err := rs.recipeRepo.FetchByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	recipesByID := make(map[primitive.ObjectID]*models.Recipe, len(recipes))
+	for _, recipe := range recipes {
+		recipesByID[recipe.ID] = recipe
+	}
+
+	terms := make(map[string]bool)
+	for _, term := range search.Terms(query) {
+		terms[term] = true
+	}
+
+	for _, hit := range hits {
+		// the index may lag behind, e.g. for the recipes of a deleted or a purged account
+		recipe, ok := recipesByID[hit.ID]
+		if !ok || !recipe.IsListed() {
+			continue
+		}
+
+		results = append(results, &SearchResult{
+			Recipe:     recipe,
+			Score:      hit.Score,
+			Highlights: highlightRecipe(recipe, terms),
+		})
+	}
+
+	return results, nil
+}
+
+// ReconcileSearchIndex : brings an index that keeps its own copy of the recipes in line with the public recipes,
+// a write to the index that failed after the recipe was saved is caught up here. Returns the number of indexed and removed recipes
+func (rs *recipeService) ReconcileSearchIndex() (int, int, error) {
+	reconciler, ok := rs.searchIndex.(search.Reconciler)
+	if !ok {
+		return 0, 0, nil
+	}
+
+	recipes, err := rs.recipeRepo.FetchAll(repository.RecipeQuery{})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return reconciler.Reconcile(recipes)
+}
+
+// syncSearchIndex : indexes a listed recipe and removes a private one or one of a deleted account from the index,
+// the recipe is saved already so a failure is only logged
+func (rs *recipeService) syncSearchIndex(recipe *models.Recipe) {
+	var err error
+	if !recipe.IsListed() {
+		err = rs.searchIndex.Delete(recipe.ID)
+	} else {
+		err = rs.searchIndex.Index(recipe)
+	}
+
+	if err != nil {
+		log.Printf("unable to update the search index for recipe %s, error: %v\n", recipe.ID.Hex(), err)
+	}
+}
+
+// highlightRecipe : the highlighted snippets of the fields of the recipe that contain any of the terms
+func highlightRecipe(recipe *models.Recipe, terms map[string]bool) map[string][]string {
+	highlights := make(map[string][]string)
+
+	add := func(field string, texts []string, maxLength int) {
+		for _, text := range texts {
+			snippet, ok := search.Highlight(text, terms, maxLength)
+			if ok {
+				highlights[field] = append(highlights[field], snippet)
+			}
+		}
+	}
+
+	add("name", []string{recipe.Name}, 0)
+	add("tags", recipe.Tags, 0)
+	add("ingredients", recipe.Ingredients, 0)
+	add("instructions", []string{strings.Join(recipe.Instructions, " ")}, snippetLength)
+
+	return highlights
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrInvalidRefreshToken : the refresh token is unknown, expired or revoked
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+	// ErrRefreshTokenReused : an already rotated refresh token was presented again, the whole family has been revoked
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// NewRefreshTokenService : returns a refreshTokenService struct that implements the RefreshTokenService interface
+func NewRefreshTokenService(refreshTokenRepo repository.RefreshTokenRepository, ttl time.Duration) RefreshTokenService {
+	return &refreshTokenService{
+		refreshTokenRepo: refreshTokenRepo,
+		ttl:              ttl,
+	}
+}
+
+type refreshTokenService struct {
+	refreshTokenRepo repository.RefreshTokenRepository
+	ttl              time.Duration
+}
+
+// ClientInfo : the device a request comes from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// Issue : issues a brand new refresh token (and token family, i.e. session) for the provided user
+func (rts *refreshTokenService) Issue(u *models.User, client ClientInfo) (string, *models.RefreshToken, error) {
+	now := time.Now()
+	return rts.issue(&models.RefreshToken{
+		FamilyID:         primitive.NewObjectID(),
+		UserID:           u.ID,
+		Username:         u.Username,
+		SessionStartedAt: now,
+		UserAgent:        client.UserAgent,
+		IP:               client.IP,
+		LastSeenAt:       now,
+		LastSeenIP:       client.IP,
+	})
+}
+
+// Rotate : exchanges a valid refresh token for a new one from the same family
+// presenting a token that was already rotated revokes the entire family
+func (rts *refreshTokenService) Rotate(plainTextToken string, client ClientInfo) (string, *models.RefreshToken, error) {
+	refreshToken, err := rts.refreshTokenRepo.FindOneByHash(hashOpaqueToken(plainTextToken))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil, ErrInvalidRefreshToken
+		}
+		return "", nil, err
+	}
+
+	if refreshToken.RevokedAt != nil {
+		return "", nil, ErrInvalidRefreshToken
+	}
+
+	if refreshToken.RotatedAt != nil {
+		return "", nil, rts.revokeReusedFamily(refreshToken.FamilyID)
+	}
+
+	if time.Now().After(refreshToken.ExpiresAt) {
+		return "", nil, ErrInvalidRefreshToken
+	}
+
+	rotated, err := rts.refreshTokenRepo.MarkRotated(refreshToken.ID, time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !rotated {
+		// another request rotated (or revoked) this token in the meantime
+		return "", nil, rts.revokeReusedFamily(refreshToken.FamilyID)
+	}
+
+	// the session keeps its details, only the last seen ones move on
+	return rts.issue(&models.RefreshToken{
+		FamilyID:         refreshToken.FamilyID,
+		UserID:           refreshToken.UserID,
+		Username:         refreshToken.Username,
+		SessionStartedAt: refreshToken.SessionStartedAt,
+		UserAgent:        refreshToken.UserAgent,
+		IP:               refreshToken.IP,
+		LastSeenAt:       time.Now(),
+		LastSeenIP:       client.IP,
+	})
+}
+
+// Revoke : revokes the family of the provided refresh token
+func (rts *refreshTokenService) Revoke(plainTextToken string) error {
+	refreshToken, err := rts.refreshTokenRepo.FindOneByHash(hashOpaqueToken(plainTextToken))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+
+	return rts.refreshTokenRepo.RevokeFamily(refreshToken.FamilyID)
+}
+
+// RevokeAllForUser : revokes every refresh token family of the user
+func (rts *refreshTokenService) RevokeAllForUser(userID primitive.ObjectID) error {
+	return rts.refreshTokenRepo.RevokeAllForUser(userID)
+}
+
+// issue : completes the refresh token with a new value, its ID and its lifetime and saves it
+func (rts *refreshTokenService) issue(refreshToken *models.RefreshToken) (string, *models.RefreshToken, error) {
+	plainTextToken, err := generateOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	refreshToken.ID = primitive.NewObjectID()
+	refreshToken.TokenHash = hashOpaqueToken(plainTextToken)
+	refreshToken.CreatedAt = now
+	refreshToken.ExpiresAt = now.Add(rts.ttl)
+
+	err = rts.refreshTokenRepo.Create(refreshToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return plainTextToken, refreshToken, nil
+}
+
+func (rts *refreshTokenService) revokeReusedFamily(familyID primitive.ObjectID) error {
+	err := rts.refreshTokenRepo.RevokeFamily(familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family, error: %s", err)
+	}
+	return ErrRefreshTokenReused
+}
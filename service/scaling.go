@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/skamranahmed/smilecook/ingredient"
+	"github.com/skamranahmed/smilecook/models"
+)
+
+var (
+	// ErrInvalidServings : a recipe can only be scaled to a positive number of servings
+	ErrInvalidServings = errors.New("servings must be a positive number")
+
+	// ErrServingsUnknown : the recipe does not say how many servings it makes, so there is nothing to scale from
+	ErrServingsUnknown = errors.New("the recipe does not specify its servings")
+)
+
+// ScaledRecipe : a recipe scaled to another number of servings, Ingredients holds the scaled lines
+// and ScaledFrom the servings of the original recipe
+type ScaledRecipe struct {
+	*models.Recipe
+	ScaledFrom            int                     `json:"scaled_from"`
+	StructuredIngredients []ingredient.Ingredient `json:"structured_ingredients"`
+}
+
+// NewScalingService : returns a scalingService struct that implements the ScalingService interface
+func NewScalingService() ScalingService {
+	return &scalingService{}
+}
+
+type scalingService struct{}
+
+// Scale : scales the quantities of the recipe to the servings, the amounts are rounded to kitchen fractions
+// and the units move up or down as the amounts grow or shrink, the lines without a quantity are kept as they are,
+// the yield is scaled along
+func (ss *scalingService) Scale(recipe *models.Recipe, servings int) (*ScaledRecipe, error) {
+	if servings < 1 {
+		return nil, ErrInvalidServings
+	}
+
+	if recipe.Servings < 1 {
+		return nil, ErrServingsUnknown
+	}
+
+	factor := float64(servings) / float64(recipe.Servings)
+	structured := ingredient.ScaleAll(recipe.StructuredIngredients(), factor)
+
+	lines := make([]string, 0, len(structured))
+	for _, scaledIngredient := range structured {
+		if scaledIngredient.Quantity == nil || factor == 1 {
+			lines = append(lines, scaledIngredient.Original)
+			continue
+		}
+		lines = append(lines, scaledIngredient.String())
+	}
+
+	scaled := *recipe
+	scaled.Servings = servings
+	scaled.Ingredients = lines
+	if recipe.Yield != nil {
+		scaled.Yield = &models.Yield{Quantity: ingredient.RoundKitchen(recipe.Yield.Quantity * factor), Unit: recipe.Yield.Unit}
+	}
+
+	return &ScaledRecipe{
+		Recipe:                &scaled,
+		ScaledFrom:            recipe.Servings,
+		StructuredIngredients: structured,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	sessionLastSeenKeyPrefix string = "session_last_seen:"
+
+	// sessionLastSeenInterval : the last seen time of a session is written at most once per interval
+	sessionLastSeenInterval time.Duration = time.Minute
+)
+
+// NewSessionService : returns a sessionService struct that implements the SessionService interface
+func NewSessionService(ctx context.Context, redisClient *redis.Client, refreshTokenRepo repository.RefreshTokenRepository, revocationService TokenRevocationService) SessionService {
+	return &sessionService{
+		ctx:               ctx,
+		redisClient:       redisClient,
+		refreshTokenRepo:  refreshTokenRepo,
+		revocationService: revocationService,
+	}
+}
+
+type sessionService struct {
+	ctx               context.Context
+	redisClient       *redis.Client
+	refreshTokenRepo  repository.RefreshTokenRepository
+	revocationService TokenRevocationService
+}
+
+// FetchAllByUserID : lists the active sessions of the user, a session is identified by its refresh token family
+func (ss *sessionService) FetchAllByUserID(userID primitive.ObjectID) ([]*models.Session, error) {
+	refreshTokens, err := ss.refreshTokenRepo.FetchActiveByUserID(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*models.Session, 0, len(refreshTokens))
+	for _, refreshToken := range refreshTokens {
+		sessions = append(sessions, &models.Session{
+			ID:         refreshToken.FamilyID,
+			UserAgent:  refreshToken.UserAgent,
+			IP:         refreshToken.IP,
+			CreatedAt:  refreshToken.SessionStartedAt,
+			LastSeenAt: refreshToken.LastSeenAt,
+			LastSeenIP: refreshToken.LastSeenIP,
+			ExpiresAt:  refreshToken.ExpiresAt,
+		})
+	}
+
+	return sessions, nil
+}
+
+// Revoke : signs the session out, its refresh tokens and its access tokens stop working
+// returns false if the user has no active session with this ID
+func (ss *sessionService) Revoke(sessionID primitive.ObjectID, userID primitive.ObjectID) (bool, error) {
+	revoked, err := ss.refreshTokenRepo.RevokeFamilyOfUser(sessionID, userID)
+	if err != nil || !revoked {
+		return false, err
+	}
+
+	err = ss.revocationService.RevokeSession(sessionID.Hex())
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Touch : records that the session was just used, the write to mongo is throttled through redis
+func (ss *sessionService) Touch(sessionID primitive.ObjectID, ip string) error {
+	// SETNX succeeds for the first request of every interval only
+	acquired, err := ss.redisClient.SetNX(ss.ctx, sessionLastSeenKeyPrefix+sessionID.Hex(), ip, sessionLastSeenInterval).Result()
+	if err != nil || !acquired {
+		return err
+	}
+
+	return ss.refreshTokenRepo.UpdateLastSeen(sessionID, time.Now(), ip)
+}
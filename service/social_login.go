@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/oidc"
+	"github.com/skamranahmed/smilecook/validation"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	oidcStateKeyPrefix string        = "oidc_state:"
+	oidcStateTTL       time.Duration = 10 * time.Minute
+)
+
+var (
+	// ErrUnknownOIDCProvider : no provider is configured with the requested name
+	ErrUnknownOIDCProvider = errors.New("unknown login provider")
+
+	// ErrInvalidOIDCState : the state is unknown, expired, already used or belongs to another provider
+	ErrInvalidOIDCState = errors.New("invalid or expired login state")
+
+	// ErrOIDCAccountConflict : the email of the external account belongs to a user whose email is not verified,
+	// linking it automatically would allow taking over the account
+	ErrOIDCAccountConflict = errors.New("an account with this email already exists, sign in with your password and verify your email first")
+)
+
+var usernameDisallowedCharacters = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// NewSocialLoginService : returns a socialLoginService struct that implements the SocialLoginService interface
+func NewSocialLoginService(ctx context.Context, redisClient *redis.Client, userService UserService, providers []*oidc.Provider) SocialLoginService {
+	providersByName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		providersByName[provider.Name()] = provider
+	}
+
+	return &socialLoginService{
+		ctx:         ctx,
+		redisClient: redisClient,
+		userService: userService,
+		providers:   providersByName,
+	}
+}
+
+type socialLoginService struct {
+	ctx         context.Context
+	redisClient *redis.Client
+	userService UserService
+	providers   map[string]*oidc.Provider
+}
+
+// pendingOIDCLogin : the value stored in redis against the state until the provider redirects back
+type pendingOIDCLogin struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+// BeginLogin : returns the url of the provider that the user has to be redirected to
+func (sls *socialLoginService) BeginLogin(providerName string) (string, error) {
+	provider, ok := sls.providers[providerName]
+	if !ok {
+		return "", ErrUnknownOIDCProvider
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	codeVerifier, codeChallenge, err := oidc.GeneratePKCE()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(pendingOIDCLogin{Provider: providerName, CodeVerifier: codeVerifier, Nonce: nonce})
+	if err != nil {
+		return "", err
+	}
+
+	err = sls.redisClient.Set(sls.ctx, oidcStateKeyPrefix+state, data, oidcStateTTL).Err()
+	if err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(sls.ctx, state, nonce, codeChallenge)
+}
+
+// CompleteLogin : exchanges the authorization code, validates the id token and returns the linked user,
+// the user is linked by a verified email or provisioned if there is none yet
+func (sls *socialLoginService) CompleteLogin(providerName string, state string, code string) (*models.User, error) {
+	provider, ok := sls.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	// GETDEL makes the state single-use
+	val, err := sls.redisClient.GetDel(sls.ctx, oidcStateKeyPrefix+state).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrInvalidOIDCState
+		}
+		return nil, err
+	}
+
+	var pending pendingOIDCLogin
+	err = json.Unmarshal([]byte(val), &pending)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending.Provider != providerName {
+		return nil, ErrInvalidOIDCState
+	}
+
+	tokenResponse, err := provider.Exchange(sls.ctx, code, pending.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := provider.VerifyIDToken(sls.ctx, tokenResponse.IDToken, pending.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return sls.resolveUser(providerName, claims)
+}
+
+// resolveUser : finds the user the external account is linked to, links it or provisions a new user
+func (sls *socialLoginService) resolveUser(providerName string, claims *oidc.IDTokenClaims) (*models.User, error) {
+	user, err := sls.userService.FindOneByIdentity(providerName, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	identity := models.Identity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+		LinkedAt: time.Now(),
+	}
+
+	// an unverified email proves nothing, it is neither used for linking nor copied to the user
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if !claims.EmailVerified {
+		email = ""
+	}
+
+	if email != "" {
+		user, err := sls.userService.FindOneByEmail(email)
+		if err == nil {
+			if !user.EmailVerified {
+				return nil, ErrOIDCAccountConflict
+			}
+
+			_, err = sls.userService.AddIdentity(user.Username, identity)
+			if err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+	}
+
+	username, err := sls.availableUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user = &models.User{
+		ID:         primitive.NewObjectID(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Username:   username,
+		Password:   "", // the user can only sign in through the provider until a password is set with a reset
+		IsAdmin:    false,
+		Email:      email,
+		Identities: []models.Identity{identity},
+	}
+	if email != "" {
+		user.EmailVerified = true
+		user.EmailVerifiedAt = &now
+	}
+
+	err = sls.userService.Create(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// availableUsername : derives a username from the claims, a random suffix is appended if it is already taken
+func (sls *socialLoginService) availableUsername(claims *oidc.IDTokenClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	if base == "" {
+		base = claims.Name
+	}
+
+	base = strings.Trim(usernameDisallowedCharacters.ReplaceAllString(strings.ToLower(base), "_"), "_")
+	if len(base) > 20 {
+		base = base[:20]
+	}
+	if len(validation.ValidateUsername(base)) > 0 {
+		base = "user"
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 10; attempt++ {
+		exists, err := sls.userService.DoesUsernameAlreadyExist(candidate)
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			return candidate, nil
+		}
+
+		suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s_%04d", base, suffix.Int64())
+	}
+
+	return "", errors.New("unable to find an available username")
+}
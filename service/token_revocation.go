@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	revokedTokenKeyPrefix     string = "revoked_jti:"
+	revokedSessionKeyPrefix   string = "revoked_sid:"
+	tokensValidAfterKeyPrefix string = "tokens_valid_after_ms:"
+)
+
+// NewTokenRevocationService : returns a tokenRevocationService struct that implements the TokenRevocationService interface
+func NewTokenRevocationService(ctx context.Context, redisClient *redis.Client, accessTokenTTL time.Duration) TokenRevocationService {
+	return &tokenRevocationService{
+		ctx:            ctx,
+		redisClient:    redisClient,
+		accessTokenTTL: accessTokenTTL,
+	}
+}
+
+type tokenRevocationService struct {
+	ctx            context.Context
+	redisClient    *redis.Client
+	accessTokenTTL time.Duration
+}
+
+// RevokeToken : adds the jti of an access token to the denylist until the token would have expired anyway
+func (trs *tokenRevocationService) RevokeToken(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// the token has already expired, nothing to do
+		return nil
+	}
+
+	return trs.redisClient.Set(trs.ctx, revokedTokenKeyPrefix+jti, 1, ttl).Err()
+}
+
+// RevokeAllForUser : every access token of the user that was issued before now is considered revoked,
+// the time is kept in milliseconds so that a token issued right after the revocation, e.g. on a password change, stays valid.
+// It is kept under the user ID, so that it neither follows the username to another user nor is lost on a rename
+func (trs *tokenRevocationService) RevokeAllForUser(userID primitive.ObjectID) error {
+	return trs.redisClient.Set(trs.ctx, tokensValidAfterKeyPrefix+userID.Hex(), time.Now().UnixMilli(), trs.accessTokenTTL).Err()
+}
+
+// RevokeSession : every access token of the session is considered revoked, the refresh tokens are revoked separately
+func (trs *tokenRevocationService) RevokeSession(sessionID string) error {
+	return trs.redisClient.Set(trs.ctx, revokedSessionKeyPrefix+sessionID, 1, trs.accessTokenTTL).Err()
+}
+
+// IsRevoked : checks whether an access token has been revoked either by its jti, by its session or by a user wide revocation
+func (trs *tokenRevocationService) IsRevoked(jti string, sessionID string, userID primitive.ObjectID, issuedAt time.Time) (bool, error) {
+	keys := make([]string, 0, 2)
+	if jti != "" {
+		keys = append(keys, revokedTokenKeyPrefix+jti)
+	}
+	if sessionID != "" {
+		keys = append(keys, revokedSessionKeyPrefix+sessionID)
+	}
+
+	if len(keys) > 0 {
+		exists, err := trs.redisClient.Exists(trs.ctx, keys...).Result()
+		if err != nil {
+			return false, err
+		}
+
+		if exists > 0 {
+			return true, nil
+		}
+	}
+
+	val, err := trs.redisClient.Get(trs.ctx, tokensValidAfterKeyPrefix+userID.Hex()).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+		return false, err
+	}
+
+	validAfter, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid tokens valid after value for user: %s, error: %s", userID.Hex(), err)
+	}
+
+	// a token issued in the same millisecond as the revocation was issued after it, the revocation comes first in every flow.
+	// A token without iat_ms is issued at the start of its second, so one of the second of the revocation is revoked
+	return issuedAt.UnixMilli() < validAfter, nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	redis "github.com/go-redis/redis/v8"
+	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/repository"
+	"github.com/skamranahmed/smilecook/totp"
+)
+
+const (
+	usedTOTPCounterKeyPrefix string = "totp_used_counter:"
+
+	recoveryCodeCount    int    = 10
+	recoveryCodeLength   int    = 10
+	recoveryCodeAlphabet string = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	// accept codes from one time step before and after, to tolerate clock drift
+	totpSkew int64 = 1
+)
+
+var (
+	// ErrTwoFactorAlreadyEnabled : the user already has two-factor authentication turned on
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+	// ErrTwoFactorNotEnabled : the user does not have two-factor authentication turned on
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+
+	// ErrTwoFactorNotEnrolled : the user has to enroll before confirming
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication enrollment has not been started")
+
+	// ErrInvalidTwoFactorCode : the code is neither a valid totp code nor an unused recovery code
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor authentication code")
+)
+
+// NewTwoFactorService : returns a twoFactorService struct that implements the TwoFactorService interface
+func NewTwoFactorService(ctx context.Context, redisClient *redis.Client, userRepo repository.UserRepository, issuer string) TwoFactorService {
+	return &twoFactorService{
+		ctx:         ctx,
+		redisClient: redisClient,
+		userRepo:    userRepo,
+		issuer:      issuer,
+	}
+}
+
+type twoFactorService struct {
+	ctx         context.Context
+	redisClient *redis.Client
+	userRepo    repository.UserRepository
+	issuer      string
+}
+
+// Enroll : generates a new secret for the user, returns the secret and its otpauth:// provisioning uri
+// the secret only becomes active once it is confirmed with a valid code
+func (tfs *twoFactorService) Enroll(u *models.User) (string, string, error) {
+	if u.TOTPEnabled {
+		return "", "", ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = tfs.userRepo.SetPendingTOTPSecret(u.Username, secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.ProvisioningURI(tfs.issuer, u.Username, secret), nil
+}
+
+// Confirm : turns on two-factor authentication if the code matches the pending secret, returns the plain text recovery codes
+func (tfs *twoFactorService) Confirm(u *models.User, code string) ([]string, error) {
+	if u.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	if u.TOTPPendingSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	_, ok := totp.Validate(u.TOTPPendingSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	recoveryCodes, hashedRecoveryCodes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tfs.userRepo.EnableTOTP(u.Username, u.TOTPPendingSecret, hashedRecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// Disable : turns off two-factor authentication, a valid code (or recovery code) is required
+func (tfs *twoFactorService) Disable(u *models.User, code string) error {
+	err := tfs.Verify(u, code)
+	if err != nil {
+		return err
+	}
+
+	_, err = tfs.userRepo.DisableTOTP(u.Username)
+	return err
+}
+
+// Verify : verifies a totp code or consumes a recovery code of the user
+func (tfs *twoFactorService) Verify(u *models.User, code string) error {
+	if !u.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	counter, ok := totp.Validate(u.TOTPSecret, code, time.Now(), totpSkew)
+	if ok {
+		return tfs.markCounterUsed(u.Username, counter)
+	}
+
+	consumed, err := tfs.userRepo.ConsumeRecoveryCode(u.Username, hashOpaqueToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+
+	if !consumed {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// markTOTPCounterScript : stores the counter unless the stored one is the same or more recent, in a single step so that
+// two concurrent sign ins cannot both use the same code, returns 1 if the counter has been stored
+var markTOTPCounterScript = redis.NewScript(`
+local last = tonumber(redis.call("GET", KEYS[1]))
+if last and tonumber(ARGV[1]) <= last then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+// markCounterUsed : a totp code can only be used once, this also rejects codes older than the last accepted one
+func (tfs *twoFactorService) markCounterUsed(username string, counter int64) error {
+	key := usedTOTPCounterKeyPrefix + username
+	ttl := time.Duration((2*totpSkew+1)*totp.Period) * time.Second
+
+	stored, err := markTOTPCounterScript.Run(tfs.ctx, tfs.redisClient, []string{key}, counter, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	if stored == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// generateRecoveryCodes : returns the plain text recovery codes (formatted as xxxxx-xxxxx) and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	hashedRecoveryCodes := make([]string, 0, recoveryCodeCount)
+
+	alphabetLength := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := 0; i < recoveryCodeCount; i++ {
+		var code strings.Builder
+		for j := 0; j < recoveryCodeLength; j++ {
+			if j == recoveryCodeLength/2 {
+				code.WriteByte('-')
+			}
+
+			// rand.Int draws uniformly, every character of the alphabet is equally likely
+			index, err := rand.Int(rand.Reader, alphabetLength)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to generate recovery codes, error: %s", err)
+			}
+			code.WriteByte(recoveryCodeAlphabet[index.Int64()])
+		}
+
+		recoveryCodes = append(recoveryCodes, code.String())
+		hashedRecoveryCodes = append(hashedRecoveryCodes, hashOpaqueToken(normalizeRecoveryCode(code.String())))
+	}
+
+	return recoveryCodes, hashedRecoveryCodes, nil
+}
+
+// normalizeRecoveryCode : recovery codes are accepted with or without the dash and in any case
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
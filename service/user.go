@@ -1,22 +1,34 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/skamranahmed/smilecook/models"
+	"github.com/skamranahmed/smilecook/passwordhash"
 	"github.com/skamranahmed/smilecook/repository"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/skamranahmed/smilecook/validation"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrPasswordMismatch : the plain text password does not match the stored hash
+var ErrPasswordMismatch = errors.New("invalid username or password")
+
 // NewUserService : returns a userService struct that implements the UserService interface
-func NewUserService(userRepo repository.UserRepository) UserService {
+// new passwords are hashed with hashParams, existing hashes with other parameters are upgraded on sign in
+func NewUserService(userRepo repository.UserRepository, passwordPolicy validation.PasswordPolicy, hashParams passwordhash.Params) UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:       userRepo,
+		passwordPolicy: passwordPolicy,
+		hashParams:     hashParams,
 	}
 }
 
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo       repository.UserRepository
+	passwordPolicy validation.PasswordPolicy
+	hashParams     passwordhash.Params
 }
 
 // Create : creates a new user record
@@ -34,16 +46,126 @@ func (us *userService) DoesUsernameAlreadyExist(username string) (bool, error) {
 	return us.userRepo.DoesUsernameAlreadyExist(username)
 }
 
-// HashPassword : hashes a plainTextPassword
+// ValidatePassword : checks a new password against the password policy, returns validation.Errors if it is rejected
+// the user inputs (e.g. the username and the email) must not make up the password
+func (us *userService) ValidatePassword(plainTextPassword string, userInputs ...string) error {
+	return us.passwordPolicy.Validate(plainTextPassword, userInputs...).Err()
+}
+
+// HashPassword : hashes a plainTextPassword with the configured algorithm, the hash describes its own parameters
 func (us *userService) HashPassword(plainTextPassword string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(plainTextPassword), bcrypt.DefaultCost)
+	hashedPassword, err := passwordhash.Hash(plainTextPassword, us.hashParams)
 	if err != nil {
 		return "", fmt.Errorf("falied to hash password, error: %s", err)
 	}
-	return string(hashedPassword), nil
+	return hashedPassword, nil
 }
 
 // VerifyPassword : verifies whether the hash of the provided plainTextPassword matches with the existing hashPassword or not
 func (us *userService) VerifyPassword(plainTextPassword, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(plainTextPassword))
+	if hashedPassword == "" {
+		// users provisioned through an external provider have no password
+		return ErrPasswordMismatch
+	}
+
+	err := passwordhash.Verify(plainTextPassword, hashedPassword)
+	if err == passwordhash.ErrMismatch {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+// RehashPasswordIfNeeded : re-hashes the verified password and saves it if the stored hash was created with older parameters,
+// the stored hash is only replaced if it is still the one the password was verified against. Returns whether the hash was upgraded
+func (us *userService) RehashPasswordIfNeeded(u *models.User, plainTextPassword string) (bool, error) {
+	if !passwordhash.NeedsRehash(u.Password, us.hashParams) {
+		return false, nil
+	}
+
+	hashedPassword, err := us.HashPassword(plainTextPassword)
+	if err != nil {
+		return false, err
+	}
+
+	return us.userRepo.ReplacePasswordHash(u.Username, u.Password, hashedPassword)
+}
+
+// StagePasswordRehash : same as RehashPasswordIfNeeded for the users that still have to pass the second factor,
+// the upgraded hash is stored aside until ApplyStagedPasswordRehash is called. Returns whether a hash was staged
+func (us *userService) StagePasswordRehash(u *models.User, plainTextPassword string) (bool, error) {
+	if !passwordhash.NeedsRehash(u.Password, us.hashParams) {
+		return false, nil
+	}
+
+	hashedPassword, err := us.HashPassword(plainTextPassword)
+	if err != nil {
+		return false, err
+	}
+
+	return us.userRepo.SetPendingRehash(u.Username, models.PasswordRehash{From: u.Password, To: hashedPassword})
+}
+
+// ApplyStagedPasswordRehash : saves the hash staged by StagePasswordRehash, unless the password has changed since.
+// Returns whether the hash was upgraded
+func (us *userService) ApplyStagedPasswordRehash(u *models.User) (bool, error) {
+	if u.PendingRehash == nil {
+		return false, nil
+	}
+
+	return us.userRepo.ReplacePasswordHash(u.Username, u.PendingRehash.From, u.PendingRehash.To)
+}
+
+// UpdatePassword : replaces the password hash of the user with the provided username
+func (us *userService) UpdatePassword(username string, hashedPassword string) (bool, error) {
+	return us.userRepo.UpdatePassword(username, hashedPassword)
+}
+
+// DoesEmailAlreadyExist : checks whether a user with the provided email exists or not
+func (us *userService) DoesEmailAlreadyExist(email string) (bool, error) {
+	return us.userRepo.DoesEmailAlreadyExist(email)
+}
+
+// UpdateEmail : sets a new, not yet verified, email for the user with the provided username
+func (us *userService) UpdateEmail(username string, email string) (bool, error) {
+	return us.userRepo.UpdateEmail(username, email)
+}
+
+// MarkEmailVerified : marks the email of the user as verified
+func (us *userService) MarkEmailVerified(username string, email string) (bool, error) {
+	return us.userRepo.MarkEmailVerified(username, email)
+}
+
+// List : lists the users matching the search term
+func (us *userService) List(search string, skip int64, limit int64) ([]*models.User, int64, error) {
+	return us.userRepo.List(search, skip, limit)
+}
+
+// UpdateRole : sets the role of the user
+func (us *userService) UpdateRole(username string, role string, isAdmin bool) (bool, error) {
+	return us.userRepo.UpdateRole(username, role, isAdmin)
+}
+
+// UpdateModeration : sets the ban and suspension state of the user
+func (us *userService) UpdateModeration(username string, bannedAt *time.Time, suspendedUntil *time.Time, reason string) (bool, error) {
+	return us.userRepo.UpdateModeration(username, bannedAt, suspendedUntil, reason)
+}
+
+// FindOneByID : finds a user record with the provided ID
+func (us *userService) FindOneByID(documentObjectID primitive.ObjectID) (*models.User, error) {
+	return us.userRepo.FindOneByID(documentObjectID)
+}
+
+// FindOneByEmail : finds a user record with the provided email
+func (us *userService) FindOneByEmail(email string) (*models.User, error) {
+	return us.userRepo.FindOneByEmail(email)
+}
+
+// FindOneByIdentity : finds the user record that the account at the external provider is linked to
+func (us *userService) FindOneByIdentity(provider string, subject string) (*models.User, error) {
+	return us.userRepo.FindOneByIdentity(provider, subject)
+}
+
+// AddIdentity : links the account at the external provider to the user
+func (us *userService) AddIdentity(username string, identity models.Identity) (bool, error) {
+	return us.userRepo.AddIdentity(username, identity)
 }
@@ -0,0 +1,111 @@
+// Package totp implements RFC 6238 time-based one-time passwords (HMAC-SHA1, 6 digits, 30 second period)
+// which is what every mainstream authenticator app supports
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits : number of digits of a code
+	Digits int = 6
+
+	// Period : number of seconds a code is valid for
+	Period int64 = 30
+
+	// secretSize : 160 bits, the size recommended by RFC 4226
+	secretSize int = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret : generates a random base32 encoded secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate totp secret, error: %s", err)
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// ProvisioningURI : returns the otpauth:// URI that authenticator apps use to enroll the secret (usually rendered as a QR code)
+func ProvisioningURI(issuer string, accountName string, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", Digits))
+	params.Set("period", fmt.Sprintf("%d", Period))
+
+	// some authenticator apps do not decode `+` as a space
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(params.Encode(), "+", "%20")
+}
+
+// Counter : returns the time step of the provided time
+func Counter(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// GenerateCode : generates the code of the secret for the provided time
+func GenerateCode(secret string, t time.Time) (string, error) {
+	return generateCode(secret, Counter(t))
+}
+
+// Validate : validates the code against the secret, accepting `skew` time steps before and after the provided time
+// it returns the time step that matched so that the caller can prevent the same code from being replayed
+func Validate(secret string, code string, t time.Time, skew int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	counter := Counter(t)
+	for i := -skew; i <= skew; i++ {
+		expected, err := generateCode(secret, counter+i)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter + i, true
+		}
+	}
+	return 0, false
+}
+
+// generateCode : HOTP (RFC 4226) with the time step as the counter
+func generateCode(secret string, counter int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret, error: %s", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed data/common_passwords.txt
+var bundledPasswordList string
+
+// PasswordList : passwords known from breaches, ranked by how common they are
+type PasswordList struct {
+	ranks map[string]int
+}
+
+// BundledPasswordList : returns the list of common breached passwords that ships with the binary
+func BundledPasswordList() *PasswordList {
+	list, _ := readPasswordList(strings.NewReader(bundledPasswordList))
+	return list
+}
+
+// LoadPasswordList : reads a list with one password per line, most common first, lines starting with # are ignored
+func LoadPasswordList(path string) (*PasswordList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readPasswordList(f)
+}
+
+func readPasswordList(r io.Reader) (*PasswordList, error) {
+	list := &PasswordList{ranks: make(map[string]int)}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		password := strings.ToLower(line)
+		if _, exists := list.ranks[password]; !exists {
+			list.ranks[password] = len(list.ranks) + 1
+		}
+	}
+
+	return list, scanner.Err()
+}
+
+// Rank : returns the 1-based rank of the password, ignoring case, 0 if it is not on the list
+func (pl *PasswordList) Rank(password string) int {
+	if pl == nil {
+		return 0
+	}
+	return pl.ranks[strings.ToLower(password)]
+}
+
+// Contains : checks whether the password is on the list, ignoring case
+func (pl *PasswordList) Contains(password string) bool {
+	return pl.Rank(password) > 0
+}
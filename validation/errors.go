@@ -0,0 +1,31 @@
+// Package validation holds the rules user supplied credentials have to follow
+package validation
+
+import "strings"
+
+// FieldError : a single failed rule, Code is stable and meant for clients, Message is meant for humans
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors : every failed rule of a request, it is returned as an error so it can travel through the service layer
+type Errors []FieldError
+
+// Error : joins the messages of the failed rules
+func (errs Errors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Field+": "+err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Err : returns nil if no rule failed, a nil Errors wrapped in an error interface would not compare equal to nil
+func (errs Errors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
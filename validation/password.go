@@ -0,0 +1,55 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PasswordMaxLength : bcrypt only looks at the first 72 bytes, longer passwords would give a false sense of security
+const PasswordMaxLength int = 72
+
+// PasswordPolicy : the rules a new password has to follow
+type PasswordPolicy struct {
+	MinLength int
+	// MinScore : minimum strength score from 0 to 4, see Score
+	MinScore int
+	// BreachedPasswords : passwords that are rejected outright, nil disables the check
+	BreachedPasswords *PasswordList
+}
+
+// Validate : checks the password against the policy, the user inputs (e.g. the username and the email)
+// must not make up the password and lower its strength score
+func (pp PasswordPolicy) Validate(password string, userInputs ...string) Errors {
+	field := "password"
+
+	if password == "" {
+		return Errors{{Field: field, Code: "required", Message: "password is required"}}
+	}
+
+	var errs Errors
+	if len([]rune(password)) < pp.MinLength {
+		errs = append(errs, FieldError{Field: field, Code: "too_short", Message: fmt.Sprintf("password must be at least %d characters long", pp.MinLength)})
+	}
+
+	if len(password) > PasswordMaxLength {
+		errs = append(errs, FieldError{Field: field, Code: "too_long", Message: fmt.Sprintf("password must be at most %d bytes long", PasswordMaxLength)})
+	}
+
+	if pp.BreachedPasswords.Contains(password) {
+		errs = append(errs, FieldError{Field: field, Code: "breached", Message: "password has appeared in a data breach, choose a different one"})
+		return errs
+	}
+
+	for _, input := range userInputs {
+		if input != "" && strings.EqualFold(password, input) {
+			errs = append(errs, FieldError{Field: field, Code: "matches_user_input", Message: "password must not be the same as the username or the email"})
+			return errs
+		}
+	}
+
+	if score := Score(password, pp.BreachedPasswords, userInputs...); score < pp.MinScore {
+		errs = append(errs, FieldError{Field: field, Code: "too_weak", Message: fmt.Sprintf("password is too easy to guess (strength %d of 4, at least %d required), use a longer password or a passphrase", score, pp.MinScore)})
+	}
+
+	return errs
+}
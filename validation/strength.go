@@ -0,0 +1,235 @@
+package validation
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// keyboardRows : adjacent keys, typing along a row adds almost no entropy
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"qwertzuiop",
+	"azertyuiop",
+}
+
+// leetSubstitutions : common character substitutions that are undone before looking up dictionary words
+var leetSubstitutions = strings.NewReplacer(
+	"@", "a", "4", "a", "8", "b", "(", "c", "3", "e", "6", "g", "1", "i", "!", "i",
+	"0", "o", "$", "s", "5", "s", "7", "t", "+", "t", "2", "z",
+)
+
+// minDictionaryWordLength : shorter matches are too frequent to mean anything
+const minDictionaryWordLength int = 4
+
+// Score : estimates how hard the password is to guess on a 0 (trivial) to 4 (very strong) scale, in the style of zxcvbn
+// the estimate covers common passwords, dictionary words with leet substitutions, repeats, sequences,
+// keyboard walks and parts of the user inputs (e.g. the username or the email)
+func Score(password string, commonPasswords *PasswordList, userInputs ...string) int {
+	return scoreFromGuesses(estimateGuessesLog10(password, commonPasswords, userInputs))
+}
+
+// scoreFromGuesses : the same thresholds zxcvbn uses
+func scoreFromGuesses(guessesLog10 float64) int {
+	switch {
+	case guessesLog10 < 3:
+		return 0
+	case guessesLog10 < 6:
+		return 1
+	case guessesLog10 < 8:
+		return 2
+	case guessesLog10 < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// estimateGuessesLog10 : every character costs log10 of the character pool, unless it is part of a cheaper pattern
+func estimateGuessesLog10(password string, commonPasswords *PasswordList, userInputs []string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	if rank := commonPasswords.Rank(password); rank > 0 {
+		return math.Log10(float64(rank))
+	}
+
+	charLog10 := math.Log10(float64(poolSize(runes)))
+	costs := make([]float64, len(runes))
+	for i := range costs {
+		costs[i] = charLog10
+	}
+
+	lower := []rune(strings.ToLower(password))
+	deLeeted := []rune(leetSubstitutions.Replace(string(lower)))
+
+	// repeats, sequences and keyboard walks: only the first character of a run is paid in full
+	for i := 1; i < len(lower); i++ {
+		if isPatternContinuation(lower[i-1], lower[i]) {
+			costs[i] = math.Log10(2)
+		}
+	}
+
+	// years are picked from a small range
+	applyYearMatches(costs, lower)
+
+	// dictionary words and user inputs: the whole match costs as much as picking it from the list
+	if len(deLeeted) == len(lower) {
+		applyWordMatches(costs, lower, deLeeted, commonPasswords, userInputs)
+	}
+
+	total := 0.0
+	for _, cost := range costs {
+		total += cost
+	}
+	return total
+}
+
+// applyWordMatches : replaces the cost of the longest matches of known words with the cost of the word
+func applyWordMatches(costs []float64, lower []rune, deLeeted []rune, commonPasswords *PasswordList, userInputs []string) {
+	inputs := make(map[string]bool)
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.SplitN(input, "@", 2)[0])
+		if len([]rune(input)) >= minDictionaryWordLength-1 {
+			inputs[input] = true
+		}
+	}
+
+	for start := 0; start < len(lower); start++ {
+		for end := len(lower); end-start >= minDictionaryWordLength-1; end-- {
+			word, plain := string(deLeeted[start:end]), string(lower[start:end])
+
+			var wordLog10 float64
+			switch {
+			case inputs[plain] || inputs[word]:
+				wordLog10 = 0
+			case end-start >= minDictionaryWordLength && commonPasswords.Rank(word) > 0:
+				wordLog10 = math.Log10(float64(commonPasswords.Rank(word)))
+			default:
+				continue
+			}
+
+			// substitutions and upper case letters make the word a little harder to guess
+			if word != plain {
+				wordLog10 += math.Log10(2)
+			}
+
+			matchCost := 0.0
+			for i := start; i < end; i++ {
+				matchCost += costs[i]
+			}
+
+			if wordLog10 < matchCost {
+				costs[start] = wordLog10
+				for i := start + 1; i < end; i++ {
+					costs[i] = 0
+				}
+			}
+			start = end - 1
+			break
+		}
+	}
+}
+
+// applyYearMatches : a standalone run of four digits between 1900 and 2099 costs as much as picking a year
+func applyYearMatches(costs []float64, lower []rune) {
+	for start := 0; start+4 <= len(lower); start++ {
+		end := start + 4
+		if (start > 0 && unicode.IsDigit(lower[start-1])) || (end < len(lower) && unicode.IsDigit(lower[end])) {
+			continue
+		}
+
+		year := string(lower[start:end])
+		if !(strings.HasPrefix(year, "19") || strings.HasPrefix(year, "20")) || !isDigits(year) {
+			continue
+		}
+
+		yearCost, matchCost := math.Log10(200), 0.0
+		for i := start; i < end; i++ {
+			matchCost += costs[i]
+		}
+
+		if yearCost < matchCost {
+			costs[start] = yearCost
+			for i := start + 1; i < end; i++ {
+				costs[i] = 0
+			}
+		}
+	}
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPatternContinuation : checks whether the character continues a repeat, a sequence or a keyboard walk
+func isPatternContinuation(previous rune, current rune) bool {
+	if previous == current {
+		return true
+	}
+
+	if unicode.IsLetter(previous) == unicode.IsLetter(current) && unicode.IsDigit(previous) == unicode.IsDigit(current) {
+		if delta := current - previous; delta == 1 || delta == -1 {
+			return true
+		}
+	}
+
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, previous)
+		if i < 0 {
+			continue
+		}
+		j := strings.IndexRune(row, current)
+		if j >= 0 && (j-i == 1 || i-j == 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// poolSize : the number of characters an attacker has to try per position, based on the character classes used
+func poolSize(runes []rune) int {
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r < unicode.MaxASCII:
+			hasSymbol = true
+		default:
+			hasOther = true
+		}
+	}
+
+	size := 0
+	if hasLower {
+		size += 26
+	}
+	if hasUpper {
+		size += 26
+	}
+	if hasDigit {
+		size += 10
+	}
+	if hasSymbol {
+		size += 33
+	}
+	if hasOther {
+		size += 100
+	}
+	return size
+}
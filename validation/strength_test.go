@@ -0,0 +1,72 @@
+package validation
+
+import (
+	"testing"
+)
+
+func TestScoreFromGuesses(t *testing.T) {
+	tests := []struct {
+		guessesLog10 float64
+		want         int
+	}{
+		{0, 0},
+		{2.99, 0},
+		{3, 1},
+		{5.99, 1},
+		{6, 2},
+		{7.99, 2},
+		{8, 3},
+		{9.99, 3},
+		{10, 4},
+		{25, 4},
+	}
+
+	for _, tt := range tests {
+		got := scoreFromGuesses(tt.guessesLog10)
+		if got != tt.want {
+			t.Errorf("scoreFromGuesses(%v) = %d, want %d", tt.guessesLog10, got, tt.want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	commonPasswords := BundledPasswordList()
+
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		want       int
+	}{
+		{"empty", "", nil, 0},
+		{"most common password", "password", nil, 0},
+		{"common password ignores case", "PassWord", nil, 0},
+		{"keyboard walk", "qwertyuiopasdf", nil, 1},
+		{"repeated character", "aaaaaaaaaaaaaaaa", nil, 1},
+		{"digit sequence", "1234567890123", nil, 1},
+		{"common word with leet substitutions", "p@ssw0rd", nil, 0},
+		{"username with a year", "alice1987", []string{"alice"}, 0},
+		{"email local part with a year", "bobsmith2001", []string{"bobsmith@example.com"}, 0},
+		{"short random lower case", "xkqmw", nil, 2},
+		{"random lower case", "xkqmvzt", nil, 3},
+		{"short random mixed classes", "Tq8#v", nil, 3},
+		{"long random mixed classes", "r8!Kd0#pQz$wLm2v", nil, 4},
+		{"passphrase", "correct-horse-battery-staple", nil, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Score(tt.password, commonPasswords, tt.userInputs...)
+			if got != tt.want {
+				t.Fatalf("Score(%q) = %d (log10 guesses %.2f), want %d", tt.password, got, estimateGuessesLog10(tt.password, commonPasswords, tt.userInputs), tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreWithoutPasswordList(t *testing.T) {
+	// a nil list disables the lookups, the patterns still apply
+	if got := Score("abcdefghijklmnop", nil); got != 1 {
+		t.Fatalf("Score of a sequence = %d, want 1", got)
+	}
+}
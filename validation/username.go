@@ -0,0 +1,54 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	UsernameMinLength int = 3
+	UsernameMaxLength int = 30
+)
+
+// usernamePattern : starts with a letter or a digit, followed by letters, digits, dots, dashes or underscores
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// reservedUsernames : names that could be mistaken for the service itself or clash with routes
+var reservedUsernames = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "system": true, "support": true,
+	"help": true, "security": true, "moderator": true, "staff": true, "official": true,
+	"smilecook": true, "api": true, "me": true, "auth": true, "oauth": true,
+	"signin": true, "signup": true, "signout": true, "login": true, "logout": true,
+	"register": true, "settings": true, "recipes": true, "null": true, "undefined": true,
+	"anonymous": true, "everyone": true, "noreply": true, "no-reply": true, "postmaster": true,
+	"webmaster": true, "hostmaster": true, "abuse": true,
+}
+
+// ValidateUsername : checks the length, the characters and the reserved names, the comparison with reserved names ignores case
+func ValidateUsername(username string) Errors {
+	field := "username"
+
+	if username == "" {
+		return Errors{{Field: field, Code: "required", Message: "username is required"}}
+	}
+
+	var errs Errors
+	if len(username) < UsernameMinLength {
+		errs = append(errs, FieldError{Field: field, Code: "too_short", Message: fmt.Sprintf("username must be at least %d characters long", UsernameMinLength)})
+	}
+
+	if len(username) > UsernameMaxLength {
+		errs = append(errs, FieldError{Field: field, Code: "too_long", Message: fmt.Sprintf("username must be at most %d characters long", UsernameMaxLength)})
+	}
+
+	if !usernamePattern.MatchString(username) {
+		errs = append(errs, FieldError{Field: field, Code: "invalid_characters", Message: "username can only contain letters, digits, dots, dashes and underscores and must start with a letter or a digit"})
+	}
+
+	if reservedUsernames[strings.ToLower(username)] {
+		errs = append(errs, FieldError{Field: field, Code: "reserved", Message: "username is reserved"})
+	}
+
+	return errs
+}